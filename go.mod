@@ -0,0 +1,3 @@
+module github.com/andydude/go2gos
+
+go 1.21
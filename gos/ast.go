@@ -0,0 +1,323 @@
+package gos
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Compiler struct {
+//	br bufio.Reader
+//	bw bufio.Writer
+//	rd io.Reader
+//	rw io.ReadWriter
+	wr io.Writer
+	fset *token.FileSet
+	info *types.Info
+	pkg *types.Package // the package info describes, once known
+	diags Diagnostics
+	temps int // the temporaries of wrapped assignments so far
+	tempTypes map[ast.Expr]types.TypeAndValue // the types of the expressions evalOnce makes
+	tempSelections map[*ast.SelectorExpr]*types.Selection // and of their selections
+	imports map[*ast.ImportSpec]string // the names importNames found
+
+	// Filename names the input in diagnostics.
+	Filename string
+
+	// TypeCheck runs go/types over the input before emitting,
+	// so that conversions, method calls, field selections and
+	// package references each get their own form.
+	TypeCheck bool
+
+	// WrapIntegers makes arithmetic on sized integer types wrap
+	// on overflow as in Go, by enclosing it in (wrap-signed bits x)
+	// or (wrap-unsigned bits x). It implies TypeCheck.
+	WrapIntegers bool
+
+	// Backend spells the output; nil means Gos.
+	Backend Backend
+
+	// ImportPath is the import path of the package being compiled,
+	// which backends that write libraries name them after. If it
+	// is empty they use the package name. CompilePackage sets it
+	// to the path of the package.
+	ImportPath string
+}
+
+// NewCompiler returns a Compiler that reads Go source and
+// writes Gos.
+func NewCompiler() *Compiler {
+	return &Compiler{}
+}
+
+// Compile parses the Go file read from rd and writes its Gos
+// translation to wr, closing wr afterwards if it is an io.Closer.
+func (c *Compiler) Compile(rd io.Reader, wr io.Writer) error {
+	c.wr = wr
+	forms, err := c.Build(rd)
+	if forms == nil {
+		return err
+	}
+	if err := Write(c.wr, forms); err != nil {
+		c.addError(err)
+	}
+	if f, ok := c.wr.(io.Closer); ok {
+		if err := f.Close(); err != nil {
+			c.addError(err)
+		}
+	}
+	return c.diags.Err()
+}
+
+// Build parses the Go file read from rd and returns its Gos
+// translation as a tree, whose nodes carry the positions of the
+// Go source they came from. The forms are returned even if there
+// are diagnostics, unless the file could not be parsed.
+func (c *Compiler) Build(rd io.Reader) ([]Node, error) {
+	c.fset = token.NewFileSet()
+	c.info = nil
+	c.tempTypes, c.tempSelections = nil, nil
+	c.diags = nil
+	file, err := parser.ParseFile(c.fset, c.Filename, rd, parser.ParseComments)
+	if err != nil {
+		c.addError(err)
+		return nil, c.diags.Err()
+	}
+	if c.TypeCheck || c.WrapIntegers {
+		c.check(file)
+	}
+	return c.emitFile(file), c.diags.Err()
+}
+
+// CompilePackage translates every file of pkg, which was loaded
+// into fset, as one (package ...) unit. Unlike Compile, it leaves
+// wr open so that several packages can be written to it in turn.
+func (c *Compiler) CompilePackage(fset *token.FileSet, pkg *Package, wr io.Writer) error {
+	c.wr = wr
+	forms, _ := c.BuildPackage(fset, pkg)
+	if err := Write(c.wr, forms); err != nil {
+		c.addError(err)
+	}
+	return c.diags.Err()
+}
+
+// BuildPackage is like CompilePackage, but returns the tree
+// instead of writing it.
+func (c *Compiler) BuildPackage(fset *token.FileSet, pkg *Package) ([]Node, error) {
+	c.fset = fset
+	c.info = pkg.Info
+	c.tempTypes, c.tempSelections = nil, nil
+	c.diags = nil
+	c.ImportPath = pkg.ImportPath
+	for _, err := range pkg.Errors {
+		c.addError(err)
+	}
+	return c.emitPackage(pkg.Name, pkg.Files), c.diags.Err()
+}
+
+func goBinaryOpToSchemeOp(name string) string {
+	var table = map[string]string{
+		"!=": "equal?", // written (not (equal? x y))
+		"%": "remainder",
+		"%=": "remainder=",
+		"&": "bitwise-and",
+		"&&": "and",
+		"&=": "bitwise-and=",
+		"&^": "bitwise-but",
+		"&^=": "bitwise-but=",
+		"<<": "arithmetic-shift",
+		"<<=": "arithmetic-shift=",
+		"==": "equal?",
+		">>": "arithmetic-shift", // written (arithmetic-shift x (- n))
+		">>=": "arithmetic-shift=",
+		"^": "bitwise-xor",
+		"^=": "bitwise-xor=",
+		"|": "bitwise-or",
+		"|=": "bitwise-or=",
+		"||": "or",
+	}
+	if table[name] != "" {
+		return table[name]
+	}
+	return name
+}
+
+// schemeBinaryOp returns the Gos operator for op, which may be a
+// compound assignment, applied to x and y. When their type is
+// known it picks quotient for integer /, = for numbers, string<?
+// and string-append for strings, and eqv? for other comparable
+// values that are not compared structurally.
+func (c *Compiler) schemeBinaryOp(op token.Token, x, y ast.Expr) string {
+	assign := ""
+	if token.ADD_ASSIGN <= op && op <= token.AND_NOT_ASSIGN {
+		op -= token.ADD_ASSIGN - token.ADD
+		assign = "="
+	}
+	t := c.operandType(x, y)
+	if t == nil {
+		return goBinaryOpToSchemeOp(op.String() + assign)
+	}
+	var table map[token.Token]string
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch info := u.Info(); {
+		case info&types.IsInteger != 0:
+			table = map[token.Token]string{token.QUO: "quotient", token.EQL: "=", token.NEQ: "="}
+		case info&types.IsNumeric != 0:
+			table = map[token.Token]string{token.EQL: "=", token.NEQ: "="}
+		case info&types.IsString != 0:
+			table = map[token.Token]string{
+				token.ADD: "string-append",
+				token.EQL: "string=?",
+				token.NEQ: "string=?",
+				token.LSS: "string<?",
+				token.LEQ: "string<=?",
+				token.GTR: "string>?",
+				token.GEQ: "string>=?",
+			}
+		case info&types.IsBoolean != 0:
+			table = map[token.Token]string{token.EQL: "eqv?", token.NEQ: "eqv?"}
+		}
+	case *types.Pointer, *types.Chan, *types.Map, *types.Slice, *types.Signature:
+		table = map[token.Token]string{token.EQL: "eqv?", token.NEQ: "eqv?"}
+	}
+	if name, ok := table[op]; ok {
+		return name + assign
+	}
+	return goBinaryOpToSchemeOp(op.String() + assign)
+}
+
+func goUnaryOpToSchemeOp(name string) string {
+	var table = map[string]string{
+		"&": "adr",
+		"^": "bitwise-not",
+		"!": "not",
+		"*": "ptr",
+	}
+	if table[name] != "" {
+		return table[name]
+	}
+	return name
+}
+
+// goIdTable maps predeclared Go identifiers to their Gos spelling.
+var goIdTable = map[string]string{
+	// types
+	"any": "&any",
+	"bool": "&bool",
+	"byte": "&byte",
+	"complex64": "&complex64",
+	"complex128": "&complex128",
+	"comparable": "&comparable",
+	"error": "&error",
+	"float32": "&float32",
+	"float64": "&float64",
+	"int": "&int",
+	"int8": "&int8",
+	"int16": "&int16",
+	"int32": "&int32",
+	"int64": "&int64",
+	"rune": "&rune",
+	"string": "&imm-string",
+	"uint": "&uint",
+	"uint8": "&uint8",
+	"uint16": "&uint16",
+	"uint32": "&uint32",
+	"uint64": "&uint64",
+	"uintptr": "&uintptr",
+	// objects
+	"true": "#t",
+	"false": "#f",
+	"nil": "%nil",
+}
+
+// goCharToSchemeChar returns a Go rune literal as a Scheme
+// character, written #\A, #\newline or #\x7f.
+func goCharToSchemeChar(node *ast.BasicLit, pos token.Position) Node {
+	// UnquoteChar, unlike Unquote, gives '\xff' as the rune U+00FF
+	// rather than the byte 0xff
+	if len(node.Value) >= 2 {
+		r, _, tail, err := strconv.UnquoteChar(node.Value[1:len(node.Value)-1], '\'')
+		if err == nil && tail == "" {
+			return &Char{Position: pos, Value: r}
+		}
+	}
+	return &Symbol{Position: pos, Name: "#\\" + node.Value}
+}
+
+// goNumberToSchemeNumber returns a Go numeric literal as a Scheme
+// number.
+func goNumberToSchemeNumber(node *ast.BasicLit, pos token.Position) Node {
+	return &Number{Position: pos, Lit: schemeNumber(node)}
+}
+
+// schemeNumber spells a Go numeric literal in Scheme: radix
+// prefixes become #x, #o and #b, underscores are dropped, hex
+// floats become inexact ratios such as #i1/4, and imaginary
+// literals become complex numbers such as +3i.
+func schemeNumber(node *ast.BasicLit) string {
+	lit := strings.Replace(node.Value, "_", "", -1)
+	imag := node.Kind == token.IMAG
+	if imag {
+		lit = strings.TrimSuffix(lit, "i")
+	}
+	prefix, digits := "", lit
+	if len(lit) > 1 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			prefix, digits = "#x", lit[2:]
+		case 'o', 'O':
+			prefix, digits = "#o", lit[2:]
+		case 'b', 'B':
+			prefix, digits = "#b", lit[2:]
+		default:
+			// 017 is octal, but 017i, 017.5 and 017e1 are decimal
+			if node.Kind == token.INT {
+				prefix, digits = "#o", lit[1:]
+			}
+		}
+	}
+	if prefix == "#x" && strings.ContainsAny(digits, ".pP") {
+		// Scheme has no hex floats, but their values are ratios
+		r, ok := new(big.Rat).SetString(lit)
+		if !ok {
+			return node.Value
+		}
+		prefix, digits = "#i", r.RatString()
+	} else if prefix == "" {
+		digits = strings.Replace(digits, "E", "e", 1)
+	}
+	if imag {
+		return prefix + "+" + digits + "i"
+	}
+	return prefix + digits
+}
+
+// goStringToSchemeString returns a Go string literal as a Scheme
+// string. Strings that are not valid UTF-8 have no Scheme string
+// syntax, so they become (convert &imm-string #u8(...)).
+func goStringToSchemeString(node *ast.BasicLit, pos token.Position) Node {
+	if node.Kind != token.STRING {
+		return &Symbol{Position: pos, Name: node.Value}
+	}
+	// Go and Scheme escapes differ, and raw strings have none,
+	// so decode the literal; the writer quotes it again
+	value, err := strconv.Unquote(node.Value)
+	if err != nil {
+		return &Symbol{Position: pos, Name: node.Value}
+	}
+	if !utf8.ValidString(value) {
+		return &List{Position: pos, Elts: []Node{
+			&Symbol{Position: pos, Name: "convert"},
+			&Symbol{Position: pos, Name: goIdTable["string"]},
+			&Bytevector{Position: pos, Value: []byte(value)},
+		}}
+	}
+	return &String{Position: pos, Value: value}
+}
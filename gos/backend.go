@@ -0,0 +1,131 @@
+package gos
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Backend decides how a translation is spelled in a target
+// dialect. The Compiler asks it for every identifier, predeclared
+// type name, literal and form name it emits, so the shape of the
+// output stays that of Gos while its words are the target's.
+//
+// Lower and the -gos2go translation read the Gos spelling, so
+// they only apply to the output of the Gos backend.
+type Backend interface {
+	// Name returns the name the backend is selected by, as in the
+	// -target flag.
+	Name() string
+
+	// Ident spells a Go identifier that is not predeclared.
+	Ident(name string) string
+
+	// TypeName spells a predeclared Go type, such as int or
+	// string, or void for the result of a function that returns
+	// nothing.
+	TypeName(name string) string
+
+	// Form spells the head of a Gos form, such as func, when or
+	// range, or a keyword within one, such as else.
+	Form(name string) string
+
+	// Literal returns x, a Bool, Nil, Char, String, Number or
+	// Bytevector in Gos syntax, in the target's syntax: either
+	// unchanged or as a Symbol spelling it.
+	Literal(x Node) Node
+}
+
+// Gos is the default Backend, which writes Gos itself: identifiers
+// as UnmangleName spells them, types as &int and &imm-string, and
+// Scheme literal syntax.
+var Gos Backend = gosBackend{}
+
+type gosBackend struct{}
+
+func (gosBackend) Name() string             { return "gos" }
+func (gosBackend) Ident(name string) string { return UnmangleName(name) }
+func (gosBackend) Form(name string) string  { return name }
+func (gosBackend) Literal(x Node) Node      { return x }
+
+func (gosBackend) TypeName(name string) string {
+	if id, ok := goIdTable[name]; ok {
+		return id
+	}
+	return "&" + name
+}
+
+// backends holds the backends that LookupBackend finds, by name.
+var backends = map[string]Backend{
+	"cl":      CL,
+	"clojure": Clojure,
+	"edn":     EDN,
+	"gos":     Gos,
+	"guile":   Guile,
+	"r7rs":    R7RS,
+	"racket":  Racket,
+}
+
+// RegisterBackend makes b available to LookupBackend under its
+// name, replacing any backend of the same name.
+func RegisterBackend(b Backend) {
+	backends[b.Name()] = b
+}
+
+// LookupBackend returns the backend named name, as in the -target
+// flag.
+func LookupBackend(name string) (Backend, error) {
+	if b, ok := backends[name]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown target %q, want one of %s", name, strings.Join(BackendNames(), ", "))
+}
+
+// BackendNames returns the names of the registered backends in
+// order.
+func BackendNames() []string {
+	var names []string
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isTypeName reports whether name is a predeclared Go type.
+func isTypeName(name string) bool {
+	return strings.HasPrefix(goIdTable[name], "&")
+}
+
+// backend returns the backend c emits for.
+func (c *Compiler) backend() Backend {
+	if c.Backend == nil {
+		return Gos
+	}
+	return c.Backend
+}
+
+// ident spells the Go identifier name for the backend.
+func (c *Compiler) ident(name string) string {
+	switch {
+	case isTypeName(name):
+		return c.backend().TypeName(name)
+	case goIdTable[name] != "":
+		// true, false and nil
+		return c.backend().Literal(atom(token.Position{}, goIdTable[name])).String()
+	}
+	return c.backend().Ident(name)
+}
+
+// form returns the symbol heading the form name, at the position
+// of node.
+func (c *Compiler) form(node ast.Node, name string) *Symbol {
+	return c.sym(node, c.backend().Form(name))
+}
+
+// literal returns the literal x in the backend's syntax.
+func (c *Compiler) literal(x Node) Node {
+	return c.backend().Literal(x)
+}
@@ -0,0 +1,283 @@
+package gos
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// upperBackend spells everything Gos does in upper case, and
+// booleans as T and NIL.
+type upperBackend struct{}
+
+func (upperBackend) Name() string                { return "upper" }
+func (upperBackend) Ident(name string) string    { return strings.ToUpper(name) }
+func (upperBackend) TypeName(name string) string { return "<" + name + ">" }
+func (upperBackend) Form(name string) string     { return strings.ToUpper(name) }
+
+func (upperBackend) Literal(x Node) Node {
+	if b, ok := x.(*Bool); ok {
+		if b.Value {
+			return &Symbol{Position: b.Position, Name: "T"}
+		}
+		return &Symbol{Position: b.Position, Name: "NIL"}
+	}
+	return x
+}
+
+func TestBackend(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`f(x, true)`, `(F X T)`},
+		{`!ok`, `(NOT OK)`},
+		{`a != b`, `(NOT (EQUAL? A B))`},
+		{`[]string{"s"}`, `#((SLICE <string>) "s")`},
+		{`func() {}`, `(FUNC () <void>)`},
+		{`x.y[1:]`, `(INDEX X.Y 1 NIL)`},
+	}
+	for _, test := range tests {
+		if got := translateExpr(t, upperBackend{}, test.expr); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestRacketLiterals(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`"a\tb\x01"`, `"a\tb\u0001"`},
+		{`"\U000e0001"`, `"\U000e0001"`},
+		{`'\a'`, `#\u0007`},
+		{`'\n'`, `#\newline`},
+		{`'x'`, `#\x`},
+		{`"\xff"`, `(convert &imm-string (bytes 255))`},
+	}
+	for _, test := range tests {
+		if got := translateExpr(t, Racket, test.expr); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func translateExpr(t *testing.T, b Backend, src string) string {
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := TranslateExpr(token.NewFileSet(), expr, &buf, Options{Backend: b}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestLookupBackend(t *testing.T) {
+	if b, err := LookupBackend("gos"); b != Gos || err != nil {
+		t.Errorf("LookupBackend(\"gos\") = %v, %v", b, err)
+	}
+	if _, err := LookupBackend("upper"); err == nil || !strings.Contains(err.Error(), "want one of cl, clojure, edn, gos") {
+		t.Errorf("LookupBackend(\"upper\") error = %v", err)
+	}
+	RegisterBackend(upperBackend{})
+	defer delete(backends, "upper")
+	if b, err := LookupBackend("upper"); b != (upperBackend{}) || err != nil {
+		t.Errorf("LookupBackend(\"upper\") = %v, %v", b, err)
+	}
+}
+
+// TestPackagers translates testdata/library.go with each backend
+// that writes libraries and compares the pretty-printed result with
+// testdata/library.name.ext.
+func TestPackagers(t *testing.T) {
+	for _, name := range BackendNames() {
+		p, ok := backends[name].(Packager)
+		if !ok {
+			continue
+		}
+		golden := filepath.Join("testdata", "library."+name+p.Ext())
+		t.Run(filepath.Base(golden), func(t *testing.T) {
+			src := filepath.Join("testdata", "library.go")
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, src, nil, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			forms, err := BuildFile(fset, file, Options{Backend: p, ImportPath: "example.com/shapes"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := Fprint(&got, forms, DefaultStyle); err != nil {
+				t.Fatal(err)
+			}
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s differs:\n--- got ---\n%s\n--- want ---\n%s", golden, got.Bytes(), want)
+			}
+		})
+	}
+}
+
+func TestLibraryFile(t *testing.T) {
+	for _, tt := range []struct{ importPath, want string }{
+		{"example.com/shapes", "go/example.com/shapes.scm"},
+		{"fmt", "go/fmt.scm"},
+		{"/src/./shapes/", "go/src/shapes.scm"},
+	} {
+		if got := LibraryFile(Guile.(Packager), tt.importPath); got != tt.want {
+			t.Errorf("LibraryFile(%q) = %q, want %q", tt.importPath, got, tt.want)
+		}
+	}
+	if got, want := LibraryFile(Clojure.(Packager), "example.com/go-shapes"), "go/example/com/go_shapes.clj"; got != want {
+		t.Errorf("Clojure LibraryFile = %q, want %q", got, want)
+	}
+}
+
+func TestR7RSExcept(t *testing.T) {
+	src := `package p
+func list(xs ...int) []int { return xs }
+var square, length = 2, 3
+func Max(a, b int) int { return max(a, b) }
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forms, err := BuildFile(fset, file, Options{Backend: R7RS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib := forms[len(forms)-1].(*List)
+	got := lib.Elts[3].(*List).Elts[1].String()
+	if want := "(except (scheme base) append max min list square length)"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRacketFields(t *testing.T) {
+	src := `package p
+type P struct{ X, Y int }
+type C struct { P; R int }
+func f(c *C) int {
+	d := C{}
+	c.R += d.X
+	return c.P.Y
+}
+func g(cs []*C) *int {
+	cs[0].R = 1
+	return &cs[1].R
+}
+`
+	want := `(define (f c) (let ((d (C (P 0 0) 0))) (set-C-R! c (+ (C-R c) (P-X (C-P d)))) (P-Y (C-P c))))`
+	for _, typed := range []bool{false, true} {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "p.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forms, err := BuildFile(fset, file, Options{Backend: Racket, TypeCheck: typed})
+		if got := forms[len(forms)-2].String(); got != want {
+			t.Errorf("typed %v: got %s, want %s", typed, got, want)
+		}
+		wantErr := "p.go:10:2: cannot tell the struct type of (index cs 0) to select its field R\n" +
+			"p.go:11:10: cannot tell the struct type of (index cs 1) to select its field R"
+		if typed {
+			wantErr = "p.go:11:10: cannot take the address of field R of C"
+		}
+		if got := fmt.Sprint(err); got != wantErr {
+			t.Errorf("typed %v: error = %q, want %q", typed, got, wantErr)
+		}
+	}
+}
+
+// statementTests are bodies of func f(x int) int and the forms
+// a backend translates them into, without the head of f.
+type statementTests []struct {
+	body string
+	want string
+}
+
+// testStatements translates each body of tests with the backend b
+// and checks that the last form is f, whose head is the given one.
+func testStatements(t *testing.T, b Backend, head string, tests statementTests) {
+	t.Helper()
+	for _, test := range tests {
+		src := "package p\nfunc f(x int) int {\n" + strings.Replace(test.body, "; ", "\n", -1) + "\n}\n"
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "p.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forms, err := BuildFile(fset, file, Options{Backend: b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := forms[len(forms)-1].String()
+		if want := "(" + head + " " + test.want + ")"; got != want {
+			t.Errorf("%s: got %s, want %s", test.body, got, want)
+		}
+	}
+}
+
+func TestCLStatements(t *testing.T) {
+	testStatements(t, CL, "defun f (x)", statementTests{
+		{`if x > 0 { return x }; return 0`, `(when (> x 0) (return-from f x)) 0`},
+		{`for x < 3 { x++ }; return x`, `(loop (unless (< x 3) (return)) (incf x)) x`},
+		{`L: for { for { break L } }; return x`, `(block %break-L (loop (loop (return-from %break-L)))) x`},
+		{`goto L; L: return x`, `(tagbody (go L) L (return-from f x))`},
+		{`return x * 1.5`, `(* x 1.5d0)`},
+		{`switch x { case 1, 2: return 'a' }; return 0`, `(case x ((1 2) (return-from f #\a))) 0`},
+	})
+}
+
+func TestClojureStatements(t *testing.T) {
+	testStatements(t, Clojure, "defn- f [x]", statementTests{
+		{`if x > 0 { return x }; return 0`, `(if (> x 0) x 0)`},
+		{`for x < 3 { x++ }; return x`, `(let [x (volatile! x)] (while (< @x 3) (vswap! x inc)) @x)`},
+		{`L: for { for { break L } }; return x`, `(block :break-L (while true (while true (return-from :break-L)))) x`},
+		{`c := make(chan int); go func() { c <- x }(); return <-c`, `(let [c (make (chan &int))] (let [%0 (fn [] (async/>!! c x))] (async/thread (%0))) (async/<!! c))`},
+		{`switch x { case 1, 2: return 'a' }; return 0`, `(block :return (case x (1 2) (return-from :return \a) nil) 0)`},
+	})
+}
+
+func TestEDNValues(t *testing.T) {
+	src := `package p
+type T struct{ A int; B []float64 }
+const K = -2
+var V = map[string]T{"a": {K, []float64{1, .5}}}
+var W = f()
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forms, err := BuildFile(fset, file, Options{Backend: EDN})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := forms[len(forms)-1].String()
+	want := `{:package "p" :import-path "p" :const {:K -2} :var {:V {"a" {:A -2 :B [1.0 0.5]}} :W #go/expr "(f)"}}`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
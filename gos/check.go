@@ -0,0 +1,273 @@
+package gos
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// check runs go/types over file and records the results in c.info,
+// which the emit* methods consult to disambiguate conversions from
+// calls, and package qualifiers from field and method selections.
+// Type errors do not stop translation; each is recorded as a
+// diagnostic and whatever information could be recovered is kept.
+func (c *Compiler) check(file *ast.File) {
+	c.info = &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(c.fset, "source", nil),
+		Error:    c.addError,
+	}
+	conf.Check(file.Name.Name, c.fset, []*ast.File{file}, c.info)
+}
+
+// definedPackage returns the package whose objects c.info records
+// the definitions of, or nil if there are none.
+func (c *Compiler) definedPackage() *types.Package {
+	if c.info == nil {
+		return nil
+	}
+	for _, obj := range c.info.Defs {
+		if obj != nil && obj.Pkg() != nil {
+			return obj.Pkg()
+		}
+	}
+	return nil
+}
+
+// isType reports whether expr denotes a type. Without type
+// information, nothing is known to be a type.
+func (c *Compiler) isType(expr ast.Expr) bool {
+	tv, ok := c.typeAndValue(expr)
+	return ok && tv.IsType()
+}
+
+// isInstance reports whether expr, the operand of an index
+// expression, names a generic function or type that is being
+// instantiated rather than a value that is being indexed.
+func (c *Compiler) isInstance(expr ast.Expr) bool {
+	if c.info == nil {
+		return false
+	}
+	var id *ast.Ident
+	switch a := expr.(type) {
+	case *ast.Ident:
+		id = a
+	case *ast.SelectorExpr:
+		id = a.Sel
+	default:
+		return false
+	}
+	_, ok := c.info.Instances[id]
+	return ok
+}
+
+// isPackage reports whether id refers to an imported package.
+func (c *Compiler) isPackage(id *ast.Ident) bool {
+	if c.info == nil {
+		return false
+	}
+	_, ok := c.info.Uses[id].(*types.PkgName)
+	return ok
+}
+
+// importNames returns the names of the packages that file imports
+// without naming them, where those are not the ones packageName
+// guesses from their paths, as yaml is not for gopkg.in/yaml.v3.
+// With type information a name is that of the package go/types
+// imported. Without it, or if the package could not be imported,
+// it is the longest qualifier the file uses that refers to nothing
+// in it, is not the name of another import, and is part of the
+// last element of the path.
+func (c *Compiler) importNames(file *ast.File) map[*ast.ImportSpec]string {
+	used := make(map[string]bool) // the unresolved qualifiers
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+	taken := make(map[string]bool)
+	for _, spec := range file.Imports {
+		if spec.Name != nil {
+			taken[spec.Name.Name] = true
+		} else if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			taken[packageName(path)] = true
+		}
+	}
+	names := make(map[*ast.ImportSpec]string)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil || err != nil {
+			continue
+		}
+		guess := packageName(path)
+		var name string
+		if pkg := c.imported(spec); pkg != nil {
+			name = pkg.Name()
+		} else if !used[guess] {
+			elem := strings.ToLower(path[strings.LastIndexByte(path, '/')+1:])
+			for q := range used {
+				if taken[q] || !strings.Contains(elem, strings.ToLower(q)) {
+					continue
+				}
+				if len(q) > len(name) || len(q) == len(name) && q < name {
+					name = q
+				}
+			}
+		}
+		if name != "" && name != guess {
+			names[spec] = name
+		}
+	}
+	return names
+}
+
+// imported returns the package that spec imports, or nil if no
+// type information is available or the package could not be
+// imported, when go/types only guesses its name.
+func (c *Compiler) imported(spec *ast.ImportSpec) *types.Package {
+	if c.info == nil {
+		return nil
+	}
+	if name, ok := c.info.Implicits[spec].(*types.PkgName); ok && name.Imported().Complete() {
+		return name.Imported()
+	}
+	return nil
+}
+
+// selection returns the field or method selection denoted by node,
+// or nil if there is none or no type information is available.
+func (c *Compiler) selection(node *ast.SelectorExpr) *types.Selection {
+	if s, ok := c.tempSelections[node]; ok {
+		return s
+	}
+	if c.info == nil {
+		return nil
+	}
+	return c.info.Selections[node]
+}
+
+// typeAndValue returns the type and value of expr, which may be
+// one of the expressions evalOnce makes, and false if they are not
+// known.
+func (c *Compiler) typeAndValue(expr ast.Expr) (types.TypeAndValue, bool) {
+	if tv, ok := c.tempTypes[expr]; ok {
+		return tv, true
+	}
+	if c.info == nil {
+		return types.TypeAndValue{}, false
+	}
+	tv, ok := c.info.Types[expr]
+	return tv, ok
+}
+
+// setType records tv as the type and value of expr, an expression
+// evalOnce made. The Info that type checking filled in may be the
+// caller's, so it is kept as it was.
+func (c *Compiler) setType(expr ast.Expr, tv types.TypeAndValue) {
+	if c.tempTypes == nil {
+		c.tempTypes = make(map[ast.Expr]types.TypeAndValue)
+	}
+	c.tempTypes[expr] = tv
+}
+
+// setSelection is setType for the selection s of node.
+func (c *Compiler) setSelection(node *ast.SelectorExpr, s *types.Selection) {
+	if c.tempSelections == nil {
+		c.tempSelections = make(map[*ast.SelectorExpr]*types.Selection)
+	}
+	c.tempSelections[node] = s
+}
+
+// typeOf returns the type of expr, or nil if it is not known.
+func (c *Compiler) typeOf(expr ast.Expr) types.Type {
+	if tv, ok := c.tempTypes[expr]; ok {
+		return tv.Type
+	}
+	return c.info.TypeOf(expr)
+}
+
+// operandType returns the type of the operands of a binary
+// operator: that of x, or of y if x is an untyped nil. It returns
+// nil if no type information is available.
+func (c *Compiler) operandType(x, y ast.Expr) types.Type {
+	if c.info == nil {
+		return nil
+	}
+	t := c.typeOf(x)
+	if b, ok := t.(*types.Basic); ok && b.Kind() == types.UntypedNil {
+		t = c.typeOf(y)
+	}
+	return t
+}
+
+// wordSizes gives the widths of int, uint and uintptr when
+// wrapping integer arithmetic; Gos assumes a 64-bit target.
+var wordSizes = types.SizesFor("gc", "amd64")
+
+// intWrap returns the helper that wraps a value of expr's type to
+// its width, wrap-signed or wrap-unsigned, and the width in bits.
+// It returns "" unless WrapIntegers is set and expr is a non-constant
+// integer.
+func (c *Compiler) intWrap(expr ast.Expr) (string, int64) {
+	if !c.WrapIntegers || c.info == nil {
+		return "", 0
+	}
+	tv, ok := c.typeAndValue(expr)
+	if !ok || tv.Value != nil || tv.Type == nil {
+		return "", 0
+	}
+	b, ok := tv.Type.Underlying().(*types.Basic)
+	if !ok || b.Info()&types.IsInteger == 0 || b.Info()&types.IsUntyped != 0 {
+		return "", 0
+	}
+	bits := wordSizes.Sizeof(b) * 8
+	if b.Info()&types.IsUnsigned != 0 {
+		return "wrap-unsigned", bits
+	}
+	return "wrap-signed", bits
+}
+
+// overflows reports whether the binary operator op can take a
+// result of expr's integer type out of its range.
+func (c *Compiler) overflows(op token.Token, expr ast.Expr) bool {
+	switch op {
+	case token.ADD, token.SUB, token.MUL, token.SHL:
+		return true
+	case token.QUO:
+		// only the most negative value divided by -1
+		name, _ := c.intWrap(expr)
+		return name == "wrap-signed"
+	}
+	return false
+}
+
+// isPure reports whether evaluating expr twice has the same effect
+// as evaluating it once.
+func isPure(expr ast.Expr) bool {
+	switch a := expr.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		return true
+	case *ast.ParenExpr:
+		return isPure(a.X)
+	case *ast.SelectorExpr:
+		return isPure(a.X)
+	case *ast.StarExpr:
+		return isPure(a.X)
+	case *ast.IndexExpr:
+		return isPure(a.X) && isPure(a.Index)
+	}
+	return false
+}
@@ -0,0 +1,1409 @@
+package gos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CL is a Backend that writes each package as a Common Lisp
+// package named go/elem... after its import path, in a file found
+// as go/elem....lisp. Functions become defuns, package variables
+// and constants defparameters, and struct types defstructs whose
+// composite literals call their constructors. Statements use CL's
+// own control flow: block and return-from for return, break and
+// continue, loop for for, and tagbody and go for labels that a goto
+// jumps to.
+//
+// The file reads Go's identifiers with an :invert readtable, so
+// that their case is kept, and refers to an imported package by
+// its Gos prefix, as a local nickname: fmt:Println. What else Go
+// does at run time is left to the package go/builtin, as for
+// R7RS, but a goroutine is started with (goroutine thunk), since
+// go is CL's. Literals are spelled in CL when the package is laid
+// out, as the translation of statements looks at them.
+var CL Backend = clBackend{}
+
+type clBackend struct{ gosBackend }
+
+func (clBackend) Name() string { return "cl" }
+func (clBackend) Ext() string  { return ".lisp" }
+
+// clReserved holds the Go identifiers that name the COMMON-LISP
+// functions and macros the translation calls, which a package
+// definition of the same name would shadow, and the constants it
+// cannot bind.
+var clReserved = map[string]bool{
+	"apply": true, "ash": true, "block": true, "coerce": true,
+	"concatenate": true, "decf": true, "defparameter": true,
+	"defstruct": true, "defun": true, "eql": true, "equal": true,
+	"float": true, "funcall": true, "incf": true, "list": true,
+	"logand": true, "logandc2": true, "logior": true, "lognot": true,
+	"logxor": true, "loop": true, "mapc": true, "null": true,
+	"otherwise": true, "pi": true, "progn": true, "psetf": true,
+	"push": true, "rem": true, "setf": true, "t": true,
+	"tagbody": true, "truncate": true,
+}
+
+// clBuiltinShadows holds the names that both COMMON-LISP and
+// go/builtin export, with their Go meaning in the latter.
+var clBuiltinShadows = []string{"append", "close", "complex", "delete", "max", "method", "min", "print", "real"}
+
+// clOps maps the Gos operators that CL spells differently.
+var clOps = map[string]string{
+	"arithmetic-shift": "ash",
+	"bitwise-and":      "logand",
+	"bitwise-but":      "logandc2",
+	"bitwise-not":      "lognot",
+	"bitwise-or":       "logior",
+	"bitwise-xor":      "logxor",
+	"eqv?":             "eql",
+	"equal?":           "equal",
+	"quotient":         "truncate",
+	"remainder":        "rem",
+	"string<=?":        "string<=",
+	"string<?":         "string<",
+	"string=?":         "string=",
+	"string>=?":        "string>=",
+	"string>?":         "string>",
+}
+
+func (clBackend) Ident(name string) string {
+	if clReserved[name] {
+		return reservedPrefix + name
+	}
+	return UnmangleName(name)
+}
+
+func (clBackend) Package(pkg *List, importPath string) ([]Node, error) {
+	lib, err := newLibrary(pkg, importPath, LowerNone)
+	if err != nil {
+		return nil, err
+	}
+	l := &clLowerer{
+		lowerer: &lowerer{level: LowerR7RS, prefixes: make(map[string]bool)},
+		funcs:   make(map[string]bool),
+		vars:    make(map[string]int),
+	}
+	types := make(map[string]Node)
+	for _, spec := range lib.imports {
+		l.prefixes[spec.prefix()] = true
+	}
+	for _, x := range lib.body {
+		switch Head(x) {
+		case "func", "func...":
+			if name := l.funcName(x.(*List)); name != nil {
+				l.funcs[name.String()] = true
+			}
+		case "var", "const":
+			for _, spec := range l.specs(x.(*List)) {
+				for _, name := range spec.names {
+					l.vars[name.String()]++
+				}
+			}
+		case "type":
+			for _, spec := range typeSpecs(x.(*List)) {
+				types[spec.name] = spec.typ
+			}
+		}
+	}
+	var body []Node
+	for _, x := range lib.body {
+		for _, y := range l.decl(x) {
+			body = append(body, l.spell(constructors(composite(y, false), types)))
+		}
+	}
+	return append(clHeader(pkg, lib, body), body...), l.diags.Err()
+}
+
+// clHeader returns the forms that begin the file of lib, whose
+// definitions are body: a readtable that keeps the case of names,
+// the package definition, and in-package.
+func clHeader(pkg *List, lib *library, body []Node) []Node {
+	name := symbol("#:" + libraryPath(lib.path))
+	use := form(":use", symbol("#:cl"), symbol("#:"+libraryPath("builtin")))
+	shadowing := form(":shadowing-import-from", symbol("#:"+libraryPath("builtin")))
+	for _, name := range clBuiltinShadows {
+		shadowing.Elts = append(shadowing.Elts, symbol("#:"+name))
+	}
+	nicknames := form(":local-nicknames")
+	for _, spec := range lib.imports {
+		path := symbol("#:" + libraryPath(spec.path))
+		switch spec.alias {
+		case ".":
+			use.Elts = append(use.Elts, path)
+		case "_":
+			// CL has no way to load a package for its effects alone
+		default:
+			nicknames.Elts = append(nicknames.Elts, &List{Elts: []Node{symbol("#:" + spec.prefix()), path}})
+		}
+	}
+	// only names without upper-case letters read as CL's
+	shadow := form(":shadow")
+	export := form(":export")
+	for _, x := range body {
+		for _, name := range clDefined(x) {
+			if strings.ToLower(name) == name {
+				shadow.Elts = append(shadow.Elts, symbol("#:"+name))
+			}
+			if isExported(name) {
+				export.Elts = append(export.Elts, symbol("#:"+name))
+			}
+		}
+	}
+	def := form("defpackage", name, use, shadowing)
+	for _, option := range []*List{shadow, nicknames, export} {
+		if len(option.Elts) > 1 {
+			def.Elts = append(def.Elts, option)
+		}
+	}
+	def.Position = pkg.Position
+	return []Node{
+		form("eval-when", form(":compile-toplevel", symbol(":load-toplevel"), symbol(":execute")),
+			form("setf", symbol("*readtable*"), form("copy-readtable", symbol("nil"))),
+			form("setf", form("readtable-case", symbol("*readtable*")), symbol(":invert"))),
+		def,
+		form("in-package", name),
+	}
+}
+
+// clDefined returns the names the top-level form x defines.
+func clDefined(x Node) []string {
+	list, ok := x.(*List)
+	if !ok || len(list.Elts) < 2 {
+		return nil
+	}
+	switch Head(list) {
+	case "defun", "defparameter":
+		return []string{list.Elts[1].String()}
+	case "defstruct":
+		if name, ok := list.Elts[1].(*List); ok && len(name.Elts) > 0 {
+			return []string{name.Elts[0].String()}
+		}
+	}
+	return nil
+}
+
+// clLiteral returns the Gos literal x in CL syntax.
+func clLiteral(x Node) Node {
+	switch x := x.(type) {
+	case *Bool:
+		if x.Value {
+			return &Symbol{Position: x.Position, Name: "t"}
+		}
+		return &Symbol{Position: x.Position, Name: "nil"}
+	case *Nil:
+		return &Symbol{Position: x.Position, Name: "nil"}
+	case *Char:
+		if name, ok := clCharNames[x.Value]; ok {
+			return &Symbol{Position: x.Position, Name: `#\` + name}
+		}
+		if unicode.IsPrint(x.Value) && !unicode.Is(unicode.M, x.Value) {
+			return &Symbol{Position: x.Position, Name: `#\` + string(x.Value)}
+		}
+		return &List{Position: x.Position, Elts: []Node{symbol("code-char"), &Number{Lit: strconv.Itoa(int(x.Value))}}}
+	case *String:
+		return clString(x)
+	case *Number:
+		return clNumber(x)
+	case *Bytevector:
+		codes := make([]string, len(x.Value))
+		for i, b := range x.Value {
+			codes[i] = strconv.Itoa(int(b))
+		}
+		return &List{Position: x.Position, Elts: []Node{
+			symbol("coerce"),
+			symbol("'(" + strings.Join(codes, " ") + ")"),
+			symbol("'(vector (unsigned-byte 8))"),
+		}}
+	}
+	return x
+}
+
+// clCharNames holds the characters that CL writes by name.
+var clCharNames = map[rune]string{
+	' ': "Space", '\n': "Newline", '\t': "Tab", '\r': "Return",
+	'\b': "Backspace", '\f': "Page", 0x7f: "Rubout",
+}
+
+// clString returns the string s in CL syntax, which escapes only
+// quotes and backslashes. A string holding control characters
+// other than newline and tab is built by (map 'string #'code-char
+// '(codes...)) instead.
+func clString(s *String) Node {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, r := range s.Value {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+		case r == '\n' || r == '\t' || unicode.IsPrint(r):
+		default:
+			var codes []string
+			for _, r := range s.Value {
+				codes = append(codes, strconv.Itoa(int(r)))
+			}
+			return &List{Position: s.Position, Elts: []Node{
+				symbol("map"), symbol("'string"), symbol("#'code-char"),
+				symbol("'(" + strings.Join(codes, " ") + ")"),
+			}}
+		}
+		buf.WriteRune(r)
+	}
+	buf.WriteByte('"')
+	return &Symbol{Position: s.Position, Name: buf.String()}
+}
+
+// clNumber returns the number x in CL syntax: floats are double
+// floats, as in 1.5d0, and imaginary numbers #c(0 y).
+func clNumber(x *Number) Node {
+	lit := x.Lit
+	imag := strings.HasSuffix(lit, "i")
+	if imag {
+		lit = strings.Replace(strings.TrimSuffix(lit, "i"), "+", "", 1)
+	}
+	var real Node = &Symbol{Position: x.Position, Name: lit}
+	switch {
+	case strings.HasPrefix(lit, "#i"):
+		// a hex float, written as its ratio
+		real = &List{Position: x.Position, Elts: []Node{symbol("float"), symbol(lit[2:]), symbol("1d0")}}
+	case strings.HasPrefix(lit, "#"):
+	case strings.ContainsAny(lit, "e"):
+		real = &Symbol{Position: x.Position, Name: strings.Replace(lit, "e", "d", 1)}
+	case strings.HasSuffix(lit, "."):
+		real = &Symbol{Position: x.Position, Name: lit + "0d0"}
+	case strings.Contains(lit, "."):
+		real = &Symbol{Position: x.Position, Name: lit + "d0"}
+	}
+	if !imag {
+		return real
+	}
+	if _, ok := real.(*List); ok {
+		return &List{Position: x.Position, Elts: []Node{symbol("complex"), &Number{Lit: "0"}, real}}
+	}
+	return &Symbol{Position: x.Position, Name: fmt.Sprintf("#c(0 %s)", real)}
+}
+
+// clLowerer translates the Gos declarations of a package into CL.
+// It reuses the parsing of specs and parameters of a lowerer.
+type clLowerer struct {
+	*lowerer
+	funcs map[string]bool // the functions the package defines
+	vars  map[string]int  // the variables in scope, called with funcall
+	block Node            // the block that return leaves
+}
+
+// declare puts names in scope and returns a function that takes
+// them out again.
+func (l *clLowerer) declare(names []Node) func() {
+	for _, name := range names {
+		l.vars[name.String()]++
+	}
+	return func() {
+		for _, name := range names {
+			l.vars[name.String()]--
+		}
+	}
+}
+
+func (l *clLowerer) decl(x Node) []Node {
+	switch Head(x) {
+	case "func", "func...":
+		return []Node{l.defun(x.(*List))}
+	case "var", "const":
+		return l.defparameters(x.(*List))
+	case "type":
+		return l.defstructs(x.(*List))
+	}
+	return []Node{l.expr(x)}
+}
+
+// defparameters turns a top-level var or const form into
+// defparameters.
+func (l *clLowerer) defparameters(x *List) []Node {
+	var out []Node
+	for _, spec := range l.specs(x) {
+		switch {
+		case spec.comment != nil:
+			out = append(out, spec.comment)
+		case len(spec.values) == 0:
+			for _, name := range spec.names {
+				out = append(out, form("defparameter", l.binding(name), zero(spec.typ)))
+			}
+		case len(spec.values) == len(spec.names):
+			for i, name := range spec.names {
+				out = append(out, form("defparameter", l.binding(name), l.expr(spec.values[i])))
+			}
+		case len(spec.values) == 1:
+			places := form("values")
+			for _, name := range spec.names {
+				name = l.binding(name)
+				out = append(out, form("defparameter", name, &Nil{}))
+				places.Elts = append(places.Elts, name)
+			}
+			out = append(out, form("setf", places, l.expr(spec.values[0])))
+		default:
+			l.errorf(x, "%d names but %d values", len(spec.names), len(spec.values))
+		}
+	}
+	return out
+}
+
+// defstructs turns the struct types of a type form into defstructs
+// with a constructor of the same name, which takes every field.
+func (l *clLowerer) defstructs(x *List) []Node {
+	var out []Node
+	for _, spec := range typeSpecs(x) {
+		if Head(spec.typ) != "struct" {
+			continue
+		}
+		fields := &List{}
+		for _, name := range fieldNames(spec.typ.(*List)) {
+			fields.Elts = append(fields.Elts, symbol(name))
+		}
+		name := &List{Elts: []Node{symbol(spec.name), form(":constructor", symbol(spec.name), fields)}}
+		out = append(out, &List{Position: x.Position, Elts: append([]Node{symbol("defstruct"), name}, fields.Elts...)})
+	}
+	return out
+}
+
+// funcName returns the name that the (func [recv] name params
+// result body...) form x defines, T.M for a method.
+func (l *clLowerer) funcName(x *List) Node {
+	recv, elts := funcRecv(x)
+	if len(elts) < 3 {
+		return nil
+	}
+	name := elts[0]
+	if Head(name) == "generic" && len(name.(*List).Elts) >= 2 {
+		name = name.(*List).Elts[1]
+	}
+	if recv != nil {
+		typ := recv
+		if v, ok := recv.(*Vector); ok && len(v.Elts) == 2 {
+			typ = v.Elts[1]
+		}
+		name = symbol(typeName(typ) + "." + name.String())
+	}
+	return name
+}
+
+// funcRecv splits the receiver, if any, off the elements of the
+// func form x that follow its head.
+func funcRecv(x *List) (Node, []Node) {
+	elts := x.Elts[1:]
+	if len(elts) >= 4 {
+		_, isVector := elts[0].(*Vector)
+		_, isName := elts[1].(*Symbol)
+		if isVector || isName {
+			return elts[0], elts[1:]
+		}
+	}
+	return nil, elts
+}
+
+// defun turns (func [recv] name params result body...) into
+// (defun name (params...) body...), in whose block return leaves.
+func (l *clLowerer) defun(x *List) Node {
+	recv, elts := funcRecv(x)
+	name := l.funcName(x)
+	if name == nil {
+		l.errorf(x, "malformed %s form", Head(x))
+		return x
+	}
+	var formals []Node
+	if recv != nil {
+		// the receiver comes first, as for R7RS
+		if v, ok := recv.(*Vector); ok && len(v.Elts) == 2 {
+			formals = []Node{l.binding(v.Elts[0])}
+		} else {
+			formals = []Node{l.binding(symbol("_"))}
+		}
+	}
+	formals = append(formals, l.lambdaList(elts[1], Head(x) == "func...")...)
+	body := elts[3:]
+	var doc []Node
+	if len(body) > 0 {
+		if s, ok := body[0].(*String); ok {
+			doc, body = []Node{s}, body[1:]
+		}
+	}
+	body = append(doc, l.funcBody(name, formals, elts[2], body)...)
+	return &List{Position: x.Position, Elts: append([]Node{symbol("defun"), name, &List{Elts: formals}}, body...)}
+}
+
+// lambdaList returns the CL lambda list for a parameter list,
+// which takes the last parameter of a variadic function as &rest.
+func (l *clLowerer) lambdaList(x Node, variadic bool) []Node {
+	formals := l.params(x, variadic)
+	for i, name := range formals {
+		if isSymbol(name, ".") {
+			formals[i] = symbol("&rest")
+		}
+	}
+	return formals
+}
+
+// lambda turns a function literal (func params result body...)
+// into a lambda, whose body is a block %lambda if a return leaves
+// it other than at its end.
+func (l *clLowerer) lambda(x *List) Node {
+	if len(x.Elts) < 3 {
+		l.errorf(x, "malformed %s form", Head(x))
+		return x
+	}
+	block := symbol("%lambda")
+	formals := l.lambdaList(x.Elts[1], Head(x) == "func...")
+	body := l.funcBody(block, formals, x.Elts[2], x.Elts[3:])
+	if returnsFrom(body, block) {
+		body = []Node{form("block", append([]Node{block}, nonEmpty(body)...)...)}
+	}
+	return &List{Position: x.Position, Elts: append([]Node{symbol("lambda"), &List{Elts: formals}}, body...)}
+}
+
+// returnsFrom reports whether body leaves block, other than within
+// a nested lambda, which has its own.
+func returnsFrom(body []Node, block Node) bool {
+	found := false
+	walk(body, func(x *List) bool {
+		found = found || Head(x) == "return-from" && len(x.Elts) > 1 && isSymbol(x.Elts[1], block.String())
+		return !found && !isFunc(x)
+	})
+	return found
+}
+
+// funcBody translates the body of a function with the given
+// formals and result, whose return leaves block. Named results
+// are bound around it, and if it defers calls, it runs them as it
+// unwinds.
+func (l *clLowerer) funcBody(block Node, formals []Node, result Node, body []Node) []Node {
+	gotos, results, outer := l.gotos, l.results, l.block
+	defer func() { l.gotos, l.results, l.block = gotos, results, outer }()
+
+	l.block = block
+	l.gotos = map[string]bool{}
+	walk(body, func(x *List) bool {
+		if Head(x) == "goto" && len(x.Elts) == 2 {
+			l.gotos[x.Elts[1].String()] = true
+		}
+		return !isFunc(x)
+	})
+	l.results = nil
+	var types []Node
+	fields := []Node{result}
+	if Head(result) == "values" {
+		fields = result.(*List).Elts[1:]
+	}
+	for _, field := range fields {
+		if v, ok := field.(*Vector); ok && len(v.Elts) >= 2 {
+			for _, name := range v.Elts[:len(v.Elts)-1] {
+				l.results = append(l.results, l.binding(name))
+				types = append(types, v.Elts[len(v.Elts)-1])
+			}
+		}
+	}
+	var names []Node
+	for _, name := range formals {
+		if !isSymbol(name, "&rest") {
+			names = append(names, name)
+		}
+	}
+	defer l.declare(append(names, l.results...))()
+
+	out := l.tail(l.seq(body))
+	if contains(body, "defer") {
+		out = []Node{form("let", &List{Elts: []Node{form("%defers", &Nil{})}},
+			form("unwind-protect", l.progn(out),
+				form("mapc", symbol("#'funcall"), symbol("%defers"))))}
+	}
+	if len(l.results) > 0 {
+		vars := &List{}
+		for i, name := range l.results {
+			vars.Elts = append(vars.Elts, &List{Elts: []Node{name, zero(types[i])}})
+		}
+		out = []Node{form("let", append([]Node{vars}, nonEmpty(out)...)...)}
+	}
+	return out
+}
+
+// tail replaces a return from the current block at the end of body
+// with the values it returns.
+func (l *clLowerer) tail(body []Node) []Node {
+	i := len(body) - 1
+	for i >= 0 {
+		if _, ok := body[i].(*Comment); !ok {
+			break
+		}
+		i--
+	}
+	if i < 0 {
+		return body
+	}
+	x, ok := body[i].(*List)
+	if !ok {
+		return body
+	}
+	out := append([]Node{}, body[:i]...)
+	after := body[i+1:]
+	var start int
+	switch Head(x) {
+	case "return-from":
+		if len(x.Elts) < 2 || !isSymbol(x.Elts[1], l.block.String()) {
+			return body
+		}
+		return append(append(out, x.Elts[2:]...), after...)
+	case "if":
+		if len(x.Elts) != 4 {
+			return body
+		}
+		then, els := l.progn(l.tail(x.Elts[2:3])), l.progn(l.tail(x.Elts[3:4]))
+		return append(append(out, form("if", x.Elts[1], then, els)), after...)
+	case "cond", "case":
+		clauses := []Node{x.Elts[0]}
+		start = 1
+		if Head(x) == "case" {
+			clauses, start = x.Elts[:2], 2
+		}
+		for _, clause := range x.Elts[start:] {
+			if c, ok := clause.(*List); ok && len(c.Elts) > 1 {
+				clause = &List{Position: c.Position, Elts: append(c.Elts[:1:1], nonEmpty(l.tail(c.Elts[1:]))...)}
+			}
+			clauses = append(clauses, clause)
+		}
+		return append(append(out, &List{Position: x.Position, Elts: clauses}), after...)
+	case "progn":
+		start = 1
+	case "when", "unless", "let", "block":
+		start = 2
+	case "multiple-value-bind":
+		start = 3
+	default:
+		return body
+	}
+	if len(x.Elts) < start {
+		return body
+	}
+	lowered := &List{Position: x.Position, Elts: append(x.Elts[:start:start], nonEmpty(l.tail(x.Elts[start:]))...)}
+	return append(append(out, lowered), after...)
+}
+
+// progn returns body as one form.
+func (l *clLowerer) progn(body []Node) Node {
+	if len(body) == 1 {
+		if _, ok := body[0].(*Comment); !ok {
+			return body[0]
+		}
+	}
+	return form("progn", nonEmpty(body)...)
+}
+
+// seq translates a sequence of statements, whose declarations scope
+// over the rest of the sequence.
+func (l *clLowerer) seq(stmts []Node) []Node {
+	for _, x := range stmts {
+		if l.isTarget(x) {
+			return []Node{l.tagbody(stmts)}
+		}
+	}
+	var out []Node
+	for i, x := range stmts {
+		switch Head(x) {
+		case ":=", "var", "const":
+			rest := stmts[i+1:]
+			return append(out, l.bind(x, func() []Node { return l.seq(rest) }))
+		}
+		out = append(out, l.stmt(x)...)
+	}
+	return out
+}
+
+// bind translates a := or a local var or const form, whose scope
+// is what body returns, into let.
+func (l *clLowerer) bind(x Node, body func() []Node) Node {
+	list := x.(*List)
+	if Head(list) == ":=" {
+		if len(list.Elts) < 3 {
+			l.errorf(x, "malformed := form")
+			return l.progn(body())
+		}
+		return l.let(x, names(list.Elts[1]), nil, list.Elts[2:], body)
+	}
+	var specs []valueSpec
+	for _, spec := range l.specs(list) {
+		if spec.comment == nil {
+			specs = append(specs, spec)
+		}
+	}
+	var nest func(specs []valueSpec) []Node
+	nest = func(specs []valueSpec) []Node {
+		if len(specs) == 0 {
+			return body()
+		}
+		spec := specs[0]
+		return []Node{l.let(x, spec.names, spec.typ, spec.values, func() []Node { return nest(specs[1:]) })}
+	}
+	return l.progn(nest(specs))
+}
+
+// let binds names to values, or to the zero value of typ if there
+// are none, around what body returns.
+func (l *clLowerer) let(x Node, names []Node, typ Node, values []Node, body func() []Node) Node {
+	values = l.exprs(values)
+	bound := make([]Node, len(names))
+	for i, name := range names {
+		bound[i] = l.binding(name)
+	}
+	done := l.declare(bound)
+	lowered := nonEmpty(body())
+	done()
+	vars := &List{}
+	switch {
+	case len(values) == 0:
+		for _, name := range bound {
+			vars.Elts = append(vars.Elts, &List{Elts: []Node{name, zero(typ)}})
+		}
+	case len(values) == len(names):
+		for i, name := range bound {
+			vars.Elts = append(vars.Elts, &List{Elts: []Node{name, values[i]}})
+		}
+	case len(values) == 1:
+		return form("multiple-value-bind", append([]Node{&List{Elts: bound}, values[0]}, lowered...)...)
+	default:
+		l.errorf(x, "%d names but %d values", len(names), len(values))
+	}
+	return form("let", append([]Node{vars}, lowered...)...)
+}
+
+func (l *clLowerer) stmt(x Node) []Node {
+	switch x := x.(type) {
+	case *Bool:
+		if !x.Value {
+			// the empty statement
+			return nil
+		}
+	case *List:
+		return l.stmtList(x, "")
+	}
+	return []Node{l.expr(x)}
+}
+
+// stmtList translates the statement x, which is labeled label if
+// that is not "".
+func (l *clLowerer) stmtList(x *List, label string) []Node {
+	elts := x.Elts[1:]
+	switch head := Head(x); head {
+	case "label":
+		if len(elts) != 2 {
+			l.errorf(x, "malformed label form")
+			return nil
+		}
+		if stmt, ok := elts[1].(*List); ok {
+			return l.stmtList(stmt, elts[0].String())
+		}
+		return l.stmt(elts[1])
+	case "when", "unless":
+		return []Node{l.ifStmt(x)}
+	case "when*", "unless*", "cond!*", "case!*", "type!*":
+		if len(elts) < 2 {
+			l.errorf(x, "malformed %s form", head)
+			return nil
+		}
+		stmt := &List{Position: x.Position, Elts: append([]Node{symbol(strings.TrimSuffix(head, "*"))}, elts[1:]...)}
+		return l.withInit(elts[0], func() Node { return l.progn(l.stmtList(stmt, label)) })
+	case "while":
+		if len(elts) < 1 {
+			l.errorf(x, "malformed while form")
+			return nil
+		}
+		return []Node{l.loop(label, elts[0], elts[1:], nil)}
+	case "for":
+		if len(elts) < 3 {
+			l.errorf(x, "malformed for form")
+			return nil
+		}
+		return l.withInit(elts[0], func() Node { return l.loop(label, elts[1], elts[3:], elts[2]) })
+	case "range":
+		return []Node{l.rangeStmt(x, label)}
+	case "cond!", "case!", "type!", "comm!":
+		return []Node{l.switchStmt(x, label)}
+	case "goto":
+		if len(elts) != 1 {
+			l.errorf(x, "malformed goto form")
+			return nil
+		}
+		return []Node{form("go", elts[0])}
+	case "break", "continue":
+		if len(elts) == 1 {
+			return []Node{form("return-from", symbol("%"+head+"-"+elts[0].String()))}
+		}
+		return []Node{form("return-from", symbol("%"+head))}
+	case "return":
+		values := l.results
+		if len(elts) > 0 {
+			values = l.exprs(elts)
+		}
+		switch len(values) {
+		case 0:
+			return []Node{form("return-from", l.block)}
+		case 1:
+			return []Node{form("return-from", l.block, values[0])}
+		}
+		return []Node{form("return-from", l.block, form("values", values...))}
+	case "fallthrough":
+		l.errorf(x, "fallthrough outside a switch clause")
+		return nil
+	case "go":
+		if len(elts) != 1 {
+			l.errorf(x, "malformed go form")
+			return nil
+		}
+		return []Node{form("goroutine", l.thunk(elts[0]))}
+	case "defer":
+		if len(elts) != 1 {
+			l.errorf(x, "malformed defer form")
+			return nil
+		}
+		return []Node{form("push", l.thunk(elts[0]), symbol("%defers"))}
+	case ":=", "var", "const":
+		return []Node{l.bind(x, func() []Node { return nil })}
+	case "type":
+		return nil
+	case "=", "++", "--":
+		return l.assign(x)
+	default:
+		if len(elts) == 2 && strings.HasSuffix(head, "=") && head != "=" {
+			return l.assign(x)
+		}
+	}
+	return []Node{l.expr(x)}
+}
+
+// withInit translates the init statement of an if, for or switch
+// around what stmt returns.
+func (l *clLowerer) withInit(init Node, stmt func() Node) []Node {
+	switch Head(init) {
+	case ":=", "var", "const":
+		return []Node{l.bind(init, func() []Node { return []Node{stmt()} })}
+	}
+	return append(l.stmt(init), stmt())
+}
+
+// ifStmt translates (when c body... (else alt...)) and unless.
+func (l *clLowerer) ifStmt(x *List) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed %s form", Head(x))
+		return &Nil{}
+	}
+	cond, body := l.expr(x.Elts[1]), x.Elts[2:]
+	if n := len(body); n > 0 && Head(body[n-1]) == "else" {
+		then, els := l.progn(l.seq(body[:n-1])), l.progn(l.seq(body[n-1].(*List).Elts[1:]))
+		if Head(x) == "unless" {
+			then, els = els, then
+		}
+		return form("if", cond, then, els)
+	}
+	lowered := l.seq(body)
+	if len(lowered) == 0 {
+		return cond
+	}
+	return form(Head(x), append([]Node{cond}, lowered...)...)
+}
+
+// loop translates a loop with the given condition and body into
+// loop, which leaves when the condition is false. post runs after
+// each iteration, including those ended by continue.
+func (l *clLowerer) loop(label string, cond Node, body []Node, post Node) Node {
+	iter := l.escape("continue", label, body, loopForms, l.seq(body))
+	if post != nil {
+		iter = append(iter, l.stmt(post)...)
+	}
+	if !isBool(cond, true) {
+		iter = append([]Node{form("unless", l.expr(cond), form("return"))}, iter...)
+	}
+	loop := form("loop", compound(iter)...)
+	return l.progn(l.escape("break", label, body, breakForms, []Node{loop}))
+}
+
+// compound returns the forms in body that are not atoms, which
+// loop and tagbody would take for keywords and tags.
+func compound(body []Node) []Node {
+	var out []Node
+	for _, x := range body {
+		switch x.(type) {
+		case *List, *Comment:
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// escape wraps lowered in (block %name ...) if the statements body
+// leave it with (name) outside any of the nested forms, and in
+// (block %name-label ...) if they leave it with (name label).
+func (l *clLowerer) escape(name, label string, body []Node, nested map[string]bool, lowered []Node) []Node {
+	plain, labeled := exits(name, label, body, nested)
+	if labeled {
+		lowered = []Node{form("block", append([]Node{symbol("%" + name + "-" + label)}, nonEmpty(lowered)...)...)}
+	}
+	if plain {
+		lowered = []Node{form("block", append([]Node{symbol("%" + name)}, nonEmpty(lowered)...)...)}
+	}
+	return lowered
+}
+
+// rangeStmt translates (range [(:= vars x)] body...) into a call
+// of range-for-each with a function of the key and value.
+func (l *clLowerer) rangeStmt(x *List, label string) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed range form")
+		return &Nil{}
+	}
+	spec, body := x.Elts[1], x.Elts[2:]
+	subject := spec
+	formals := []Node{symbol("%k"), symbol("%v")}
+	var pre []Node
+	if head := Head(spec); (head == ":=" || head == "=") && len(spec.(*List).Elts) == 3 {
+		vars := names(spec.(*List).Elts[1])
+		subject = spec.(*List).Elts[2]
+		for i := 0; i < len(vars) && i < 2; i++ {
+			if head == ":=" {
+				formals[i] = l.binding(vars[i])
+			} else if !isSymbol(vars[i], "_") {
+				pre = append(pre, form("=", vars[i], formals[i]))
+			}
+		}
+	}
+	subject = l.expr(subject)
+	done := l.declare(formals)
+	var lowered []Node
+	for _, stmt := range pre {
+		lowered = append(lowered, l.stmt(stmt)...)
+	}
+	lowered = append(lowered, l.escape("continue", label, body, loopForms, l.seq(body))...)
+	done()
+	fn := form("lambda", append([]Node{&List{Elts: formals}}, nonEmpty(lowered)...)...)
+	loop := form("range-for-each", fn, subject)
+	return l.progn(l.escape("break", label, body, breakForms, []Node{loop}))
+}
+
+// switchStmt translates cond!, case!, type! and comm!.
+func (l *clLowerer) switchStmt(x *List, label string) Node {
+	var lowered Node
+	var body []Node
+	for _, clause := range x.Elts[1:] {
+		if list, ok := clause.(*List); ok {
+			body = append(body, list.Elts...)
+		}
+	}
+	switch Head(x) {
+	case "cond!":
+		lowered = l.condSwitch(x.Elts[1:])
+	case "case!":
+		lowered = l.caseSwitch(x)
+	case "type!":
+		lowered = l.typeSwitch(x)
+	case "comm!":
+		lowered = l.selectStmt(x.Elts[1:])
+	}
+	return l.progn(l.escape("break", label, body, breakForms, []Node{lowered}))
+}
+
+// condSwitch translates the clauses of cond! into cond.
+func (l *clLowerer) condSwitch(list []Node) Node {
+	out := []Node{symbol("cond")}
+	for i, body := range clauses(list) {
+		clause, ok := list[i].(*List)
+		if !ok || len(clause.Elts) == 0 {
+			out = append(out, list[i])
+			continue
+		}
+		var test Node = symbol("t")
+		if !isSymbol(clause.Elts[0], "else") {
+			test = l.expr(clause.Elts[0])
+		}
+		out = append(out, &List{Position: clause.Position, Elts: append([]Node{test}, l.seq(body)...)})
+	}
+	return &List{Elts: out}
+}
+
+// caseSwitch translates (case! tag ((values...) body...)...) into
+// case when every value is a literal that eql compares, and
+// otherwise into cond over equal.
+func (l *clLowerer) caseSwitch(x *List) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed case! form")
+		return &Nil{}
+	}
+	tag, list := x.Elts[1], x.Elts[2:]
+	literal := true
+	for _, clause := range list {
+		if values, ok := clause.(*List); ok && len(values.Elts) > 0 {
+			if v, ok := values.Elts[0].(*List); ok {
+				for _, value := range v.Elts {
+					switch value.(type) {
+					case *Number, *Char, *Bool:
+					default:
+						literal = false
+					}
+				}
+			}
+		}
+	}
+	if literal {
+		out := []Node{symbol("case"), l.expr(tag)}
+		for i, body := range clauses(list) {
+			clause, ok := list[i].(*List)
+			if !ok || len(clause.Elts) == 0 {
+				out = append(out, list[i])
+				continue
+			}
+			keys := clause.Elts[0]
+			if isSymbol(keys, "else") {
+				keys = symbol("otherwise")
+			}
+			out = append(out, &List{Position: clause.Position, Elts: append([]Node{keys}, l.seq(body)...)})
+		}
+		return &List{Elts: out}
+	}
+	return l.temp("%tag", tag, func(tag Node) Node {
+		var conds []Node
+		for _, clause := range list {
+			c, ok := clause.(*List)
+			if !ok || len(c.Elts) == 0 || isSymbol(c.Elts[0], "else") {
+				conds = append(conds, clause)
+				continue
+			}
+			var tests []Node
+			if v, ok := c.Elts[0].(*List); ok {
+				for _, value := range v.Elts {
+					tests = append(tests, form("equal?", tag, value))
+				}
+			}
+			conds = append(conds, &List{Position: c.Position, Elts: append([]Node{or(tests)}, c.Elts[1:]...)})
+		}
+		return l.condSwitch(conds)
+	})
+}
+
+// temp calls f with the expression x if it is a symbol, and
+// otherwise binds name to its value around what f returns for name.
+func (l *clLowerer) temp(name string, x Node, f func(Node) Node) Node {
+	if _, ok := x.(*Symbol); ok {
+		return f(x)
+	}
+	return form("let", &List{Elts: []Node{form(name, l.expr(x))}}, f(symbol(name)))
+}
+
+// typeSwitch translates (type! [(:= v] (as x type)[)] ((types...)
+// body...)...) into cond over type-is?, binding v in each clause.
+func (l *clLowerer) typeSwitch(x *List) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed type! form")
+		return &Nil{}
+	}
+	guard := x.Elts[1]
+	var v Node
+	if Head(guard) == ":=" && len(guard.(*List).Elts) == 3 {
+		v, guard = guard.(*List).Elts[1], guard.(*List).Elts[2]
+	}
+	if Head(guard) != "as" || len(guard.(*List).Elts) != 3 {
+		l.errorf(guard, "malformed type! guard %s", guard)
+		return &Nil{}
+	}
+	list := x.Elts[2:]
+	return l.temp("%x", guard.(*List).Elts[1], func(subject Node) Node {
+		out := []Node{symbol("cond")}
+		for i, body := range clauses(list) {
+			clause, ok := list[i].(*List)
+			if !ok || len(clause.Elts) == 0 {
+				out = append(out, list[i])
+				continue
+			}
+			var test Node = symbol("t")
+			if types, ok := clause.Elts[0].(*List); ok {
+				var tests []Node
+				for _, typ := range types.Elts {
+					if _, ok := typ.(*Nil); ok {
+						tests = append(tests, form("null", subject))
+					} else {
+						tests = append(tests, form("type-is?", subject, typ))
+					}
+				}
+				test = or(tests)
+			}
+			var lowered []Node
+			if v != nil {
+				done := l.declare([]Node{v})
+				lowered = []Node{form("let", append([]Node{&List{Elts: []Node{&List{Elts: []Node{v, subject}}}}}, nonEmpty(l.seq(body))...)...)}
+				done()
+			} else {
+				lowered = l.seq(body)
+			}
+			out = append(out, &List{Position: clause.Position, Elts: append([]Node{test}, lowered...)})
+		}
+		return &List{Elts: out}
+	})
+}
+
+// selectStmt translates the clauses of comm! into a call of select.
+func (l *clLowerer) selectStmt(list []Node) Node {
+	cases := []Node{symbol("list")}
+	var otherwise Node = &Nil{}
+	for _, x := range list {
+		clause, ok := x.(*List)
+		if !ok || len(clause.Elts) == 0 {
+			continue
+		}
+		comm, body := clause.Elts[0], clause.Elts[1:]
+		if isSymbol(comm, "else") {
+			otherwise = form("lambda", append([]Node{&List{}}, nonEmpty(l.seq(body))...)...)
+			continue
+		}
+		formals := []Node{symbol("%v"), symbol("%ok")}
+		var pre []Node
+		elts := []Node{nil}
+		if list, ok := comm.(*List); ok {
+			elts = list.Elts
+		}
+		switch head := Head(comm); {
+		case head == "<-!" && len(elts) == 3:
+			fn := form("lambda", append([]Node{&List{}}, nonEmpty(l.seq(body))...)...)
+			cases = append(cases, form("send-case", l.expr(elts[1]), l.expr(elts[2]), fn))
+			continue
+		case (head == ":=" || head == "=") && len(elts) == 3:
+			vars := names(elts[1])
+			for i := 0; i < len(vars) && i < 2; i++ {
+				if head == ":=" {
+					formals[i] = l.binding(vars[i])
+				} else if !isSymbol(vars[i], "_") {
+					pre = append(pre, form("=", vars[i], formals[i]))
+				}
+			}
+			comm, elts = elts[2], []Node{nil}
+			if list, ok := comm.(*List); ok {
+				elts = list.Elts
+			}
+		}
+		if Head(comm) != "<-" || len(elts) != 2 {
+			l.errorf(comm, "malformed comm! clause %s", comm)
+			continue
+		}
+		ch := l.expr(elts[1])
+		done := l.declare(formals)
+		var lowered []Node
+		for _, stmt := range pre {
+			lowered = append(lowered, l.stmt(stmt)...)
+		}
+		lowered = append(lowered, l.seq(body)...)
+		done()
+		fn := form("lambda", append([]Node{&List{Elts: formals}}, nonEmpty(lowered)...)...)
+		cases = append(cases, form("recv-case", ch, fn))
+	}
+	return form("select", &List{Elts: cases}, otherwise)
+}
+
+// tagbody translates a sequence of statements holding goto
+// targets into a tagbody with a tag for each target, which
+// (go L) jumps to. Go does not let a goto jump over a declaration,
+// so the sequence's declarations can be hoisted to its start and
+// become assignments.
+func (l *clLowerer) tagbody(stmts []Node) Node {
+	vars := &List{}
+	var body []Node
+	for _, x := range stmts {
+		switch Head(x) {
+		case ":=":
+			elts := x.(*List).Elts
+			if len(elts) < 3 {
+				l.errorf(x, "malformed := form")
+				continue
+			}
+			for _, name := range names(elts[1]) {
+				if !isSymbol(name, "_") {
+					vars.Elts = append(vars.Elts, name)
+				}
+			}
+			body = append(body, &List{Position: x.Pos(), Elts: append([]Node{symbol("=")}, elts[1:]...)})
+		case "var", "const":
+			for _, spec := range l.specs(x.(*List)) {
+				if spec.comment != nil {
+					continue
+				}
+				var lhs Node = &List{Elts: spec.names}
+				if len(spec.names) == 1 {
+					lhs = spec.names[0]
+				}
+				values := spec.values
+				if len(values) == 0 {
+					for range spec.names {
+						values = append(values, zero(spec.typ))
+					}
+				}
+				for _, name := range spec.names {
+					if !isSymbol(name, "_") {
+						vars.Elts = append(vars.Elts, name)
+					}
+				}
+				body = append(body, form("=", append([]Node{lhs}, values...)...))
+			}
+		default:
+			body = append(body, x)
+		}
+	}
+	defer l.declare(vars.Elts)()
+	tb := form("tagbody")
+	for _, x := range body {
+		if l.isTarget(x) {
+			tb.Elts = append(tb.Elts, x.(*List).Elts[1])
+		}
+		tb.Elts = append(tb.Elts, compound(l.stmt(x))...)
+	}
+	if len(vars.Elts) == 0 {
+		return tb
+	}
+	return form("let", vars, tb)
+}
+
+// thunk returns a function of no arguments that makes the call x,
+// whose function and arguments are evaluated at once, as go and
+// defer do.
+func (l *clLowerer) thunk(x Node) Node {
+	call, ok := l.expr(x).(*List)
+	if !ok || len(call.Elts) == 0 {
+		l.errorf(x, "expected call, found %s", x)
+		return form("lambda", &List{}, x)
+	}
+	vars := &List{}
+	elts := call.Elts[:1:1]
+	for i, arg := range call.Elts[1:] {
+		switch arg.(type) {
+		case *Number, *String, *Char, *Bool, *Nil:
+			elts = append(elts, arg)
+			continue
+		}
+		if i == 1 && Head(call) == "call-method" {
+			// the method name
+			elts = append(elts, arg)
+			continue
+		}
+		name := symbol("%" + strconv.Itoa(len(vars.Elts)))
+		vars.Elts = append(vars.Elts, &List{Elts: []Node{name, arg}})
+		elts = append(elts, name)
+	}
+	lambda := form("lambda", &List{}, &List{Position: call.Position, Elts: elts})
+	if len(vars.Elts) == 0 {
+		return lambda
+	}
+	return form("let", vars, lambda)
+}
+
+// assign translates =, compound assignments, ++ and -- into setf
+// of the target as a place, psetf for several, and incf and decf.
+func (l *clLowerer) assign(x *List) []Node {
+	elts := x.Elts[1:]
+	switch head := Head(x); head {
+	case "++", "--":
+		if len(elts) != 1 {
+			break
+		}
+		op := map[string]string{"++": "incf", "--": "decf"}[head]
+		return []Node{form(op, l.place(elts[0]))}
+	case "=":
+		if len(elts) < 2 {
+			break
+		}
+		targets, values := names(elts[0]), l.exprs(elts[1:])
+		blank := false
+		for _, target := range targets {
+			blank = blank || isSymbol(target, "_")
+		}
+		switch {
+		case len(targets) == 1 && len(values) == 1:
+			if blank {
+				return values
+			}
+			return []Node{form("setf", l.place(targets[0]), values[0])}
+		case len(targets) == len(values) && !blank:
+			set := form("psetf")
+			for i, target := range targets {
+				set.Elts = append(set.Elts, l.place(target), values[i])
+			}
+			return []Node{set}
+		case len(targets) == len(values):
+			// evaluate every value before assigning any
+			vars := &List{}
+			var sets []Node
+			for i, target := range targets {
+				name := symbol("%" + strconv.Itoa(i))
+				vars.Elts = append(vars.Elts, &List{Elts: []Node{name, values[i]}})
+				if !isSymbol(target, "_") {
+					sets = append(sets, form("setf", l.place(target), name))
+				}
+			}
+			if len(sets) == 0 {
+				return values
+			}
+			return []Node{form("let", append([]Node{vars}, sets...)...)}
+		case len(values) == 1 && !blank:
+			places := form("values")
+			for _, target := range targets {
+				places.Elts = append(places.Elts, l.place(target))
+			}
+			return []Node{form("setf", places, values[0])}
+		case len(values) == 1:
+			formals := &List{}
+			var sets []Node
+			for i, target := range targets {
+				name := symbol("%" + strconv.Itoa(i))
+				formals.Elts = append(formals.Elts, name)
+				if !isSymbol(target, "_") {
+					sets = append(sets, form("setf", l.place(target), name))
+				}
+			}
+			return []Node{form("multiple-value-bind", append([]Node{formals, values[0]}, sets...)...)}
+		}
+	default:
+		if len(elts) != 2 || len(names(elts[0])) != 1 {
+			break
+		}
+		place, value := l.place(elts[0]), l.expr(elts[1])
+		switch op := strings.TrimSuffix(head, "="); op {
+		case "+":
+			return []Node{form("incf", place, value)}
+		case "-":
+			return []Node{form("decf", place, value)}
+		default:
+			return []Node{form("setf", place, l.expr(form(op, elts[0], elts[1])))}
+		}
+	}
+	l.errorf(x, "malformed %s form", Head(x))
+	return nil
+}
+
+// place translates the target of an assignment into a place that
+// setf can assign: a variable, or a form such as (dot x f) or
+// (index a i) for which go/builtin defines a setf function.
+func (l *clLowerer) place(target Node) Node {
+	if _, ok := target.(*Symbol); ok {
+		return target
+	}
+	return l.expr(target)
+}
+
+// helper function
+func (l *clLowerer) exprs(list []Node) []Node {
+	out := make([]Node, len(list))
+	for i, x := range list {
+		out[i] = l.expr(x)
+	}
+	return out
+}
+
+// expr translates the expression x: function literals become
+// lambdas, the operators CL spells differently are renamed, calls
+// of variables use funcall, and the functions the package defines
+// are referred to as #'f where they are values. The types in x are
+// left alone.
+func (l *clLowerer) expr(x Node) Node {
+	switch x := x.(type) {
+	case *Symbol:
+		if l.vars[x.Name] == 0 && l.funcs[x.Name] {
+			return &Symbol{Position: x.Position, Name: "#'" + x.Name}
+		}
+		return x
+	case *Vector:
+		// a composite literal, whose first element is its type
+		if len(x.Elts) == 0 {
+			return x
+		}
+		elts := x.Elts[:1:1]
+		for _, elt := range x.Elts[1:] {
+			if kv, ok := elt.(*List); ok && Head(kv) == ":" && len(kv.Elts) == 3 {
+				// a field name stays as it is
+				key := kv.Elts[1]
+				if _, ok := key.(*Symbol); !ok {
+					key = l.expr(key)
+				}
+				elts = append(elts, &List{Position: kv.Position, Elts: []Node{kv.Elts[0], key, l.expr(kv.Elts[2])}})
+				continue
+			}
+			elts = append(elts, l.expr(elt))
+		}
+		return &Vector{Position: x.Position, Elts: elts}
+	case *List:
+		if len(x.Elts) == 0 {
+			return x
+		}
+		// the leading elements that are types or names, not
+		// expressions
+		keep := 1
+		switch head := Head(x); head {
+		case "func", "func...":
+			return l.lambda(x)
+		case "convert", "make", "new", "method-expr":
+			keep = 2
+		case "as":
+			if len(x.Elts) == 3 {
+				return &List{Position: x.Position, Elts: []Node{x.Elts[0], l.expr(x.Elts[1]), x.Elts[2]}}
+			}
+		case "dot", "method", "call-method":
+			if len(x.Elts) >= 3 {
+				elts := []Node{x.Elts[0], l.expr(x.Elts[1]), x.Elts[2]}
+				return &List{Position: x.Position, Elts: append(elts, l.exprs(x.Elts[3:])...)}
+			}
+		case "inst":
+			return x
+		case "apply...":
+			if len(x.Elts) >= 2 {
+				fn := l.expr(x.Elts[1])
+				if sym, ok := fn.(*Symbol); ok && l.vars[sym.Name] == 0 && !strings.HasPrefix(sym.Name, "#'") {
+					fn = &Symbol{Position: sym.Position, Name: "#'" + sym.Name}
+				}
+				return &List{Position: x.Position, Elts: append([]Node{symbol("apply"), fn}, l.exprs(x.Elts[2:])...)}
+			}
+		case "string-append":
+			return &List{Position: x.Position, Elts: append([]Node{symbol("concatenate"), symbol("'string")}, l.exprs(x.Elts[1:])...)}
+		default:
+			if op, ok := clOps[head]; ok {
+				return &List{Position: x.Position, Elts: append([]Node{symbol(op)}, l.exprs(x.Elts[1:])...)}
+			}
+			sym, isSym := x.Elts[0].(*Symbol)
+			if !isSym || l.vars[sym.Name] > 0 {
+				// a call of a function value
+				return &List{Position: x.Position, Elts: append([]Node{symbol("funcall")}, l.exprs(x.Elts)...)}
+			}
+		}
+		if keep > len(x.Elts) {
+			keep = len(x.Elts)
+		}
+		return &List{Position: x.Position, Elts: append(x.Elts[:keep:keep], l.exprs(x.Elts[keep:])...)}
+	}
+	return x
+}
+
+// spell writes the literals in x in CL syntax, and the names of
+// imported packages' members as pkg:Name.
+func (l *clLowerer) spell(x Node) Node {
+	switch x := x.(type) {
+	case *List:
+		elts := make([]Node, len(x.Elts))
+		for i, elt := range x.Elts {
+			elts[i] = l.spell(elt)
+		}
+		return &List{Position: x.Position, Elts: elts}
+	case *Symbol:
+		quote := ""
+		name := x.Name
+		if strings.HasPrefix(name, "#'") {
+			quote, name = "#'", name[2:]
+		}
+		if i := strings.IndexByte(name, '.'); i > 0 && l.prefixes[name[:i]] {
+			return &Symbol{Position: x.Position, Name: quote + name[:i] + ":" + name[i+1:]}
+		}
+		return x
+	case *Comment:
+		return x
+	}
+	return clLiteral(x)
+}
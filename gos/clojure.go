@@ -0,0 +1,1874 @@
+package gos
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Clojure is a Backend that writes each package as a Clojure
+// namespace named go.elem... after its import path, found as
+// go/elem/....clj on the classpath. Struct types become records,
+// interfaces protocols that the records with their methods extend,
+// functions defns, and package variables and constants defs.
+// Statements use Clojure's own forms where they can: let, if,
+// cond, case and while. Blocks that return, break and continue
+// leave early are (block :name ...), left by (return-from :name
+// v), and locals that Go assigns are volatiles; so are records
+// whose fields a function assigns, which it replaces with assoc, as
+// records cannot be changed. Channel operations
+// and select use core.async, with the blocking operations of
+// threads, as goroutines run in async/thread; what else Go does at
+// run time is left to the namespace go.builtin, as for R7RS.
+//
+// Clojure has no goto, so a function that uses one is reported as
+// an error.
+var Clojure Backend = clojureBackend{}
+
+type clojureBackend struct{ gosBackend }
+
+func (clojureBackend) Name() string { return "clojure" }
+func (clojureBackend) Ext() string  { return ".clj" }
+
+// libraryFile returns the path of the namespace of the package,
+// go/elem/....clj, as Clojure finds a namespace by its name.
+func (clojureBackend) libraryFile(importPath string) string {
+	name := cljNamespace(importPath)
+	return strings.NewReplacer(".", "/", "-", "_").Replace(name) + ".clj"
+}
+
+// cljReserved holds the Go identifiers that name the special forms
+// and clojure.core functions and macros the translation calls.
+var cljReserved = map[string]bool{
+	"apply": true, "block": true, "catch": true, "condp": true,
+	"conj": true, "declare": true, "def": true, "defn": true,
+	"defprotocol": true, "defrecord": true, "do": true,
+	"extend": true, "finally": true, "fn": true, "let": true,
+	"loop": true, "ns": true, "quot": true, "quote": true,
+	"recur": true, "rem": true, "str": true, "throw": true,
+	"try": true,
+}
+
+// cljBuiltinExcludes holds the names that both clojure.core and
+// go.builtin define, with their Go meaning in the latter.
+var cljBuiltinExcludes = []string{"max", "min", "print", "println"}
+
+// cljCore holds the clojure.core names that a Go package is apt
+// to define itself, which its namespace then excludes.
+var cljCore = map[string]bool{
+	"assoc": true, "char": true, "compare": true, "count": true,
+	"dec": true, "empty": true, "filter": true, "find": true,
+	"first": true, "format": true, "get": true, "hash": true,
+	"inc": true, "int": true, "key": true, "keys": true, "last": true,
+	"list": true, "load": true, "map": true, "merge": true,
+	"name": true, "next": true, "peek": true, "pop": true,
+	"range": true, "read": true, "reduce": true, "remove": true,
+	"replace": true, "rest": true, "reverse": true, "second": true,
+	"seq": true, "set": true, "shuffle": true, "sort": true,
+	"split": true, "test": true, "time": true, "type": true,
+	"update": true, "val": true, "vals": true, "vector": true,
+}
+
+// cljOps maps the Gos operators that Clojure spells differently.
+var cljOps = map[string]string{
+	"<-":            "async/<!!",
+	"<-!":           "async/>!!",
+	"bitwise-and":   "bit-and",
+	"bitwise-but":   "bit-and-not",
+	"bitwise-not":   "bit-not",
+	"bitwise-or":    "bit-or",
+	"bitwise-xor":   "bit-xor",
+	"eqv?":          "identical?",
+	"equal?":        "=",
+	"new":           "go-new",
+	"quotient":      "quot",
+	"remainder":     "rem",
+	"string-append": "str",
+	"string=?":      "=",
+}
+
+func (clojureBackend) Ident(name string) string {
+	if cljReserved[name] {
+		return reservedPrefix + name
+	}
+	return UnmangleName(name)
+}
+
+func (clojureBackend) Package(pkg *List, importPath string) ([]Node, error) {
+	lib, err := newLibrary(pkg, importPath, LowerNone)
+	if err != nil {
+		return nil, err
+	}
+	l := &cljLowerer{
+		lowerer:   &lowerer{level: LowerR7RS, prefixes: make(map[string]bool)},
+		types:     make(map[string]Node),
+		protocols: make(map[string]int),
+		locals:    make(map[string][]cljLocal),
+	}
+	for _, spec := range lib.imports {
+		l.prefixes[spec.prefix()] = true
+	}
+	methods := make(map[string][]string)
+	var order []string
+	for _, x := range lib.body {
+		switch Head(x) {
+		case "func", "func...":
+			recv, elts := funcRecv(x.(*List))
+			if recv != nil && len(elts) >= 3 {
+				typ := typeName(cljReceiverType(recv))
+				methods[typ] = append(methods[typ], elts[0].String())
+			}
+		case "type":
+			for _, spec := range typeSpecs(x.(*List)) {
+				l.types[spec.name] = spec.typ
+				order = append(order, spec.name)
+				if Head(spec.typ) == "interface" {
+					for _, elt := range spec.typ.(*List).Elts[1:] {
+						if v, ok := elt.(*Vector); ok && len(v.Elts) == 2 {
+							l.protocols[v.Elts[0].String()] = l.fixed(v.Elts[1])
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// types come first, as Clojure compiles a form only after
+	// those before it
+	var types, decls, comments []Node
+	for _, x := range lib.body {
+		if _, ok := x.(*Comment); ok {
+			comments = append(comments, x)
+			continue
+		}
+		lowered := append(comments, l.decl(x)...)
+		comments = nil
+		if Head(x) == "type" {
+			types = append(types, lowered...)
+		} else {
+			decls = append(decls, lowered...)
+		}
+	}
+	decls = append(decls, comments...)
+	declare := form("declare")
+	for _, x := range decls {
+		if name := cljDefined(x); name != "" {
+			declare.Elts = append(declare.Elts, symbol(name))
+		}
+	}
+	out := types
+	if len(declare.Elts) > 1 {
+		out = append(out, declare)
+	}
+	out = append(out, decls...)
+	out = append(out, l.extends(order, methods)...)
+	for i, x := range out {
+		out[i] = l.spell(x)
+	}
+	return append([]Node{cljNs(pkg, lib, out)}, out...), l.diags.Err()
+}
+
+// cljNamespace returns the namespace go.elem... for importPath.
+func cljNamespace(importPath string) string {
+	return strings.Join(append([]string{"go"}, libraryElems(importPath)...), ".")
+}
+
+// cljNs returns the ns form that begins the file of lib, whose
+// definitions are body.
+func cljNs(pkg *List, lib *library, body []Node) Node {
+	exclude := &Brackets{}
+	for _, name := range cljBuiltinExcludes {
+		exclude.Elts = append(exclude.Elts, symbol(name))
+	}
+	for _, x := range body {
+		if name := cljDefined(x); cljCore[name] {
+			exclude.Elts = append(exclude.Elts, symbol(name))
+		}
+	}
+	require := form(":require")
+	if cljUses(body, "async/") {
+		require.Elts = append(require.Elts, &Brackets{Elts: []Node{symbol("clojure.core.async"), symbol(":as"), symbol("async")}})
+	}
+	require.Elts = append(require.Elts, &Brackets{Elts: []Node{symbol(cljNamespace("builtin")), symbol(":refer"), symbol(":all")}})
+	for _, spec := range lib.imports {
+		name := symbol(cljNamespace(spec.path))
+		switch spec.alias {
+		case ".":
+			require.Elts = append(require.Elts, &Brackets{Elts: []Node{name, symbol(":refer"), symbol(":all")}})
+		case "_":
+			require.Elts = append(require.Elts, name)
+		default:
+			require.Elts = append(require.Elts, &Brackets{Elts: []Node{name, symbol(":as"), symbol(spec.prefix())}})
+		}
+	}
+	ns := form("ns", symbol(cljNamespace(lib.path)), form(":refer-clojure", symbol(":exclude"), exclude), require)
+	ns.Position = pkg.Position
+	return ns
+}
+
+// cljUses reports whether a symbol in body begins with prefix.
+func cljUses(body []Node, prefix string) bool {
+	for _, x := range body {
+		switch x := x.(type) {
+		case *Symbol:
+			if strings.HasPrefix(x.Name, prefix) {
+				return true
+			}
+		case *List:
+			if cljUses(x.Elts, prefix) {
+				return true
+			}
+		case *Brackets:
+			if cljUses(x.Elts, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cljDefined returns the name that the top-level def or defn x
+// defines, or "".
+func cljDefined(x Node) string {
+	list, ok := x.(*List)
+	if !ok || len(list.Elts) < 2 {
+		return ""
+	}
+	switch Head(list) {
+	case "def", "defn", "defn-":
+		name := list.Elts[1]
+		if isSymbol(name, "^:private") && len(list.Elts) > 2 {
+			name = list.Elts[2]
+		}
+		return name.String()
+	}
+	return ""
+}
+
+// cljReceiverType returns the type of the receiver of a method.
+func cljReceiverType(recv Node) Node {
+	if v, ok := recv.(*Vector); ok && len(v.Elts) == 2 {
+		return v.Elts[1]
+	}
+	return recv
+}
+
+// cljProtocolMethods returns the names of the methods that the
+// (interface #(M (func ...))...) form x declares, leaving out
+// those of the interfaces it embeds.
+func cljProtocolMethods(x *List) []string {
+	var out []string
+	for _, elt := range x.Elts[1:] {
+		if v, ok := elt.(*Vector); ok && len(v.Elts) == 2 {
+			out = append(out, v.Elts[0].String())
+		}
+	}
+	return out
+}
+
+// fixed returns the number of fixed parameters of the variadic
+// function type sig, or -1 if it is not variadic. A protocol
+// function cannot take rest arguments, so those of a variadic
+// method are passed to it as one vector.
+func (l *cljLowerer) fixed(sig Node) int {
+	list, ok := sig.(*List)
+	if !ok || Head(list) != "func..." || len(list.Elts) < 2 {
+		return -1
+	}
+	return len(l.params(list.Elts[1], false)) - 1
+}
+
+// cljLowerer translates the Gos declarations of a package into
+// Clojure. It reuses the parsing of specs and parameters of a
+// lowerer.
+type cljLowerer struct {
+	*lowerer
+	types     map[string]Node       // the package's types
+	protocols map[string]int        // the package's interface methods, as fixed counts them
+	locals    map[string][]cljLocal // the locals in scope, innermost last
+	returns   bool                  // whether the function returns before its end
+}
+
+// cljLocal is what is known of a local: whether it is held in a
+// volatile, and the type of the record it holds if it is one of
+// the package's struct values rather than a pointer to one.
+type cljLocal struct {
+	volatile bool
+	record   Node
+}
+
+// declare puts names in scope, as volatiles where volatile says
+// so and as records of the types records gives, if it is not nil,
+// and returns a function that takes them out again.
+func (l *cljLowerer) declare(names []Node, volatile []bool, records []Node) func() {
+	for i, name := range names {
+		local := cljLocal{volatile: volatile[i]}
+		if records != nil {
+			local.record = records[i]
+		}
+		l.locals[name.String()] = append(l.locals[name.String()], local)
+	}
+	return func() {
+		for _, name := range names {
+			scopes := l.locals[name.String()]
+			l.locals[name.String()] = scopes[:len(scopes)-1]
+		}
+	}
+}
+
+// isVolatile reports whether the symbol x names a local volatile.
+func (l *cljLowerer) isVolatile(x Node) bool {
+	sym, ok := x.(*Symbol)
+	if !ok {
+		return false
+	}
+	scopes := l.locals[sym.Name]
+	return len(scopes) > 0 && scopes[len(scopes)-1].volatile
+}
+
+// recordType returns the type of the record the symbol x names, or
+// nil if it is not a local record.
+func (l *cljLowerer) recordType(x Node) Node {
+	sym, ok := x.(*Symbol)
+	if !ok {
+		return nil
+	}
+	if scopes := l.locals[sym.Name]; len(scopes) > 0 {
+		return scopes[len(scopes)-1].record
+	}
+	return nil
+}
+
+// record returns typ if it is one of the package's struct types,
+// whose values are records, and otherwise nil.
+func (l *cljLowerer) record(typ Node) Node {
+	if _, ok := typ.(*Symbol); ok && Head(l.types[typ.String()]) == "struct" {
+		return typ
+	}
+	return nil
+}
+
+// recordVolatiles is volatiles for names that are records where
+// records says so, which must also be volatiles if scope assigns
+// one of their fields, as a record is changed by replacing it.
+func recordVolatiles(names []Node, records []Node, scope []Node) []bool {
+	out := volatiles(names, scope)
+	for i, name := range names {
+		if records != nil && records[i] != nil && fieldAssigned(name.String(), scope) {
+			out[i] = true
+		}
+	}
+	return out
+}
+
+// fieldAssigned reports whether the statements body assign a field
+// of name, or a field of one of its fields.
+func fieldAssigned(name string, body []Node) bool {
+	found := false
+	walk(body, func(x *List) bool {
+		var targets []Node
+		switch head := Head(x); {
+		case len(x.Elts) < 2:
+		case head == "=":
+			targets = names(x.Elts[1])
+		case head == "++", head == "--":
+			targets = x.Elts[1:2]
+		case head == ":=", head == "<=", head == ">=":
+		case strings.HasSuffix(head, "=") && len(x.Elts) == 3:
+			targets = x.Elts[1:2]
+		}
+		for _, target := range targets {
+			if root, path := fieldPath(target); len(path) > 0 && isSymbol(root, name) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// fieldPath returns the variable and the fields that the selection
+// (dot (dot x f) g) selects: x and [f g]. For anything but a
+// selection, it returns x itself and no fields.
+func fieldPath(x Node) (Node, []Node) {
+	list, ok := x.(*List)
+	if !ok || Head(list) != "dot" || len(list.Elts) != 3 {
+		return x, nil
+	}
+	root, path := fieldPath(list.Elts[1])
+	return root, append(path, list.Elts[2])
+}
+
+// volatiles returns which of names the statements scope assign or
+// take the address of.
+func volatiles(names []Node, scope []Node) []bool {
+	out := make([]bool, len(names))
+	for i, name := range names {
+		out[i] = !isSymbol(name, "_") && assigned(name.String(), scope)
+	}
+	return out
+}
+
+// assigned reports whether the statements body assign name, or
+// take its address, so that a local of that name must be held in
+// a volatile.
+func assigned(name string, body []Node) bool {
+	for _, x := range body {
+		var elts []Node
+		switch x := x.(type) {
+		case *Vector:
+			elts = x.Elts
+		case *List:
+			elts = x.Elts
+		}
+		if len(elts) < 2 {
+			continue
+		}
+		switch head := Head(x); head {
+		case "var", "const":
+			// the specs declare, and only their values can assign
+			for _, spec := range elts[1:] {
+				if Head(spec) == "=" && assigned(name, spec.(*List).Elts[2:]) {
+					return true
+				}
+			}
+			continue
+		case "=":
+			for _, target := range names(elts[1]) {
+				if isSymbol(target, name) {
+					return true
+				}
+			}
+		case "++", "--", "adr":
+			if isSymbol(elts[1], name) {
+				return true
+			}
+		case ":=", "<=", ">=":
+		default:
+			if strings.HasSuffix(head, "=") && len(elts) == 3 && isSymbol(elts[1], name) {
+				return true
+			}
+		}
+		if assigned(name, elts) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebind returns body with those of names that scope assigns bound
+// to volatiles holding their values; records, if not nil, says
+// which of them are records.
+func (l *cljLowerer) rebind(names []Node, records []Node, scope []Node, body func() []Node) []Node {
+	volatile := recordVolatiles(names, records, scope)
+	bindings := &Brackets{Pairs: true}
+	for i, name := range names {
+		if volatile[i] {
+			bindings.Elts = append(bindings.Elts, name, form("volatile!", name))
+		}
+	}
+	done := l.declare(names, volatile, records)
+	lowered := body()
+	done()
+	if len(bindings.Elts) == 0 {
+		return lowered
+	}
+	return []Node{form("let", append([]Node{bindings}, lowered...)...)}
+}
+
+func (l *cljLowerer) decl(x Node) []Node {
+	switch Head(x) {
+	case "func", "func...":
+		return []Node{l.defn(x.(*List))}
+	case "var", "const":
+		return l.defs(x.(*List))
+	case "type":
+		return l.deftypes(x.(*List))
+	}
+	return []Node{l.expr(x)}
+}
+
+// def returns (def name value), private unless Go exports name.
+func def(name, value Node) Node {
+	if isExported(name.String()) {
+		return form("def", name, value)
+	}
+	return form("def", symbol("^:private"), name, value)
+}
+
+// defs turns a top-level var or const form into defs.
+func (l *cljLowerer) defs(x *List) []Node {
+	var out []Node
+	for _, spec := range l.specs(x) {
+		switch {
+		case spec.comment != nil:
+			out = append(out, spec.comment)
+		case len(spec.values) == 0:
+			for _, name := range spec.names {
+				out = append(out, def(l.binding(name), typeZero(spec.typ, l.types, 0)))
+			}
+		case len(spec.values) == len(spec.names):
+			for i, name := range spec.names {
+				out = append(out, def(l.binding(name), l.valueOf(spec.values[i], spec.typ)))
+			}
+		case len(spec.values) == 1:
+			formals := &Brackets{}
+			var defs []Node
+			for _, name := range spec.names {
+				name = l.binding(name)
+				formals.Elts = append(formals.Elts, name)
+				defs = append(defs, def(name, name))
+			}
+			out = append(out, form("let", append([]Node{&Brackets{Pairs: true, Elts: []Node{formals, l.expr(spec.values[0])}}}, defs...)...))
+		default:
+			l.errorf(x, "%d names but %d values", len(spec.names), len(spec.values))
+		}
+	}
+	return out
+}
+
+// deftypes turns the struct types of a type form into records and
+// its interfaces into protocols.
+func (l *cljLowerer) deftypes(x *List) []Node {
+	var out []Node
+	for _, spec := range typeSpecs(x) {
+		switch Head(spec.typ) {
+		case "struct":
+			fields := &Brackets{}
+			for _, name := range fieldNames(spec.typ.(*List)) {
+				fields.Elts = append(fields.Elts, symbol(name))
+			}
+			out = append(out, &List{Position: x.Position, Elts: []Node{symbol("defrecord"), symbol(spec.name), fields}})
+		case "interface":
+			protocol := &List{Position: x.Position, Elts: []Node{symbol("defprotocol"), symbol(spec.name)}}
+			for _, elt := range spec.typ.(*List).Elts[1:] {
+				v, ok := elt.(*Vector)
+				if !ok || len(v.Elts) != 2 || len(names(v.Elts[1])) < 2 {
+					continue
+				}
+				sig := v.Elts[1].(*List)
+				formals := &Brackets{Elts: []Node{symbol("this")}}
+				for _, name := range l.params(sig.Elts[1], false) {
+					formals.Elts = append(formals.Elts, name)
+				}
+				protocol.Elts = append(protocol.Elts, form(v.Elts[0].String(), formals))
+			}
+			out = append(out, protocol)
+		}
+	}
+	return out
+}
+
+// extends returns, for each struct type of the package in order,
+// an (extend T P {:M T-M...}) for each protocol P whose methods T
+// has some of.
+func (l *cljLowerer) extends(order []string, methods map[string][]string) []Node {
+	var out []Node
+	for _, name := range order {
+		if Head(l.types[name]) != "struct" {
+			continue
+		}
+		for _, protocol := range order {
+			iface, ok := l.types[protocol].(*List)
+			if !ok || Head(iface) != "interface" {
+				continue
+			}
+			impls := &Brackets{Map: true}
+			for _, method := range cljProtocolMethods(iface) {
+				if indexOf(methods[name], method) >= 0 {
+					impls.Elts = append(impls.Elts, symbol(":"+method), symbol(name+"-"+method))
+				}
+			}
+			if len(impls.Elts) > 0 {
+				out = append(out, form("extend", symbol(name), symbol(protocol), impls))
+			}
+		}
+	}
+	return out
+}
+
+// defn turns (func [recv] name params result body...) into
+// (defn name [params...] body...). A method M of T is the function
+// T-M of the receiver and the method's parameters.
+func (l *cljLowerer) defn(x *List) Node {
+	recv, elts := funcRecv(x)
+	if len(elts) < 3 {
+		l.errorf(x, "malformed %s form", Head(x))
+		return x
+	}
+	name := elts[0]
+	if Head(name) == "generic" && len(name.(*List).Elts) >= 2 {
+		name = name.(*List).Elts[1]
+	}
+	exported := isExported(name.String())
+	var formals []Node
+	records := l.paramRecords(elts[1])
+	if recv != nil {
+		formals = []Node{l.binding(symbol("_"))}
+		if v, ok := recv.(*Vector); ok && len(v.Elts) == 2 {
+			formals = []Node{l.binding(v.Elts[0])}
+			records[v.Elts[0].String()] = l.record(v.Elts[1])
+		}
+		name = symbol(typeName(cljReceiverType(recv)) + "-" + name.String())
+	}
+	variadic := Head(x) == "func..."
+	if n, ok := l.protocols[elts[0].String()]; ok && recv != nil && n >= 0 {
+		variadic = false
+	}
+	formals = append(formals, l.params(elts[1], variadic)...)
+	body := elts[3:]
+	var doc []Node
+	if len(body) > 0 {
+		if s, ok := body[0].(*String); ok {
+			doc, body = []Node{s}, body[1:]
+		}
+	}
+	head := "defn"
+	if !exported {
+		head = "defn-"
+	}
+	params, lowered := l.funcBody(formals, records, elts[2], body)
+	elts = append([]Node{symbol(head), name}, doc...)
+	return &List{Position: x.Position, Elts: append(append(elts, params), lowered...)}
+}
+
+// lambda turns a function literal (func params result body...)
+// into fn.
+func (l *cljLowerer) lambda(x *List) Node {
+	if len(x.Elts) < 3 {
+		l.errorf(x, "malformed %s form", Head(x))
+		return x
+	}
+	formals := l.params(x.Elts[1], Head(x) == "func...")
+	params, body := l.funcBody(formals, l.paramRecords(x.Elts[1]), x.Elts[2], x.Elts[3:])
+	return &List{Position: x.Position, Elts: append([]Node{symbol("fn"), params}, body...)}
+}
+
+// paramRecords returns the types of the parameters of the parameter
+// list x that are records.
+func (l *cljLowerer) paramRecords(x Node) map[string]Node {
+	out := make(map[string]Node)
+	if list, ok := x.(*List); ok {
+		for _, elt := range list.Elts {
+			if v, ok := elt.(*Vector); ok && len(v.Elts) >= 2 {
+				for _, name := range v.Elts[:len(v.Elts)-1] {
+					out[name.String()] = l.record(v.Elts[len(v.Elts)-1])
+				}
+			}
+		}
+	}
+	return out
+}
+
+// funcBody translates the body of a function with the given
+// formals, of which records are records, and result, returning its parameter vector and body.
+// Named results are bound around it, and if it defers calls, it
+// runs them as it unwinds.
+func (l *cljLowerer) funcBody(formals []Node, records map[string]Node, result Node, body []Node) (Node, []Node) {
+	returns, results := l.returns, l.results
+	defer func() { l.returns, l.results = returns, results }()
+	l.returns, l.results = false, nil
+
+	walk(body, func(x *List) bool {
+		if Head(x) == "goto" {
+			l.errorf(x, "goto has no Clojure translation")
+		}
+		return !isFunc(x)
+	})
+	params := &Brackets{}
+	var names []Node
+	var record []Node
+	for _, name := range formals {
+		if isSymbol(name, ".") {
+			params.Elts = append(params.Elts, symbol("&"))
+			continue
+		}
+		params.Elts = append(params.Elts, name)
+		names = append(names, name)
+		record = append(record, records[name.String()])
+	}
+	lowered := l.rebind(names, record, body, func() []Node {
+		var results, types []Node
+		fields := []Node{result}
+		if Head(result) == "values" {
+			fields = result.(*List).Elts[1:]
+		}
+		for _, field := range fields {
+			if v, ok := field.(*Vector); ok && len(v.Elts) >= 2 {
+				for _, name := range v.Elts[:len(v.Elts)-1] {
+					results = append(results, l.binding(name))
+					types = append(types, v.Elts[len(v.Elts)-1])
+				}
+			}
+		}
+		volatile := volatiles(results, body)
+		done := l.declare(results, volatile, nil)
+		defer done()
+		for _, name := range results {
+			l.results = append(l.results, l.expr(name))
+		}
+
+		out := l.seq(body, true)
+		if contains(body, "defer") {
+			run := form("run!", form("fn", &Brackets{Elts: []Node{symbol("f")}}, form("f")), symbol("@%defers"))
+			defers := &Brackets{Pairs: true, Elts: []Node{symbol("%defers"), form("volatile!", &List{})}}
+			out = []Node{form("let", defers, form("try", append(out, form("finally", run))...))}
+		}
+		if l.returns {
+			out = []Node{form("block", append([]Node{symbol(":return")}, out...)...)}
+		}
+		if len(results) > 0 {
+			bindings := &Brackets{Pairs: true}
+			for i, name := range results {
+				var value Node = typeZero(types[i], l.types, 0)
+				if volatile[i] {
+					value = form("volatile!", value)
+				}
+				bindings.Elts = append(bindings.Elts, name, value)
+			}
+			out = []Node{form("let", append([]Node{bindings}, out...)...)}
+		}
+		return out
+	})
+	return params, lowered
+}
+
+// do returns body as one form.
+func (l *cljLowerer) do(body []Node) Node {
+	if len(body) == 1 {
+		if _, ok := body[0].(*Comment); !ok {
+			return body[0]
+		}
+	}
+	return form("do", body...)
+}
+
+// last returns the index of the last statement in stmts that is
+// not a comment, or -1.
+func last(stmts []Node) int {
+	for i := len(stmts) - 1; i >= 0; i-- {
+		if _, ok := stmts[i].(*Comment); !ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// seq translates a sequence of statements, whose declarations scope
+// over the rest of the sequence. If tail is set, the sequence ends
+// the function, and its value is what the function returns.
+func (l *cljLowerer) seq(stmts []Node, tail bool) []Node {
+	end := last(stmts)
+	var out []Node
+	for i, x := range stmts {
+		switch Head(x) {
+		case ":=", "var", "const":
+			rest := stmts[i+1:]
+			return append(out, l.bind(x, rest, func() []Node { return l.seq(rest, tail) }))
+		case "when", "unless":
+			if tail && i < end && returns(x.(*List)) {
+				// the rest of the function is the else branch
+				els := &List{Elts: append([]Node{symbol("else")}, stmts[i+1:]...)}
+				stmt := &List{Position: x.(*List).Position, Elts: append(x.(*List).Elts, els)}
+				return append(out, l.ifStmt(stmt, true))
+			}
+		}
+		out = append(out, l.stmt(x, tail && i == end)...)
+	}
+	return out
+}
+
+// returns reports whether the when or unless form x, which has no
+// else branch, ends by returning.
+func returns(x *List) bool {
+	body := x.Elts[2:]
+	end := last(body)
+	return len(x.Elts) > 2 && end >= 0 && Head(body[end]) == "return" && Head(body[len(body)-1]) != "else"
+}
+
+// bind translates a := or a local var or const form, whose scope is
+// the statements scope, into let around what body returns.
+func (l *cljLowerer) bind(x Node, scope []Node, body func() []Node) Node {
+	list := x.(*List)
+	if Head(list) == ":=" {
+		if len(list.Elts) < 3 {
+			l.errorf(x, "malformed := form")
+			return l.do(body())
+		}
+		return l.let(x, names(list.Elts[1]), nil, list.Elts[2:], scope, body)
+	}
+	var specs []valueSpec
+	for _, spec := range l.specs(list) {
+		if spec.comment == nil {
+			specs = append(specs, spec)
+		}
+	}
+	var nest func(specs []valueSpec) []Node
+	nest = func(specs []valueSpec) []Node {
+		if len(specs) == 0 {
+			return body()
+		}
+		spec := specs[0]
+		return []Node{l.let(x, spec.names, spec.typ, spec.values, scope, func() []Node { return nest(specs[1:]) })}
+	}
+	return l.do(nest(specs))
+}
+
+// let binds names to values, or to the zero value of typ if there
+// are none, around what body returns. The names that the statements
+// scope assign are bound to volatiles.
+func (l *cljLowerer) let(x Node, names []Node, typ Node, values []Node, scope []Node, body func() []Node) Node {
+	recv := len(names) == 2 && len(values) == 1 && Head(values[0]) == "<-"
+	lowered := make([]Node, len(values))
+	for i, value := range values {
+		lowered[i] = l.valueOf(value, typ)
+	}
+	bound := make([]Node, len(names))
+	records := make([]Node, len(names))
+	for i, name := range names {
+		bound[i] = l.binding(name)
+		switch {
+		case typ != nil:
+			records[i] = l.record(typ)
+		case len(values) == len(names):
+			if v, ok := values[i].(*Vector); ok && len(v.Elts) > 0 {
+				records[i] = l.record(v.Elts[0])
+			}
+		}
+	}
+	volatile := recordVolatiles(bound, records, scope)
+	bindings := &Brackets{Pairs: true}
+	switch {
+	case len(values) == 0:
+		for i, name := range bound {
+			var value Node = typeZero(typ, l.types, 0)
+			if volatile[i] {
+				value = form("volatile!", value)
+			}
+			bindings.Elts = append(bindings.Elts, name, value)
+		}
+	case len(values) == len(names):
+		for i, name := range bound {
+			value := lowered[i]
+			if volatile[i] {
+				value = form("volatile!", value)
+			}
+			bindings.Elts = append(bindings.Elts, name, value)
+		}
+	case recv:
+		// v, ok := <-c: a closed channel gives nil
+		bindings.Elts = append(bindings.Elts, bound[0], lowered[0], bound[1], form("some?", bound[0]))
+	case len(values) == 1:
+		bindings.Elts = append(bindings.Elts, &Brackets{Elts: bound}, lowered[0])
+	default:
+		l.errorf(x, "%d names but %d values", len(names), len(values))
+	}
+	if len(values) == 1 && len(names) > 1 {
+		for i, name := range bound {
+			if volatile[i] {
+				bindings.Elts = append(bindings.Elts, name, form("volatile!", name))
+			}
+		}
+	}
+	done := l.declare(bound, volatile, records)
+	defer done()
+	return form("let", append([]Node{bindings}, body()...)...)
+}
+
+func (l *cljLowerer) stmt(x Node, tail bool) []Node {
+	switch x := x.(type) {
+	case *Bool:
+		if !x.Value {
+			// the empty statement
+			return nil
+		}
+	case *List:
+		return l.stmtList(x, "", tail)
+	}
+	return []Node{l.expr(x)}
+}
+
+// stmtList translates the statement x, which is labeled label if
+// that is not "".
+func (l *cljLowerer) stmtList(x *List, label string, tail bool) []Node {
+	elts := x.Elts[1:]
+	switch head := Head(x); head {
+	case "label":
+		if len(elts) != 2 {
+			l.errorf(x, "malformed label form")
+			return nil
+		}
+		if stmt, ok := elts[1].(*List); ok {
+			return l.stmtList(stmt, elts[0].String(), tail)
+		}
+		return l.stmt(elts[1], tail)
+	case "when", "unless":
+		return []Node{l.ifStmt(x, tail)}
+	case "when*", "unless*", "cond!*", "case!*", "type!*":
+		if len(elts) < 2 {
+			l.errorf(x, "malformed %s form", head)
+			return nil
+		}
+		stmt := &List{Position: x.Position, Elts: append([]Node{symbol(strings.TrimSuffix(head, "*"))}, elts[1:]...)}
+		return l.withInit(elts[0], x, func() Node { return l.do(l.stmtList(stmt, label, tail)) })
+	case "while":
+		if len(elts) < 1 {
+			l.errorf(x, "malformed while form")
+			return nil
+		}
+		return []Node{l.loop(label, elts[0], elts[1:], nil)}
+	case "for":
+		if len(elts) < 3 {
+			l.errorf(x, "malformed for form")
+			return nil
+		}
+		return l.withInit(elts[0], x, func() Node { return l.loop(label, elts[1], elts[3:], elts[2]) })
+	case "range":
+		return []Node{l.rangeStmt(x, label)}
+	case "cond!", "case!", "type!", "comm!":
+		return []Node{l.switchStmt(x, label, tail)}
+	case "goto":
+		// reported by funcBody
+		return nil
+	case "break", "continue":
+		if len(elts) == 1 {
+			return []Node{form("return-from", symbol(":"+head+"-"+elts[0].String()))}
+		}
+		return []Node{form("return-from", symbol(":"+head))}
+	case "return":
+		values := l.results
+		if len(elts) > 0 {
+			values = l.exprs(elts)
+		}
+		var value Node
+		switch len(values) {
+		case 0:
+			if tail {
+				return nil
+			}
+		case 1:
+			value = values[0]
+		default:
+			value = &Brackets{Elts: values}
+		}
+		if tail {
+			return []Node{value}
+		}
+		l.returns = true
+		if value == nil {
+			return []Node{form("return-from", symbol(":return"))}
+		}
+		return []Node{form("return-from", symbol(":return"), value)}
+	case "fallthrough":
+		l.errorf(x, "fallthrough outside a switch clause")
+		return nil
+	case "go":
+		if len(elts) != 1 {
+			l.errorf(x, "malformed go form")
+			return nil
+		}
+		return []Node{l.thunk(elts[0], func(call Node) Node { return form("async/thread", call) })}
+	case "defer":
+		if len(elts) != 1 {
+			l.errorf(x, "malformed defer form")
+			return nil
+		}
+		return []Node{l.thunk(elts[0], func(call Node) Node {
+			return form("vswap!", symbol("%defers"), symbol("conj"), form("fn", &Brackets{}, call))
+		})}
+	case ":=", "var", "const":
+		return []Node{l.bind(x, nil, func() []Node { return nil })}
+	case "type":
+		return nil
+	case "=", "++", "--":
+		return l.assign(x)
+	default:
+		if len(elts) == 2 && strings.HasSuffix(head, "=") && head != "<=" && head != ">=" {
+			return l.assign(x)
+		}
+	}
+	return []Node{l.expr(x)}
+}
+
+// withInit translates the init statement of the if, for or switch
+// x around what stmt returns.
+func (l *cljLowerer) withInit(init, x Node, stmt func() Node) []Node {
+	switch Head(init) {
+	case ":=", "var", "const":
+		return []Node{l.bind(init, []Node{x}, func() []Node { return []Node{stmt()} })}
+	}
+	return append(l.stmt(init, false), stmt())
+}
+
+// ifStmt translates (when c body... (else alt...)) and unless.
+func (l *cljLowerer) ifStmt(x *List, tail bool) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed %s form", Head(x))
+		return &Nil{}
+	}
+	cond, body := l.expr(x.Elts[1]), x.Elts[2:]
+	if n := len(body); n > 0 && Head(body[n-1]) == "else" {
+		then, els := l.do(l.seq(body[:n-1], tail)), l.do(l.seq(body[n-1].(*List).Elts[1:], tail))
+		if Head(x) == "unless" {
+			then, els = els, then
+		}
+		return form("if", cond, then, els)
+	}
+	lowered := l.seq(body, tail)
+	if len(lowered) == 0 {
+		return cond
+	}
+	head := "when"
+	if Head(x) == "unless" {
+		head = "when-not"
+	}
+	return form(head, append([]Node{cond}, lowered...)...)
+}
+
+// loop translates a loop with the given condition and body into
+// while. post runs after each iteration, including those ended by
+// continue.
+func (l *cljLowerer) loop(label string, cond Node, body []Node, post Node) Node {
+	iter := l.escape("continue", label, body, loopForms, l.seq(body, false))
+	if post != nil {
+		iter = append(iter, l.stmt(post, false)...)
+	}
+	loop := form("while", append([]Node{l.expr(cond)}, iter...)...)
+	return l.do(l.escape("break", label, body, breakForms, []Node{loop}))
+}
+
+// escape wraps lowered in (block :name ...) if the statements body
+// leave it with (name) outside any of the nested forms, and in
+// (block :name-label ...) if they leave it with (name label).
+func (l *cljLowerer) escape(name, label string, body []Node, nested map[string]bool, lowered []Node) []Node {
+	plain, labeled := exits(name, label, body, nested)
+	if labeled {
+		lowered = []Node{form("block", append([]Node{symbol(":" + name + "-" + label)}, lowered...)...)}
+	}
+	if plain {
+		lowered = []Node{form("block", append([]Node{symbol(":" + name)}, lowered...)...)}
+	}
+	return lowered
+}
+
+// rangeStmt translates (range [(:= vars x)] body...) into a call
+// of range-for-each with a function of the key and value.
+func (l *cljLowerer) rangeStmt(x *List, label string) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed range form")
+		return &Nil{}
+	}
+	spec, body := x.Elts[1], x.Elts[2:]
+	subject := spec
+	formals := []Node{symbol("%k"), symbol("%v")}
+	var pre []Node
+	if head := Head(spec); (head == ":=" || head == "=") && len(spec.(*List).Elts) == 3 {
+		vars := names(spec.(*List).Elts[1])
+		subject = spec.(*List).Elts[2]
+		for i := 0; i < len(vars) && i < 2; i++ {
+			if head == ":=" {
+				formals[i] = l.binding(vars[i])
+			} else if !isSymbol(vars[i], "_") {
+				pre = append(pre, form("=", vars[i], formals[i]))
+			}
+		}
+	}
+	subject = l.expr(subject)
+	lowered := l.rebind(formals, nil, body, func() []Node {
+		var out []Node
+		for _, stmt := range pre {
+			out = append(out, l.stmt(stmt, false)...)
+		}
+		return append(out, l.escape("continue", label, body, loopForms, l.seq(body, false))...)
+	})
+	fn := form("fn", append([]Node{&Brackets{Elts: formals}}, lowered...)...)
+	loop := form("range-for-each", fn, subject)
+	return l.do(l.escape("break", label, body, breakForms, []Node{loop}))
+}
+
+// switchStmt translates cond!, case!, type! and comm!.
+func (l *cljLowerer) switchStmt(x *List, label string, tail bool) Node {
+	var lowered Node
+	var body []Node
+	for _, clause := range x.Elts[1:] {
+		if list, ok := clause.(*List); ok {
+			body = append(body, list.Elts...)
+		}
+	}
+	switch Head(x) {
+	case "cond!":
+		lowered = l.condSwitch(x.Elts[1:], tail)
+	case "case!":
+		lowered = l.caseSwitch(x, tail)
+	case "type!":
+		lowered = l.typeSwitch(x, tail)
+	case "comm!":
+		lowered = l.selectStmt(x.Elts[1:], tail)
+	}
+	return l.do(l.escape("break", label, body, breakForms, []Node{lowered}))
+}
+
+// condSwitch translates the clauses of cond! into cond.
+func (l *cljLowerer) condSwitch(list []Node, tail bool) Node {
+	out := form("cond")
+	for i, body := range clauses(list) {
+		clause, ok := list[i].(*List)
+		if !ok || len(clause.Elts) == 0 {
+			out.Elts = append(out.Elts, list[i])
+			continue
+		}
+		var test Node = symbol(":else")
+		if !isSymbol(clause.Elts[0], "else") {
+			test = l.expr(clause.Elts[0])
+		}
+		out.Elts = append(out.Elts, test, l.do(l.seq(body, tail)))
+	}
+	return out
+}
+
+// caseSwitch translates (case! tag ((values...) body...)...) into
+// case when every value is a literal, and otherwise into cond over
+// =.
+func (l *cljLowerer) caseSwitch(x *List, tail bool) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed case! form")
+		return &Nil{}
+	}
+	tag, list := x.Elts[1], x.Elts[2:]
+	literal := true
+	for _, clause := range list {
+		if values, ok := clause.(*List); ok && len(values.Elts) > 0 {
+			if v, ok := values.Elts[0].(*List); ok {
+				for _, value := range v.Elts {
+					switch value.(type) {
+					case *Number, *Char, *String:
+					default:
+						literal = false
+					}
+				}
+			}
+		}
+	}
+	if !literal {
+		return l.temp("%tag", tag, func(tag Node) Node {
+			var conds []Node
+			for _, clause := range list {
+				c, ok := clause.(*List)
+				if !ok || len(c.Elts) == 0 || isSymbol(c.Elts[0], "else") {
+					conds = append(conds, clause)
+					continue
+				}
+				var tests []Node
+				if v, ok := c.Elts[0].(*List); ok {
+					for _, value := range v.Elts {
+						tests = append(tests, form("equal?", tag, value))
+					}
+				}
+				conds = append(conds, &List{Position: c.Position, Elts: append([]Node{or(tests)}, c.Elts[1:]...)})
+			}
+			return l.condSwitch(conds, tail)
+		})
+	}
+	out := form("case", l.expr(tag))
+	var otherwise Node = &Nil{}
+	for i, body := range clauses(list) {
+		clause, ok := list[i].(*List)
+		if !ok || len(clause.Elts) == 0 {
+			out.Elts = append(out.Elts, list[i])
+			continue
+		}
+		if isSymbol(clause.Elts[0], "else") {
+			otherwise = l.do(l.seq(body, tail))
+			continue
+		}
+		keys := clause.Elts[0]
+		if v, ok := keys.(*List); ok && len(v.Elts) == 1 {
+			keys = v.Elts[0]
+		}
+		out.Elts = append(out.Elts, keys, l.do(l.seq(body, tail)))
+	}
+	// case throws if nothing matches
+	return &List{Elts: append(out.Elts, otherwise)}
+}
+
+// temp calls f with the expression x if it is a symbol that is
+// not a volatile, and otherwise binds name to its value around
+// what f returns for name.
+func (l *cljLowerer) temp(name string, x Node, f func(Node) Node) Node {
+	if _, ok := x.(*Symbol); ok && !l.isVolatile(x) {
+		return f(x)
+	}
+	return form("let", &Brackets{Pairs: true, Elts: []Node{symbol(name), l.expr(x)}}, f(symbol(name)))
+}
+
+// typeSwitch translates (type! [(:= v] (as x type)[)] ((types...)
+// body...)...) into cond over type-is?, binding v in each clause.
+func (l *cljLowerer) typeSwitch(x *List, tail bool) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed type! form")
+		return &Nil{}
+	}
+	guard := x.Elts[1]
+	var v Node
+	if Head(guard) == ":=" && len(guard.(*List).Elts) == 3 {
+		v, guard = guard.(*List).Elts[1], guard.(*List).Elts[2]
+	}
+	if Head(guard) != "as" || len(guard.(*List).Elts) != 3 {
+		l.errorf(guard, "malformed type! guard %s", guard)
+		return &Nil{}
+	}
+	list := x.Elts[2:]
+	return l.temp("%x", guard.(*List).Elts[1], func(subject Node) Node {
+		out := form("cond")
+		for i, body := range clauses(list) {
+			clause, ok := list[i].(*List)
+			if !ok || len(clause.Elts) == 0 {
+				out.Elts = append(out.Elts, list[i])
+				continue
+			}
+			var test Node = symbol(":else")
+			if types, ok := clause.Elts[0].(*List); ok {
+				var tests []Node
+				for _, typ := range types.Elts {
+					if _, ok := typ.(*Nil); ok {
+						tests = append(tests, form("nil?", subject))
+					} else {
+						tests = append(tests, form("type-is?", subject, typ))
+					}
+				}
+				test = or(tests)
+			}
+			var lowered Node
+			if v != nil {
+				volatile := volatiles([]Node{v}, body)
+				var value Node = subject
+				if volatile[0] {
+					value = form("volatile!", value)
+				}
+				done := l.declare([]Node{v}, volatile, nil)
+				lowered = form("let", append([]Node{&Brackets{Pairs: true, Elts: []Node{v, value}}}, l.seq(body, tail)...)...)
+				done()
+			} else {
+				lowered = l.do(l.seq(body, tail))
+			}
+			out.Elts = append(out.Elts, test, lowered)
+		}
+		return out
+	})
+}
+
+// selectStmt translates the clauses of comm! into a call of
+// async/alts!! on their channels, and condp on the channel it
+// returns. A closed channel gives nil.
+func (l *cljLowerer) selectStmt(list []Node, tail bool) Node {
+	bindings := &Brackets{Pairs: true}
+	ports := &Brackets{}
+	dispatch := form("condp", symbol("="), symbol("%port"))
+	var otherwise Node
+	for _, x := range list {
+		clause, ok := x.(*List)
+		if !ok || len(clause.Elts) == 0 {
+			continue
+		}
+		comm, body := clause.Elts[0], clause.Elts[1:]
+		if isSymbol(comm, "else") {
+			otherwise = l.do(l.seq(body, tail))
+			continue
+		}
+		port := symbol("%" + strconv.Itoa(len(ports.Elts)))
+		elts := []Node{nil}
+		if list, ok := comm.(*List); ok {
+			elts = list.Elts
+		}
+		if Head(comm) == "<-!" && len(elts) == 3 {
+			bindings.Elts = append(bindings.Elts, port, l.expr(elts[1]))
+			ports.Elts = append(ports.Elts, &Brackets{Elts: []Node{port, l.expr(elts[2])}})
+			dispatch.Elts = append(dispatch.Elts, port, l.do(l.seq(body, tail)))
+			continue
+		}
+		formals := []Node{symbol("%v"), symbol("%ok")}
+		var pre []Node
+		switch head := Head(comm); {
+		case (head == ":=" || head == "=") && len(elts) == 3:
+			vars := names(elts[1])
+			for i := 0; i < len(vars) && i < 2; i++ {
+				if head == ":=" {
+					formals[i] = l.binding(vars[i])
+				} else if !isSymbol(vars[i], "_") {
+					pre = append(pre, form("=", vars[i], formals[i]))
+				}
+			}
+			comm, elts = elts[2], []Node{nil}
+			if list, ok := comm.(*List); ok {
+				elts = list.Elts
+			}
+		}
+		if Head(comm) != "<-" || len(elts) != 2 {
+			l.errorf(comm, "malformed comm! clause %s", comm)
+			continue
+		}
+		bindings.Elts = append(bindings.Elts, port, l.expr(elts[1]))
+		ports.Elts = append(ports.Elts, port)
+		values := &Brackets{Pairs: true, Elts: []Node{formals[0], symbol("%v"), formals[1], form("some?", symbol("%v"))}}
+		lowered := l.rebind(formals, nil, body, func() []Node {
+			var out []Node
+			for _, stmt := range pre {
+				out = append(out, l.stmt(stmt, false)...)
+			}
+			return append(out, l.seq(body, tail)...)
+		})
+		dispatch.Elts = append(dispatch.Elts, port, form("let", append([]Node{values}, lowered...)...))
+	}
+	alts := form("async/alts!!", ports)
+	if otherwise != nil {
+		alts.Elts = append(alts.Elts, symbol(":default"), &Nil{})
+		dispatch.Elts = append(dispatch.Elts, symbol(":default"), otherwise)
+	}
+	bindings.Elts = append(bindings.Elts, &Brackets{Elts: []Node{symbol("%v"), symbol("%port")}}, alts)
+	return form("let", bindings, dispatch)
+}
+
+// thunk returns wrap of the call x, whose function and arguments
+// are evaluated at once, as go and defer do, and bound around it.
+func (l *cljLowerer) thunk(x Node, wrap func(call Node) Node) Node {
+	call, ok := l.expr(x).(*List)
+	if !ok || len(call.Elts) == 0 {
+		l.errorf(x, "expected call, found %s", x)
+		return wrap(x)
+	}
+	bindings := &Brackets{Pairs: true}
+	elts := call.Elts[:1:1]
+	if _, ok := call.Elts[0].(*Symbol); !ok {
+		elts = nil
+	}
+	for i, arg := range call.Elts[len(elts):] {
+		switch arg.(type) {
+		case *Number, *String, *Char, *Bool, *Nil:
+			elts = append(elts, arg)
+			continue
+		}
+		if sym, ok := arg.(*Symbol); ok && strings.HasPrefix(sym.Name, ":") {
+			// a method or field name
+			elts = append(elts, arg)
+			continue
+		}
+		name := symbol("%" + strconv.Itoa(i))
+		bindings.Elts = append(bindings.Elts, name, arg)
+		elts = append(elts, name)
+	}
+	wrapped := wrap(&List{Position: call.Position, Elts: elts})
+	if len(bindings.Elts) == 0 {
+		return wrapped
+	}
+	return form("let", bindings, wrapped)
+}
+
+// assign translates =, compound assignments, ++ and -- into
+// assignments of each target as set does.
+func (l *cljLowerer) assign(x *List) []Node {
+	elts := x.Elts[1:]
+	switch head := Head(x); head {
+	case "++", "--":
+		if len(elts) != 1 {
+			break
+		}
+		op := map[string]string{"++": "inc", "--": "dec"}[head]
+		return []Node{l.update(elts[0], symbol(op))}
+	case "=":
+		if len(elts) < 2 {
+			break
+		}
+		targets, values := names(elts[0]), l.exprs(elts[1:])
+		switch {
+		case len(targets) == 1 && len(values) == 1:
+			return []Node{l.set(targets[0], values[0])}
+		case len(targets) == len(values):
+			// evaluate every value before assigning any
+			bindings := &Brackets{Pairs: true}
+			var sets []Node
+			for i, target := range targets {
+				name := symbol("%" + strconv.Itoa(i))
+				bindings.Elts = append(bindings.Elts, name, values[i])
+				if !isSymbol(target, "_") {
+					sets = append(sets, l.set(target, name))
+				}
+			}
+			return []Node{form("let", append([]Node{bindings}, sets...)...)}
+		case len(values) == 1:
+			formals := &Brackets{}
+			var sets []Node
+			for i, target := range targets {
+				name := symbol("%" + strconv.Itoa(i))
+				formals.Elts = append(formals.Elts, name)
+				if !isSymbol(target, "_") {
+					sets = append(sets, l.set(target, name))
+				}
+			}
+			return []Node{form("let", append([]Node{&Brackets{Pairs: true, Elts: []Node{formals, values[0]}}}, sets...)...)}
+		}
+	default:
+		if len(elts) != 2 || len(names(elts[0])) != 1 {
+			break
+		}
+		op := strings.TrimSuffix(head, "=")
+		if name, ok := cljOps[op]; ok {
+			op = name
+		}
+		return []Node{l.update(elts[0], symbol(op), l.expr(elts[1]))}
+	}
+	l.errorf(x, "malformed %s form", Head(x))
+	return nil
+}
+
+// set assigns value to target: a local volatile, a package
+// variable, or a place that index-set!, dot-set! or ptr-set!
+// assigns.
+func (l *cljLowerer) set(target, value Node) Node {
+	switch {
+	case isSymbol(target, "_"):
+		return value
+	case l.isVolatile(target):
+		return form("vreset!", target, value)
+	}
+	if root, path := fieldPath(target); len(path) > 0 && l.isVolatile(root) {
+		if keys := l.recordKeys(l.recordType(root), path); keys != nil {
+			// a record is changed by replacing it
+			if len(keys) == 1 {
+				return form("vswap!", root, symbol("assoc"), keys[0], value)
+			}
+			return form("vswap!", root, symbol("assoc-in"), &Brackets{Elts: keys}, value)
+		}
+	}
+	switch Head(target) {
+	case "index":
+		return form("index-set!", append(l.exprs(target.(*List).Elts[1:]), value)...)
+	case "dot":
+		if elts := target.(*List).Elts; len(elts) == 3 {
+			return form("dot-set!", l.expr(elts[1]), symbol(":"+elts[2].String()), value)
+		}
+	case "ptr":
+		return form("ptr-set!", append(l.exprs(target.(*List).Elts[1:]), value)...)
+	}
+	if _, ok := target.(*Symbol); ok {
+		return form("alter-var-root", form("var", target), form("constantly", value))
+	}
+	l.errorf(target, "cannot assign to %s", target)
+	return value
+}
+
+// recordKeys returns the keywords of the fields path of a record
+// of type typ, or nil if it is not a record or one of the fields
+// but the last is not a record itself, which dot-set! then
+// assigns through.
+func (l *cljLowerer) recordKeys(typ Node, path []Node) []Node {
+	var keys []Node
+	for i, field := range path {
+		if l.record(typ) == nil {
+			return nil
+		}
+		keys = append(keys, symbol(":"+field.String()))
+		if i < len(path)-1 {
+			st := l.types[typ.String()].(*List)
+			j := indexOf(fieldNames(st), field.String())
+			if j < 0 {
+				return nil
+			}
+			typ = fieldTypes(st)[j]
+		}
+	}
+	return keys
+}
+
+// update assigns the result of calling op with target's value and
+// args to target.
+func (l *cljLowerer) update(target, op Node, args ...Node) Node {
+	if l.isVolatile(target) {
+		return form("vswap!", append([]Node{target, op}, args...)...)
+	}
+	if _, ok := target.(*Symbol); ok && Head(target) == "" {
+		return form("alter-var-root", append([]Node{form("var", target), op}, args...)...)
+	}
+	return l.set(target, &List{Elts: append([]Node{op, l.expr(target)}, args...)})
+}
+
+// helper function
+func (l *cljLowerer) exprs(list []Node) []Node {
+	out := make([]Node, len(list))
+	for i, x := range list {
+		out[i] = l.expr(x)
+	}
+	return out
+}
+
+// valueOf translates the value x of a declaration of type typ,
+// which an elided composite literal has.
+func (l *cljLowerer) valueOf(x, typ Node) Node {
+	if v, ok := x.(*Vector); ok && typ != nil {
+		return l.composite(v, typ)
+	}
+	return l.expr(x)
+}
+
+// expr translates the expression x: function literals become fns,
+// the operators Clojure spells differently are renamed, volatiles
+// are dereferenced, and composite literals of the package's struct
+// types call their constructors. Field and method names become
+// keywords, and the types in x are left alone.
+func (l *cljLowerer) expr(x Node) Node {
+	switch x := x.(type) {
+	case *Symbol:
+		if l.isVolatile(x) {
+			return &Symbol{Position: x.Position, Name: "@" + x.Name}
+		}
+		return x
+	case *Vector:
+		return l.composite(x, nil)
+	case *List:
+		if len(x.Elts) == 0 {
+			return x
+		}
+		// the leading elements that are types, not expressions
+		keep := 1
+		switch head := Head(x); head {
+		case "func", "func...":
+			return l.lambda(x)
+		case "convert", "make", "new", "method-expr":
+			keep = 2
+			if head == "method-expr" && len(x.Elts) == 3 {
+				if _, ok := l.types[typeName(x.Elts[1])]; ok {
+					return symbol(typeName(x.Elts[1]) + "-" + x.Elts[2].String())
+				}
+				return form(head, x.Elts[1], symbol(":"+x.Elts[2].String()))
+			}
+		case "as":
+			if len(x.Elts) == 3 {
+				return &List{Position: x.Position, Elts: []Node{x.Elts[0], l.expr(x.Elts[1]), x.Elts[2]}}
+			}
+		case "dot", "method":
+			if len(x.Elts) == 3 {
+				return &List{Position: x.Position, Elts: []Node{x.Elts[0], l.expr(x.Elts[1]), symbol(":" + x.Elts[2].String())}}
+			}
+		case "call-method":
+			if len(x.Elts) >= 3 {
+				return l.call(x, x.Elts[1], x.Elts[2], x.Elts[3:])
+			}
+		case "adr":
+			if len(x.Elts) == 2 && l.isVolatile(x.Elts[1]) {
+				// the volatile is the pointer
+				return x.Elts[1]
+			}
+		case "inst":
+			return x
+		case "apply...":
+			if len(x.Elts) < 2 {
+				break
+			}
+			if sel, ok := x.Elts[1].(*List); ok && Head(sel) == "dot" && len(sel.Elts) == 3 {
+				if n, ok := l.protocols[sel.Elts[2].String()]; ok && n >= 0 {
+					// the slice is the protocol function's last argument
+					return &List{Position: x.Position, Elts: append([]Node{sel.Elts[2], l.expr(sel.Elts[1])}, l.exprs(x.Elts[2:])...)}
+				}
+			}
+			if len(x.Elts) >= 2 {
+				return &List{Position: x.Position, Elts: append([]Node{symbol("apply")}, l.exprs(x.Elts[1:])...)}
+			}
+		case "dot-set!", "index-set!", "ptr-set!":
+		default:
+			if sel, ok := x.Elts[0].(*List); ok && Head(sel) == "dot" && len(sel.Elts) == 3 {
+				return l.call(x, sel.Elts[1], sel.Elts[2], x.Elts[1:])
+			}
+			keep = 0
+		}
+		elts := append(append([]Node{}, x.Elts[:keep]...), l.exprs(x.Elts[keep:])...)
+		if op, ok := cljOps[Head(x)]; ok {
+			elts[0] = symbol(op)
+		}
+		return &List{Position: x.Position, Elts: elts}
+	}
+	return x
+}
+
+// call translates a call of the method or function field name of
+// x, calling the protocol function of that name if the package
+// declares one.
+func (l *cljLowerer) call(x *List, recv, name Node, args []Node) Node {
+	if n, ok := l.protocols[name.String()]; ok {
+		args = l.exprs(args)
+		if n >= 0 && len(args) >= n {
+			args = append(args[:n:n], &Brackets{Elts: args[n:]})
+		}
+		return &List{Position: x.Position, Elts: append([]Node{name, l.expr(recv)}, args...)}
+	}
+	elts := []Node{symbol("call-method"), l.expr(recv), symbol(":" + name.String())}
+	return &List{Position: x.Position, Elts: append(elts, l.exprs(args)...)}
+}
+
+// underlying returns the type typ names, looking through the
+// package's types.
+func (l *cljLowerer) underlying(typ Node) Node {
+	for i := 0; i <= len(l.types); i++ {
+		under, ok := l.types[typ.String()]
+		if !ok {
+			break
+		}
+		typ = under
+	}
+	return typ
+}
+
+// elemTypes returns the types of the keys and elements of the
+// composite literals of type typ, as far as the package knows them.
+func (l *cljLowerer) elemTypes(typ Node) (key, elem Node) {
+	list, ok := l.underlying(typ).(*List)
+	if !ok || len(list.Elts) < 2 {
+		return nil, nil
+	}
+	switch Head(list) {
+	case "slice", "array", "array...":
+		return nil, list.Elts[len(list.Elts)-1]
+	case "map-type":
+		if len(list.Elts) == 3 {
+			return list.Elts[1], list.Elts[2]
+		}
+	}
+	return nil, nil
+}
+
+// composite translates the composite literal #(T elts...), whose
+// type is typ if T is elided as _. A struct type of the package's
+// is constructed by its constructor; any other is left to the
+// composite form of go.builtin, with the keyed elements (: k v)
+// gathered into maps.
+func (l *cljLowerer) composite(x *Vector, typ Node) Node {
+	if len(x.Elts) == 0 {
+		return x
+	}
+	if !isSymbol(x.Elts[0], "_") || typ == nil {
+		typ = x.Elts[0]
+	}
+	if Head(typ) == "ptr" && isSymbol(x.Elts[0], "_") && len(typ.(*List).Elts) == 2 {
+		// &T elided with the *
+		return form("adr", l.composite(x, typ.(*List).Elts[1]))
+	}
+	under := l.underlying(typ)
+	key, elem := l.elemTypes(typ)
+	var fields []string
+	var fieldTypes_ []Node
+	if Head(under) == "struct" {
+		fields, fieldTypes_ = fieldNames(under.(*List)), fieldTypes(under.(*List))
+	}
+	value := func(x Node, i int, name string) Node {
+		t := elem
+		if fields != nil {
+			if name != "" {
+				i = indexOf(fields, name)
+			}
+			if 0 <= i && i < len(fieldTypes_) {
+				t = fieldTypes_[i]
+			}
+		}
+		return l.valueOf(x, t)
+	}
+	keyed := Head(under) != "map-type" && Head(under) != "slice" && Head(under) != "array" && Head(under) != "array..."
+	list := &List{Position: x.Position, Elts: []Node{symbol("composite"), typ}}
+	var m *Brackets
+	for i, elt := range x.Elts[1:] {
+		kv, ok := elt.(*List)
+		if !ok || Head(kv) != ":" || len(kv.Elts) != 3 {
+			list.Elts = append(list.Elts, value(elt, i, ""))
+			m = nil
+			continue
+		}
+		k := kv.Elts[1]
+		if _, ok := k.(*Symbol); ok && keyed {
+			k = symbol(":" + k.String())
+		} else {
+			k = l.valueOf(k, key)
+		}
+		if _, ok := l.types[typ.String()]; ok && fields != nil {
+			// constructors wants the field names
+			list.Elts = append(list.Elts, form(":", kv.Elts[1], value(kv.Elts[2], 0, kv.Elts[1].String())))
+			continue
+		}
+		if m == nil {
+			m = &Brackets{Map: true}
+			list.Elts = append(list.Elts, m)
+		}
+		m.Elts = append(m.Elts, k, value(kv.Elts[2], 0, ""))
+	}
+	if y := constructors(list, l.types); Head(y) != "composite" {
+		return y
+	}
+	// a literal constructors cannot take
+	for i, elt := range list.Elts {
+		if kv, ok := elt.(*List); ok && Head(kv) == ":" {
+			list.Elts[i] = &Brackets{Map: true, Elts: []Node{symbol(":" + kv.Elts[1].String()), kv.Elts[2]}}
+		}
+	}
+	return list
+}
+
+// spell writes the literals in x in Clojure syntax, the names of
+// imported packages' members as pkg/Name, and the constructors of
+// the package's struct types as ->T. The field types left in types
+// become (field names... T).
+func (l *cljLowerer) spell(x Node) Node {
+	switch x := x.(type) {
+	case *List:
+		elts := make([]Node, len(x.Elts))
+		for i, elt := range x.Elts {
+			elts[i] = l.spell(elt)
+		}
+		if len(elts) == 0 {
+			return x
+		}
+		if sym, ok := elts[0].(*Symbol); ok && Head(l.types[sym.Name]) == "struct" {
+			elts[0] = &Symbol{Position: sym.Position, Name: "->" + sym.Name}
+		}
+		return &List{Position: x.Position, Elts: elts}
+	case *Brackets:
+		elts := make([]Node, len(x.Elts))
+		for i, elt := range x.Elts {
+			elts[i] = l.spell(elt)
+		}
+		return &Brackets{Position: x.Position, Map: x.Map, Pairs: x.Pairs, Elts: elts}
+	case *Vector:
+		return l.spell(composite(x, true))
+	case *Symbol:
+		if i := strings.IndexByte(x.Name, '.'); i > 0 && l.prefixes[strings.TrimPrefix(x.Name[:i], "@")] {
+			return &Symbol{Position: x.Position, Name: x.Name[:i] + "/" + x.Name[i+1:]}
+		}
+		return x
+	case *Comment:
+		return x
+	}
+	return cljLiteral(x)
+}
+
+// cljLiteral returns the Gos literal x in Clojure syntax, which EDN
+// shares.
+func cljLiteral(x Node) Node {
+	switch x := x.(type) {
+	case *Bool:
+		return &Symbol{Position: x.Position, Name: strconv.FormatBool(x.Value)}
+	case *Nil:
+		return &Symbol{Position: x.Position, Name: "nil"}
+	case *Char:
+		return cljChar(x)
+	case *String:
+		return &Symbol{Position: x.Position, Name: cljString(x.Value)}
+	case *Number:
+		return cljNumber(x)
+	case *Bytevector:
+		bytes := &Brackets{Position: x.Position}
+		for _, b := range x.Value {
+			bytes.Elts = append(bytes.Elts, symbol(strconv.Itoa(int(int8(b)))))
+		}
+		return &List{Position: x.Position, Elts: []Node{symbol("byte-array"), bytes}}
+	}
+	return x
+}
+
+// cljCharNames holds the characters that Clojure writes by name.
+var cljCharNames = map[rune]string{
+	' ': "space", '\n': "newline", '\t': "tab", '\r': "return",
+	'\b': "backspace", '\f': "formfeed",
+}
+
+// cljChar returns the character x in Clojure syntax: by name, as
+// itself, or as a \u escape. Clojure's characters are UTF-16 code
+// units, so a character beyond them is written as its code.
+func cljChar(x *Char) Node {
+	r := x.Value
+	switch name, ok := cljCharNames[r]; {
+	case ok:
+		return &Symbol{Position: x.Position, Name: `\` + name}
+	case r > 0xffff:
+		return &Symbol{Position: x.Position, Name: strconv.Itoa(int(r))}
+	case unicode.IsPrint(r) && !unicode.Is(unicode.M, r):
+		return &Symbol{Position: x.Position, Name: `\` + string(r)}
+	}
+	return &Symbol{Position: x.Position, Name: `\u` + hex4(r)}
+}
+
+// cljString returns s in Clojure syntax, with Java's escapes.
+func cljString(s string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+			continue
+		case '\n', '\t', '\r', '\b', '\f':
+			buf.WriteString(strings.Trim(strconv.QuoteRune(r), "'"))
+			continue
+		}
+		switch {
+		case unicode.IsPrint(r):
+			buf.WriteRune(r)
+		case r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			buf.WriteString(`\u` + hex4(r1) + `\u` + hex4(r2))
+		default:
+			buf.WriteString(`\u` + hex4(r))
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// helper function
+func hex4(r rune) string {
+	s := strconv.FormatInt(int64(r), 16)
+	return strings.Repeat("0", 4-len(s)) + s
+}
+
+// cljNumber returns the number x in Clojure syntax: integers in
+// decimal, with N if they need more than 64 bits, and floats with
+// a digit on each side of the point. Clojure has no complex
+// numbers, so an imaginary number is (complex 0 y).
+func cljNumber(x *Number) Node {
+	lit := x.Lit
+	imag := strings.HasSuffix(lit, "i")
+	if imag {
+		lit = strings.Replace(strings.TrimSuffix(lit, "i"), "+", "", 1)
+	}
+	switch {
+	case strings.HasPrefix(lit, "#i"):
+		if r, ok := new(big.Rat).SetString(lit[2:]); ok {
+			f, _ := r.Float64()
+			lit = strconv.FormatFloat(f, 'g', -1, 64)
+			if !strings.ContainsAny(lit, ".eEn") {
+				lit += ".0"
+			}
+		}
+	case len(lit) > 2 && lit[0] == '#':
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[lit[1]]
+		if n, ok := new(big.Int).SetString(lit[2:], base); ok && base != 0 {
+			lit = n.String()
+			if !n.IsInt64() {
+				lit += "N"
+			}
+		}
+	case strings.Contains(lit, "."):
+		if strings.HasPrefix(lit, ".") {
+			lit = "0" + lit
+		}
+		if i := strings.IndexByte(lit, '.'); i+1 == len(lit) || !unicode.IsDigit(rune(lit[i+1])) {
+			lit = lit[:i+1] + "0" + lit[i+1:]
+		}
+	case !strings.ContainsAny(lit, "eE"):
+		if n, ok := new(big.Int).SetString(lit, 10); ok && !n.IsInt64() {
+			lit += "N"
+		}
+	}
+	num := &Symbol{Position: x.Position, Name: lit}
+	if !imag {
+		return num
+	}
+	return &List{Position: x.Position, Elts: []Node{symbol("complex"), symbol("0"), num}}
+}
@@ -0,0 +1,95 @@
+package gos
+
+import (
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Diagnostic describes a construct that could not be translated,
+// or an error in the input found while parsing or type-checking.
+type Diagnostic struct {
+	Pos token.Position
+	Msg string
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Msg)
+}
+
+// Diagnostics is the aggregated error returned by Compile when
+// anything was reported. Translation continues past each one.
+type Diagnostics []Diagnostic
+
+func (list Diagnostics) Error() string {
+	lines := make([]string, len(list))
+	for i, d := range list {
+		lines[i] = d.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (list Diagnostics) Len() int {
+	return len(list)
+}
+
+func (list Diagnostics) Less(i, j int) bool {
+	a, b := list[i].Pos, list[j].Pos
+	if a.Filename != b.Filename {
+		return a.Filename < b.Filename
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
+
+func (list Diagnostics) Swap(i, j int) {
+	list[i], list[j] = list[j], list[i]
+}
+
+// Err returns list sorted by position, or nil if it is empty.
+func (list Diagnostics) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	sort.Stable(list)
+	return list
+}
+
+// errorf records a diagnostic at the position of node.
+func (c *Compiler) errorf(node ast.Node, format string, params ...interface{}) {
+	var pos token.Position
+	if node != nil && c.fset != nil {
+		pos = c.fset.Position(node.Pos())
+	}
+	c.diags = append(c.diags, Diagnostic{pos, fmt.Sprintf(format, params...)})
+}
+
+// addError records err, which came from the parser or the type
+// checker, as one or more diagnostics.
+func (c *Compiler) addError(err error) {
+	switch e := err.(type) {
+	case scanner.ErrorList:
+		for _, a := range e {
+			c.diags = append(c.diags, Diagnostic{a.Pos, a.Msg})
+		}
+	case types.Error:
+		c.diags = append(c.diags, Diagnostic{e.Fset.Position(e.Pos), e.Msg})
+	case Diagnostics:
+		c.diags = append(c.diags, e...)
+	default:
+		c.diags = append(c.diags, Diagnostic{token.Position{Filename: c.Filename}, err.Error()})
+	}
+}
+
+// emitUnsupported records node as untranslatable and returns a
+// placeholder, so the rest of the output stays well-formed.
+func (c *Compiler) emitUnsupported(node ast.Node, kind string) Node {
+	c.errorf(node, "unsupported %s %T", kind, node)
+	return c.sym(node, "%unsupported")
+}
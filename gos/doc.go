@@ -0,0 +1,13 @@
+// Package gos translates Go source into Gos, a Scheme dialect whose
+// forms mirror Go's syntax: (package ...), (func ...), (when ...),
+// (range ...), (case! ...) and so on.
+//
+// Translate and its per-node counterparts work on an already parsed
+// go/ast tree; Compiler reads Go source directly, and Loader gathers
+// whole packages for CompilePackage. Each of them first builds the
+// output as a tree of Nodes, which BuildFile, Compiler.Build and
+// Compiler.BuildPackage return as is. Lower rewrites such a tree
+// into plainer Scheme, Fprint pretty-prints it and Write writes it
+// unformatted. A Backend decides how the tree is spelled, Gos
+// itself by default.
+package gos
@@ -0,0 +1,356 @@
+package gos
+
+import (
+	"strconv"
+	"strings"
+)
+
+// EDN is a Backend that writes only the data a package declares,
+// as one EDN map for configuration tools to read:
+//
+//	{:package "shapes"
+//	 :import-path "example.com/shapes"
+//	 :const {:Pi 3.14...}
+//	 :var {:scale 2.0}}
+//
+// The constants and variables are keyed by their Go names. Their
+// values are the literals they are declared with, with the
+// constants and variables of the package they name looked up, and
+// their composite literals written as vectors for slices and
+// arrays and as maps for maps and structs. A value that is not
+// data, such as a call, is the tagged literal #go/expr "form" of
+// its Clojure spelling.
+var EDN Backend = ednBackend{}
+
+type ednBackend struct{ gosBackend }
+
+func (ednBackend) Name() string { return "edn" }
+func (ednBackend) Ext() string  { return ".edn" }
+
+func (ednBackend) Package(pkg *List, importPath string) ([]Node, error) {
+	lib, err := newLibrary(pkg, importPath, LowerNone)
+	if err != nil {
+		return nil, err
+	}
+	e := &ednWriter{
+		lowerer:  &lowerer{level: LowerR7RS},
+		prefixes: make(map[string]bool),
+		types:    make(map[string]Node),
+		specs:    make(map[string]ednSpec),
+		values:   make(map[string]Node),
+	}
+	for _, spec := range lib.imports {
+		e.prefixes[spec.prefix()] = true
+	}
+	decls := map[string]*Brackets{
+		"const": {Map: true},
+		"var":   {Map: true},
+	}
+	for _, x := range lib.body {
+		switch Head(x) {
+		case "type":
+			for _, spec := range typeSpecs(x.(*List)) {
+				e.types[spec.name] = spec.typ
+			}
+		case "var", "const":
+			for _, spec := range e.lowerer.specs(x.(*List)) {
+				for i, name := range spec.names {
+					if len(spec.values) == len(spec.names) {
+						e.specs[name.String()] = ednSpec{spec.typ, spec.values[i]}
+					} else if len(spec.values) == 0 {
+						e.specs[name.String()] = ednSpec{spec.typ, nil}
+					}
+				}
+			}
+		}
+	}
+	var comments []Node
+	for _, x := range lib.body {
+		switch Head(x) {
+		case "var", "const":
+		default:
+			if c, ok := x.(*Comment); ok {
+				comments = append(comments, c)
+			} else {
+				comments = nil
+			}
+			continue
+		}
+		m := decls[Head(x)]
+		m.Elts = append(m.Elts, comments...)
+		comments = nil
+		for _, spec := range e.lowerer.specs(x.(*List)) {
+			if spec.comment != nil {
+				m.Elts = append(m.Elts, spec.comment)
+				continue
+			}
+			for _, name := range spec.names {
+				if _, ok := e.specs[name.String()]; !ok || isSymbol(name, "_") {
+					// set from the values of one call
+					continue
+				}
+				m.Elts = append(m.Elts, symbol(":"+MangleName(name.String())), e.value(name))
+			}
+		}
+	}
+	out := &Brackets{Position: pkg.Position, Map: true, Pairs: true, Elts: []Node{
+		symbol(":package"), cljLiteral(&String{Value: lib.name}),
+		symbol(":import-path"), cljLiteral(&String{Value: lib.path}),
+		symbol(":const"), decls["const"],
+		symbol(":var"), decls["var"],
+	}}
+	return []Node{out}, e.diags.Err()
+}
+
+// ednSpec is the type and value a constant or variable is declared
+// with; a nil value is the zero value of the type.
+type ednSpec struct {
+	typ, value Node
+}
+
+// ednWriter turns the values of a package's constants and
+// variables into EDN.
+type ednWriter struct {
+	*lowerer
+	prefixes map[string]bool    // the names of the imported packages
+	types    map[string]Node    // the package's types
+	specs    map[string]ednSpec // the package's constants and variables
+	values   map[string]Node    // those already written, or nil while being written
+}
+
+// value returns the value of the constant or variable name.
+func (e *ednWriter) value(name Node) Node {
+	if v, ok := e.values[name.String()]; ok {
+		if v == nil {
+			// declared in terms of itself
+			return e.expr(name)
+		}
+		return v
+	}
+	e.values[name.String()] = nil
+	spec := e.specs[name.String()]
+	var v Node
+	if spec.value == nil {
+		v = e.data(typeZero(spec.typ, e.types, 0), spec.typ)
+	} else {
+		v = e.data(spec.value, spec.typ)
+	}
+	e.values[name.String()] = v
+	return v
+}
+
+// data returns x, of type typ if that is not nil, as EDN.
+func (e *ednWriter) data(x, typ Node) Node {
+	switch x := x.(type) {
+	case *Number:
+		v := cljNumber(x)
+		if sym, ok := v.(*Symbol); ok && ednFloat(typ) && !strings.ContainsAny(sym.Name, ".eEN") {
+			return &Symbol{Position: sym.Position, Name: sym.Name + ".0"}
+		}
+		return v
+	case *Bytevector:
+		bytes := &Brackets{Position: x.Position}
+		for _, b := range x.Value {
+			bytes.Elts = append(bytes.Elts, symbol(strconv.Itoa(int(b))))
+		}
+		return bytes
+	case *Bool, *Nil, *Char, *String:
+		return cljLiteral(x)
+	case *Symbol:
+		if _, ok := e.specs[x.Name]; ok {
+			return e.value(x)
+		}
+	case *Vector:
+		return e.composite(x, typ)
+	case *List:
+		switch Head(x) {
+		case "-":
+			if len(x.Elts) == 2 {
+				if n, ok := e.data(x.Elts[1], typ).(*Symbol); ok && isNumber(n.Name) && n.Name[0] != '-' {
+					return &Symbol{Position: n.Position, Name: "-" + n.Name}
+				}
+			}
+		case "adr":
+			if len(x.Elts) == 2 {
+				return e.data(x.Elts[1], nil)
+			}
+		case "convert":
+			if len(x.Elts) == 3 {
+				return e.data(x.Elts[2], x.Elts[1])
+			}
+		default:
+			if v := e.call(x); v != nil {
+				return v
+			}
+		}
+	case *Brackets:
+		elts := make([]Node, len(x.Elts))
+		for i, elt := range x.Elts {
+			elts[i] = e.data(elt, nil)
+		}
+		return &Brackets{Position: x.Position, Map: x.Map, Elts: elts}
+	}
+	return e.expr(x)
+}
+
+// call returns the call x as EDN if it converts data to a type,
+// or constructs a struct of the package's from its fields, and
+// otherwise nil.
+func (e *ednWriter) call(x *List) Node {
+	typ := x.Elts[0]
+	under, local := e.types[typ.String()]
+	switch {
+	case local && Head(under) == "struct":
+		fields, types := fieldNames(under.(*List)), fieldTypes(under.(*List))
+		if len(fields) != len(x.Elts)-1 || len(types) != len(fields) {
+			return nil
+		}
+		out := &Brackets{Position: x.Position, Map: true}
+		for i, arg := range x.Elts[1:] {
+			out.Elts = append(out.Elts, symbol(":"+MangleName(fields[i])), e.data(arg, types[i]))
+		}
+		return out
+	case len(x.Elts) != 2:
+		return nil
+	case local, strings.HasPrefix(typ.String(), "&"):
+	default:
+		switch Head(typ) {
+		case "slice", "array", "map-type", "ptr":
+		default:
+			return nil
+		}
+	}
+	return e.data(x.Elts[1], typ)
+}
+
+// ednFloat reports whether typ is a floating-point type.
+func ednFloat(typ Node) bool {
+	if typ == nil {
+		return false
+	}
+	switch typ.String() {
+	case "&float32", "&float64":
+		return true
+	}
+	return false
+}
+
+// composite returns the composite literal #(T elts...), of type
+// typ if T is elided, as EDN: a vector for a slice or array, and a
+// map for a map or struct.
+func (e *ednWriter) composite(x *Vector, typ Node) Node {
+	if len(x.Elts) == 0 {
+		return e.expr(x)
+	}
+	if !isSymbol(x.Elts[0], "_") || typ == nil {
+		typ = x.Elts[0]
+	}
+	if Head(typ) == "ptr" && len(typ.(*List).Elts) == 2 {
+		typ = typ.(*List).Elts[1]
+	}
+	under := typ
+	for i := 0; i <= len(e.types); i++ {
+		t, ok := e.types[under.String()]
+		if !ok {
+			break
+		}
+		under = t
+	}
+	var key, elem Node
+	var fields []string
+	var types []Node
+	list, _ := under.(*List)
+	switch Head(under) {
+	case "slice", "array", "array...":
+		elem = list.Elts[len(list.Elts)-1]
+	case "map-type":
+		if len(list.Elts) == 3 {
+			key, elem = list.Elts[1], list.Elts[2]
+		}
+	case "struct":
+		fields, types = fieldNames(list), fieldTypes(list)
+	default:
+		// a struct type the package does not declare
+		for _, elt := range x.Elts[1:] {
+			if kv, ok := elt.(*List); !ok || Head(kv) != ":" || len(kv.Elts) != 3 {
+				return e.expr(x)
+			}
+		}
+	}
+	switch Head(under) {
+	case "slice", "array", "array...":
+		out := &Brackets{Position: x.Position}
+		for _, elt := range x.Elts[1:] {
+			if Head(elt) == ":" {
+				// an indexed array element
+				return e.expr(x)
+			}
+			out.Elts = append(out.Elts, e.data(elt, elem))
+		}
+		return out
+	}
+	out := &Brackets{Position: x.Position, Map: true}
+	for i, elt := range x.Elts[1:] {
+		kv, ok := elt.(*List)
+		switch {
+		case ok && Head(kv) == ":" && len(kv.Elts) == 3 && key != nil:
+			out.Elts = append(out.Elts, e.data(kv.Elts[1], key), e.data(kv.Elts[2], elem))
+		case ok && Head(kv) == ":" && len(kv.Elts) == 3:
+			var t Node
+			if j := indexOf(fields, kv.Elts[1].String()); j >= 0 && j < len(types) {
+				t = types[j]
+			}
+			out.Elts = append(out.Elts, symbol(":"+MangleName(kv.Elts[1].String())), e.data(kv.Elts[2], t))
+		case i < len(fields) && i < len(types):
+			out.Elts = append(out.Elts, symbol(":"+MangleName(fields[i])), e.data(elt, types[i]))
+		default:
+			return e.expr(x)
+		}
+	}
+	return out
+}
+
+// expr returns x, which is not data, as the tagged literal
+// #go/expr "form".
+func (e *ednWriter) expr(x Node) Node {
+	var buf strings.Builder
+	if err := Fprint(&buf, []Node{e.form(x)}, Style{Width: 1 << 30}); err != nil {
+		e.errorf(x, "%v", err)
+	}
+	return symbol("#go/expr " + cljString(strings.TrimSpace(buf.String())))
+}
+
+// form returns x spelled as Clojure, with its comments left out.
+func (e *ednWriter) form(x Node) Node {
+	var elts []Node
+	switch x := x.(type) {
+	case *Comment:
+		return nil
+	case *List:
+		elts = x.Elts
+	case *Vector:
+		elts = x.Elts
+	case *Brackets:
+		elts = x.Elts
+	case *Symbol:
+		if i := strings.IndexByte(x.Name, '.'); i > 0 && e.prefixes[x.Name[:i]] {
+			return symbol(x.Name[:i] + "/" + x.Name[i+1:])
+		}
+		return x
+	default:
+		return cljLiteral(x)
+	}
+	var out []Node
+	for _, elt := range elts {
+		if y := e.form(elt); y != nil {
+			out = append(out, y)
+		}
+	}
+	if b, ok := x.(*Brackets); ok {
+		return &Brackets{Map: b.Map, Elts: out}
+	}
+	if _, ok := x.(*Vector); ok {
+		return &Brackets{Elts: out}
+	}
+	return &List{Elts: out}
+}
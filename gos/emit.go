@@ -0,0 +1,1121 @@
+package gos
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// helper function
+func (c *Compiler) pos(node ast.Node) token.Position {
+	if node == nil || c.fset == nil || !node.Pos().IsValid() {
+		return token.Position{}
+	}
+	return c.fset.Position(node.Pos())
+}
+
+// helper function
+func (c *Compiler) list(node ast.Node, elts ...Node) *List {
+	return &List{Position: c.pos(node), Elts: elts}
+}
+
+// helper function
+func (c *Compiler) vector(node ast.Node, elts ...Node) *Vector {
+	return &Vector{Position: c.pos(node), Elts: elts}
+}
+
+// helper function
+func (c *Compiler) sym(node ast.Node, name string) *Symbol {
+	return &Symbol{Position: c.pos(node), Name: name}
+}
+
+// helper function
+func (c *Compiler) wrap(node ast.Node, name string, bits int64, x Node) Node {
+	// "(%s %d %s)", wrap, bits, expr
+	return c.list(node, c.form(node, name), c.literal(&Number{Position: c.pos(node), Lit: strconv.FormatInt(bits, 10)}), x)
+}
+
+func (c *Compiler) emitArrayType(node *ast.ArrayType) Node {
+	if node.Len == nil {
+		return c.list(node, c.form(node, "slice"), c.emitType(node.Elt))
+	} else if _, ok := node.Len.(*ast.Ellipsis); ok {
+		return c.list(node, c.form(node, "array..."), c.emitType(node.Elt))
+	}
+	return c.list(node, c.form(node, "array"), c.emitExpr(node.Len), c.emitType(node.Elt))
+}
+
+func (c *Compiler) emitAssignStmt(node *ast.AssignStmt) Node {
+	if len(node.Lhs) == 1 && len(node.Rhs) == 1 && token.ADD_ASSIGN <= node.Tok && node.Tok <= token.AND_NOT_ASSIGN {
+		op := node.Tok - (token.ADD_ASSIGN - token.ADD)
+		if name, _ := c.intWrap(node.Lhs[0]); name != "" && c.overflows(op, node.Lhs[0]) {
+			return c.emitWrappedAssign(node, node.Lhs[0], op, node.Rhs[0])
+		}
+	}
+	var op string
+	if len(node.Lhs) == 1 && len(node.Rhs) == 1 {
+		// "(%s= %s %s)", op, x, y for x op= y
+		op = c.schemeBinaryOp(node.Tok, node.Lhs[0], node.Rhs[0])
+	} else {
+		op = goBinaryOpToSchemeOp(node.Tok.String())
+	}
+	var lhs Node
+	if len(node.Lhs) == 1 {
+		switch a := node.Lhs[0].(type) {
+		case *ast.Ident:
+			lhs = c.emitIdent(a)
+		case *ast.SelectorExpr:
+			lhs = c.emitSelectorExpr(a)
+		}
+	}
+	if lhs == nil {
+		targets := c.list(node)
+		for _, expr := range node.Lhs {
+			targets.Elts = append(targets.Elts, c.emitExpr(expr))
+		}
+		lhs = targets
+	}
+	x := c.list(node, c.form(node, op), lhs)
+	for _, expr := range node.Rhs {
+		x.Elts = append(x.Elts, c.emitOperand(node.Tok, expr))
+	}
+	return x
+}
+
+// helper function
+func (c *Compiler) emitWrappedAssign(node ast.Stmt, lhs ast.Expr, op token.Token, rhs ast.Expr) Node {
+	// "(= %s (%s %d (%s %s %s)))", x, wrap, bits, op, x, y
+	// "(begin (:= %%t0 i) (= (%s) ...))", x for x with side effects
+	name, bits := c.intWrap(lhs)
+	var temps []Node
+	place := c.evalOnce(lhs, &temps)
+	value := c.emitBinaryExpr(&ast.BinaryExpr{X: place, Op: op, Y: rhs})
+	target := c.emitExpr(place)
+	switch place.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+	default:
+		target = c.list(lhs, target)
+	}
+	x := c.list(node, c.form(node, "="), target, c.wrap(lhs, name, bits, value))
+	if len(temps) == 0 {
+		return x
+	}
+	// emitStmts splices the temporaries into the enclosing statements
+	return c.list(node, append(append([]Node{c.form(node, "begin")}, temps...), x)...)
+}
+
+// evalOnce returns the assignable expr with each operand that has
+// side effects replaced by a temporary, and appends the (:= %tN x)
+// forms that bind them to temps, so that the place x op= y assigns
+// is evaluated once. The type information of the replaced nodes is
+// copied to their replacements.
+func (c *Compiler) evalOnce(expr ast.Expr, temps *[]Node) ast.Expr {
+	var out ast.Expr
+	switch a := expr.(type) {
+	case *ast.ParenExpr:
+		return c.evalOnce(a.X, temps)
+	case *ast.SelectorExpr:
+		sel := *a
+		sel.X = c.evalOperand(a.X, temps)
+		if s := c.selection(a); s != nil {
+			c.setSelection(&sel, s)
+		}
+		out = &sel
+	case *ast.StarExpr:
+		star := *a
+		star.X = c.temp(a.X, temps)
+		out = &star
+	case *ast.IndexExpr:
+		index := *a
+		index.X = c.evalOperand(a.X, temps)
+		index.Index = c.temp(a.Index, temps)
+		out = &index
+	default:
+		return expr
+	}
+	if tv, ok := c.typeAndValue(expr); ok {
+		c.setType(out, tv)
+	}
+	return out
+}
+
+// evalOperand is evalOnce for the operand of a selector or index
+// expression: an array or struct is a variable of its own, whose
+// operands are evaluated once, and anything else a reference that
+// can be held in a temporary.
+func (c *Compiler) evalOperand(expr ast.Expr, temps *[]Node) ast.Expr {
+	if tv, ok := c.typeAndValue(expr); ok && tv.Type != nil {
+		switch tv.Type.Underlying().(type) {
+		case *types.Array, *types.Struct:
+			return c.evalOnce(expr, temps)
+		}
+	}
+	return c.temp(expr, temps)
+}
+
+// temp returns expr if it is pure, and otherwise a new temporary
+// %tN, appending the (:= %tN expr) form that binds it to temps.
+func (c *Compiler) temp(expr ast.Expr, temps *[]Node) ast.Expr {
+	if isPure(expr) {
+		return expr
+	}
+	id := &ast.Ident{NamePos: expr.Pos(), Name: "%t" + strconv.Itoa(c.temps)}
+	c.temps++
+	if tv, ok := c.typeAndValue(expr); ok {
+		c.setType(id, tv)
+	}
+	*temps = append(*temps, c.list(expr, c.form(expr, ":="), c.emitIdent(id), c.emitExpr(expr)))
+	return id
+}
+
+func (c *Compiler) emitBasicLit(node *ast.BasicLit) Node {
+	switch node.Kind {
+	case token.CHAR:
+		return c.literal(goCharToSchemeChar(node, c.pos(node)))
+	case token.INT, token.FLOAT, token.IMAG:
+		return c.literal(goNumberToSchemeNumber(node, c.pos(node)))
+	}
+	x := goStringToSchemeString(node, c.pos(node))
+	if conv, ok := x.(*List); ok {
+		// (convert &imm-string #u8(...))
+		return c.list(node, c.form(node, "convert"), c.sym(node, c.backend().TypeName("string")), c.literal(conv.Elts[2]))
+	}
+	return c.literal(x)
+}
+
+func (c *Compiler) emitBinaryExpr(node *ast.BinaryExpr) Node {
+	// "(%s %s %s)", op, x, y
+	// "(not (%s %s %s))", op, x, y for x != y
+	var x Node = c.list(node, c.form(node, c.schemeBinaryOp(node.Op, node.X, node.Y)),
+		c.emitExpr(node.X), c.emitOperand(node.Op, node.Y))
+	if node.Op == token.NEQ {
+		x = c.list(node, c.form(node, "not"), x)
+	}
+	if c.overflows(node.Op, node) {
+		if name, bits := c.intWrap(node); name != "" {
+			x = c.wrap(node, name, bits, x)
+		}
+	}
+	return x
+}
+
+// helper function
+func (c *Compiler) emitOperand(op token.Token, node ast.Expr) Node {
+	if op == token.SHR || op == token.SHR_ASSIGN {
+		// shift right by n is arithmetic-shift by (- n)
+		return c.list(node, c.form(node, "-"), c.emitExpr(node))
+	}
+	return c.emitExpr(node)
+}
+
+func (c *Compiler) emitBlockStmt(node *ast.BlockStmt) []Node {
+	if node == nil { return nil }
+	return c.emitStmtList(node.List)
+}
+
+// helper function
+func (c *Compiler) emitStmtList(list []ast.Stmt) []Node {
+	var out []Node
+	for _, stmt := range list {
+		out = append(out, c.emitStmts(stmt)...)
+	}
+	return out
+}
+
+func (c *Compiler) emitBranchStmt(node *ast.BranchStmt) Node {
+	// (break), (continue), (goto label), (fallthrough)
+	x := c.list(node, c.form(node, node.Tok.String()))
+	if node.Label != nil {
+		x.Elts = append(x.Elts, c.sym(node.Label, node.Label.String()))
+	}
+	return x
+}
+
+func (c *Compiler) emitCallExpr(node *ast.CallExpr) Node {
+	if c.isType(node.Fun) {
+		// (convert T x)
+		var x Node = c.list(node, c.form(node, "convert"), c.emitType(node.Fun))
+		for _, arg := range node.Args {
+			x.(*List).Elts = append(x.(*List).Elts, c.emitExpr(arg))
+		}
+		if name, bits := c.intWrap(node); name != "" && len(node.Args) == 1 {
+			from, fromBits := c.intWrap(node.Args[0])
+			widens := from == name && fromBits <= bits ||
+				from == "wrap-unsigned" && fromBits < bits
+			if from != "" && !widens {
+				// (wrap-unsigned 8 (convert &uint8 x))
+				x = c.wrap(node, name, bits, x)
+			}
+		}
+		return x
+	}
+	x := c.list(node)
+	if node.Ellipsis != 0 {
+		x.Elts = append(x.Elts, c.form(node, "apply..."))
+	}
+	if sel, ok := node.Fun.(*ast.SelectorExpr); ok &&
+		c.selection(sel) != nil && c.selection(sel).Kind() == types.MethodVal {
+		// (call-method x M args...)
+		x.Elts = append(x.Elts, c.form(sel, "call-method"), c.emitExpr(sel.X),
+			c.sym(sel.Sel, c.ident(sel.Sel.Name)))
+	} else {
+		x.Elts = append(x.Elts, c.emitExpr(node.Fun))
+	}
+	for _, arg := range node.Args {
+		x.Elts = append(x.Elts, c.emitExpr(arg))
+	}
+	return x
+}
+
+func (c *Compiler) emitCaseClause(node *ast.CaseClause, cond bool) Node {
+	if node.List == nil || len(node.List) == 0 {
+		x := c.list(node, c.form(node, "else"))
+		x.Elts = append(x.Elts, c.emitStmtList(node.Body)...)
+		return x
+	}
+	var test Node
+	if cond {
+		test = c.emitExpr(node.List[0])
+	} else {
+		values := c.list(node.List[0])
+		for _, expr := range node.List {
+			values.Elts = append(values.Elts, c.emitExpr(expr))
+		}
+		test = values
+	}
+	x := c.list(node, test)
+	x.Elts = append(x.Elts, c.emitStmtList(node.Body)...)
+	return x
+}
+
+// ChanDir
+
+func (c *Compiler) emitChanType(node *ast.ChanType) Node {
+	head := "chan"
+	switch node.Dir {
+	case ast.RECV:
+		head += "<-"
+	case ast.SEND:
+		head += "<-!"
+	}
+	return c.list(node, c.form(node, head), c.emitType(node.Value))
+}
+
+func (c *Compiler) emitCommClause(node *ast.CommClause) Node {
+	var x *List
+	if node.Comm == nil {
+		x = c.list(node, c.form(node, "else"))
+	} else {
+		x = c.list(node, c.emitStmt(node.Comm))
+	}
+	x.Elts = append(x.Elts, c.emitStmtList(node.Body)...)
+	return x
+}
+
+func (c *Compiler) emitComment(node *ast.Comment) []Node {
+	// ";; %s\n", text
+	pos := c.pos(node)
+	text := node.Text
+	if strings.HasPrefix(text, "//") {
+		return []Node{&Comment{Position: pos, Text: ";;" + strings.TrimRight(text[2:], " \t\r")}}
+	}
+	var out []Node
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			out = append(out, &Comment{Position: pos, Text: ";;"})
+			continue
+		}
+		out = append(out, &Comment{Position: pos, Text: ";; " + strings.TrimLeft(line, " \t")})
+	}
+	return out
+}
+
+// emitCommentGroup returns the comments of node, the first of them
+// trailing the preceding node if trailing is set.
+func (c *Compiler) emitCommentGroup(node *ast.CommentGroup, trailing bool) []Node {
+	if node == nil {
+		return nil
+	}
+	var out []Node
+	for _, comment := range node.List {
+		out = append(out, c.emitComment(comment)...)
+	}
+	if len(out) > 0 {
+		out[0].(*Comment).Trailing = trailing
+	}
+	return out
+}
+
+// helper function
+func (c *Compiler) emitDocString(node *ast.CommentGroup) []Node {
+	if node == nil {
+		return nil
+	}
+	// "\"%s\" ", doc
+	text := strings.TrimRight(node.Text(), "\n")
+	return []Node{c.literal(&String{Position: c.pos(node), Value: text})}
+}
+
+func (c *Compiler) emitCompositeLit(node *ast.CompositeLit) Node {
+	x := c.vector(node)
+	if node.Type == nil {
+		// elided, as in []T{{...}}
+		x.Elts = append(x.Elts, c.form(node, "_"))
+	} else {
+		x.Elts = append(x.Elts, c.emitType(node.Type))
+	}
+	for _, arg := range node.Elts {
+		x.Elts = append(x.Elts, c.emitExpr(arg))
+	}
+	return x
+}
+
+func (c *Compiler) emitDecl(node ast.Decl) []Node {
+	switch a := node.(type) {
+	case *ast.GenDecl:
+		return c.emitGenDecl(a)
+	case *ast.FuncDecl:
+		return []Node{c.emitFuncDecl(a)}
+	default:
+		return []Node{c.emitUnsupported(node, "declaration")}
+	}
+}
+
+// DeclStmt
+
+func (c *Compiler) emitDeferStmt(node *ast.DeferStmt) Node {
+	return c.list(node, c.form(node, "defer"), c.emitCallExpr(node.Call))
+}
+
+func (c *Compiler) emitEmptyStmt(node *ast.EmptyStmt) Node {
+	return c.literal(&Bool{Position: c.pos(node), Value: false})
+}
+
+func (c *Compiler) emitExpr(node ast.Expr) Node {
+	switch a := node.(type) {
+	case *ast.BasicLit:       return c.emitBasicLit(a)
+	case *ast.CompositeLit:   return c.emitCompositeLit(a)
+	case *ast.Ellipsis:       return c.emitExpr(a.Elt)
+	case *ast.FuncLit:        return c.emitFuncLit(a)
+	case *ast.Ident:          return c.emitIdent(a)
+
+	// Expr
+	case *ast.BinaryExpr:     return c.emitBinaryExpr(a)
+	case *ast.CallExpr:       return c.emitCallExpr(a)
+	case *ast.IndexExpr:      return c.emitIndexExpr(a)
+	case *ast.IndexListExpr:  return c.emitIndexListExpr(a)
+	case *ast.KeyValueExpr:   return c.emitKeyValueExpr(a)
+	case *ast.ParenExpr:      return c.emitExpr(a.X)
+	case *ast.SelectorExpr:   return c.emitSelectorExpr(a)
+	case *ast.SliceExpr:      return c.emitSliceExpr(a)
+	case *ast.StarExpr:       return c.emitStarExpr(a)
+	case *ast.TypeAssertExpr: return c.emitTypeAssertExpr(a)
+	case *ast.UnaryExpr:      return c.emitUnaryExpr(a)
+
+	// Type
+	case *ast.ArrayType:      return c.emitArrayType(a)
+	case *ast.ChanType:       return c.emitChanType(a)
+	case *ast.FuncType:       return c.emitFuncType(a)
+	case *ast.InterfaceType:  return c.emitInterfaceType(a)
+	case *ast.MapType:        return c.emitMapType(a)
+	case *ast.StructType:     return c.emitStructType(a)
+
+	default:
+		return c.emitUnsupported(node, "expression")
+	}
+}
+
+// ExprStmt
+
+func (c *Compiler) emitField(node *ast.Field) Node {
+	if len(node.Names) == 0 {
+		return c.emitType(node.Type)
+	}
+	x := c.vector(node)
+	for _, name := range node.Names {
+		x.Elts = append(x.Elts, c.sym(name, c.ident(name.Name)))
+	}
+	x.Elts = append(x.Elts, c.emitType(node.Type))
+	return x
+}
+
+// FieldFilter
+
+func (c *Compiler) emitFieldList(node *ast.FieldList) []Node {
+	var out []Node
+	for _, field := range node.List {
+		out = append(out, c.emitCommentGroup(field.Doc, false)...)
+		out = append(out, c.emitField(field))
+		out = append(out, c.emitCommentGroup(field.Comment, true)...)
+	}
+	return out
+}
+
+func (c *Compiler) emitFile(node *ast.File) []Node {
+	return c.emitPackage(node.Name.Name, []*ast.File{node})
+}
+
+func (c *Compiler) emitPackage(name string, files []*ast.File) []Node {
+	var out []Node
+	for _, file := range files {
+		out = append(out, c.emitCommentGroup(file.Doc, false)...)
+	}
+	var pos ast.Node
+	if len(files) > 0 {
+		pos = files[0].Name
+	}
+	x := c.list(pos, c.form(pos, "package"), c.sym(pos, c.ident(name)))
+	c.pkg = c.definedPackage()
+	for _, file := range files {
+		c.imports = c.importNames(file)
+		for _, decl := range file.Decls {
+			x.Elts = append(x.Elts, c.emitDecl(decl)...)
+		}
+	}
+	if p, ok := c.backend().(Packager); ok {
+		var forms []Node
+		var err error
+		if tp, ok := p.(typedPackager); ok && c.info != nil {
+			forms, err = tp.typedPackage(x, c.ImportPath)
+		} else {
+			forms, err = p.Package(x, c.ImportPath)
+		}
+		if err != nil {
+			c.addError(err)
+		}
+		return append(out, forms...)
+	}
+	return append(out, x)
+}
+
+func (c *Compiler) emitForStmt(node *ast.ForStmt) Node {
+	var cond Node = c.literal(&Bool{Position: c.pos(node), Value: true})
+	if node.Cond != nil {
+		cond = c.emitExpr(node.Cond)
+	}
+	if node.Init == nil && node.Post == nil {
+		x := c.list(node, c.form(node, "while"), cond)
+		x.Elts = append(x.Elts, c.emitBlockStmt(node.Body)...)
+		return x
+	}
+
+	var init, post Node = c.literal(&Bool{Position: c.pos(node)}), c.literal(&Bool{Position: c.pos(node)})
+	if node.Init != nil {
+		init = c.emitStmt(node.Init)
+	}
+	if node.Post != nil {
+		post = c.emitStmt(node.Post)
+	}
+	x := c.list(node, c.form(node, "for"), init, cond, post)
+	x.Elts = append(x.Elts, c.emitBlockStmt(node.Body)...)
+	return x
+}
+
+// helper function
+func isVariadic(node *ast.FuncType) bool {
+	if pars := node.Params.List;
+	   pars != nil && len(pars) > 0 {
+		last := pars[len(pars) - 1]
+		if _, ok := last.Type.(*ast.Ellipsis); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function
+func (c *Compiler) emitFuncHead(node *ast.FuncType) *Symbol {
+	if isVariadic(node) {
+		return c.form(node, "func...")
+	}
+	return c.form(node, "func")
+}
+
+func (c *Compiler) emitFuncDecl(node *ast.FuncDecl) Node {
+	// "(define-func (%s %s) %s)", name, type, body
+	x := c.list(node, c.emitFuncHead(node.Type))
+	if node.Recv != nil {
+		x.Elts = append(x.Elts, c.emitFieldList(node.Recv)...)
+	}
+	x.Elts = append(x.Elts, c.emitTypeParams(node.Name, node.Type.TypeParams))
+	x.Elts = append(x.Elts, c.emitFuncTypes(node.Type)...)
+	x.Elts = append(x.Elts, c.emitDocString(node.Doc)...)
+	x.Elts = append(x.Elts, c.emitBlockStmt(node.Body)...)
+	return x
+}
+
+func (c *Compiler) emitFuncLit(node *ast.FuncLit) Node {
+	x := c.list(node, c.emitFuncHead(node.Type))
+	x.Elts = append(x.Elts, c.emitFuncTypes(node.Type)...)
+	x.Elts = append(x.Elts, c.emitBlockStmt(node.Body)...)
+	return x
+}
+
+func (c *Compiler) emitFuncType(node *ast.FuncType) Node {
+	x := c.list(node, c.emitFuncHead(node))
+	x.Elts = append(x.Elts, c.emitFuncTypes(node)...)
+	return x
+}
+
+func (c *Compiler) emitFuncTypes(node *ast.FuncType) []Node {
+	// It is the responsibility of the caller to
+	// add "func " or whatever is appropriate
+	// because we have no idea at the point if this
+	// is being called from a Decl/Stmt/Expr, etc.
+	params := c.list(node.Params, c.emitFieldList(node.Params)...)
+	return []Node{params, c.emitFuncResults(node, node.Results)}
+}
+
+func (c *Compiler) emitFuncResults(fn *ast.FuncType, node *ast.FieldList) Node {
+	if node == nil || len(node.List) == 0 {
+		return c.sym(fn, c.backend().TypeName("void"))
+	}
+
+	if len(node.List) == 1 {
+		return c.emitField(node.List[0])
+	}
+
+	x := c.list(node, c.form(node, "values"))
+	for _, field := range node.List {
+		x.Elts = append(x.Elts, c.emitField(field))
+	}
+	return x
+}
+
+func (c *Compiler) emitGenDecl(node *ast.GenDecl) []Node {
+	out := c.emitCommentGroup(node.Doc, false)
+	if node.Tok == token.IMPORT {
+		// "(import \"%s\")", path
+		// "(import (as %s \"%s\"))", name, path
+		// "(import (dot \"%s\"))", path
+		return append(out, c.emitImports(node, node.Specs))
+	}
+
+	// otherwise
+	x := c.list(node, c.form(node, node.Tok.String()))
+	switch node.Tok {
+	case token.TYPE:
+		// "(define-type %s %s)", name, type
+		for _, spec := range node.Specs {
+			spec := spec.(*ast.TypeSpec)
+			x.Elts = append(x.Elts, c.emitSpecComment(spec.Doc, spec.Comment, c.emitTypeSpec(spec)...)...)
+		}
+	case token.CONST:
+		// "(define-const %s)", name
+		// "(define-const (= %s %s))", name, value
+		// "(define-const (= #(%s %s) %s))", name, type, value
+		fallthrough
+	case token.VAR:
+		// "(define-var (= %s %s))", name, value
+		// "(define-var (= (%s) %s))", name(s), value(s)
+		// "(define-var (= #(%s %s) %s))", name(s), type, value(s)
+		// "(define-var #(%s %s))", name(s), type
+		for _, spec := range node.Specs {
+			spec := spec.(*ast.ValueSpec)
+			x.Elts = append(x.Elts, c.emitSpecComment(spec.Doc, spec.Comment, c.emitValueSpec(spec))...)
+		}
+	}
+	return append(out, x)
+}
+
+// helper function
+func (c *Compiler) emitSpecComment(doc, comment *ast.CommentGroup, spec ...Node) []Node {
+	out := c.emitCommentGroup(doc, false)
+	out = append(out, spec...)
+	return append(out, c.emitCommentGroup(comment, true)...)
+}
+
+func (c *Compiler) emitGoStmt(node *ast.GoStmt) Node {
+	return c.list(node, c.form(node, "go"), c.emitCallExpr(node.Call))
+}
+
+func (c *Compiler) emitIdent(node *ast.Ident) Node {
+	if id := goIdTable[node.Name]; id != "" && !isTypeName(node.Name) {
+		// true, false and nil
+		return c.literal(atom(c.pos(node), id))
+	}
+	return c.sym(node, c.ident(node.Name))
+}
+
+func (c *Compiler) emitIfStmt(node *ast.IfStmt) Node {
+	unless := false
+	if un, ok := node.Cond.(*ast.UnaryExpr); ok {
+		if un.Op.String() == "!" {
+			unless = true
+		}
+	}
+	head := "when"
+	if unless {
+		head = "unless"
+	}
+	if node.Init != nil {
+		head += "*"
+	}
+	x := c.list(node, c.form(node, head))
+	if node.Init != nil {
+		x.Elts = append(x.Elts, c.emitStmt(node.Init))
+	}
+	if unless {
+		x.Elts = append(x.Elts, c.emitExpr(node.Cond.(*ast.UnaryExpr).X))
+	} else {
+		x.Elts = append(x.Elts, c.emitExpr(node.Cond))
+	}
+	x.Elts = append(x.Elts, c.emitBlockStmt(node.Body)...)
+	if node.Else != nil {
+		els := c.list(node.Else, c.form(node.Else, "else"))
+		switch a := node.Else.(type) {
+		case *ast.BlockStmt:
+			els.Elts = append(els.Elts, c.emitBlockStmt(a)...)
+		default:
+			els.Elts = append(els.Elts, c.emitStmt(node.Else))
+		}
+		x.Elts = append(x.Elts, els)
+	}
+	return x
+}
+
+func (c *Compiler) emitImports(node ast.Node, any interface{}) Node {
+	x := c.list(node, c.form(node, "import"))
+	switch specs := any.(type) {
+
+	// from (*ast.GenDecl).Specs
+	case []ast.Spec:
+		for _, spec := range specs {
+			x.Elts = append(x.Elts, c.emitImportSpec(spec.(*ast.ImportSpec)))
+		}
+
+	// from (*ast.File).Imports
+	case []*ast.ImportSpec:
+		for _, spec := range specs {
+			x.Elts = append(x.Elts, c.emitImportSpec(spec))
+		}
+	}
+	return x
+}
+
+func (c *Compiler) emitImportSpec(node *ast.ImportSpec) Node {
+	if node.Name != nil {
+		if node.Name.Name == "." {
+			return c.list(node, c.form(node.Name, "dot"), c.emitBasicLit(node.Path))
+		}
+		return c.list(node, c.form(node.Name, "as"), c.emitIdent(node.Name), c.emitBasicLit(node.Path))
+	}
+	if name := c.imports[node]; name != "" {
+		// a package not named after its path, as (as yaml "gopkg.in/yaml.v3")
+		return c.list(node, c.form(node, "as"), c.sym(node, c.ident(name)), c.emitBasicLit(node.Path))
+	}
+	return c.emitBasicLit(node.Path)
+}
+
+// Importer
+
+func (c *Compiler) emitIncDecStmt(node *ast.IncDecStmt) Node {
+	if name, _ := c.intWrap(node.X); name != "" {
+		op := token.ADD
+		if node.Tok == token.DEC {
+			op = token.SUB
+		}
+		return c.emitWrappedAssign(node, node.X, op, &ast.BasicLit{ValuePos: node.TokPos, Kind: token.INT, Value: "1"})
+	}
+	return c.list(node, c.form(node, node.Tok.String()), c.emitExpr(node.X))
+}
+
+func (c *Compiler) emitIndexExpr(node *ast.IndexExpr) Node {
+	if c.isInstance(node.X) {
+		return c.emitInstance(node, node.X, []ast.Expr{node.Index})
+	}
+	return c.list(node, c.form(node, "index"), c.emitExpr(node.X), c.emitExpr(node.Index))
+}
+
+func (c *Compiler) emitIndexListExpr(node *ast.IndexListExpr) Node {
+	return c.emitInstance(node, node.X, node.Indices)
+}
+
+func (c *Compiler) emitInstance(node ast.Node, generic ast.Expr, args []ast.Expr) Node {
+	// "(inst %s %s ...)", generic, type argument(s)
+	x := c.list(node, c.form(node, "inst"), c.emitType(generic))
+	for _, arg := range args {
+		x.Elts = append(x.Elts, c.emitType(arg))
+	}
+	return x
+}
+
+func (c *Compiler) emitInterfaceType(node *ast.InterfaceType) Node {
+	x := c.list(node, c.form(node, "interface"))
+	x.Elts = append(x.Elts, c.emitFieldList(node.Methods)...)
+	return x
+}
+
+func (c *Compiler) emitKeyValueExpr(node *ast.KeyValueExpr) Node {
+	var key Node
+	if k, ok := node.Key.(*ast.BasicLit); ok {
+		key = c.emitBasicLit(k)
+	} else if k, ok := node.Key.(*ast.Ident); ok {
+		key = c.sym(k, c.ident(k.Name))
+	} else {
+		// map keys may be arbitrary expressions
+		key = c.emitExpr(node.Key)
+	}
+	return c.list(node, c.form(node, ":"), key, c.emitExpr(node.Value))
+}
+
+func (c *Compiler) emitLabeledStmt(node *ast.LabeledStmt) Node {
+	x := c.list(node, c.form(node, "label"), c.sym(node.Label, node.Label.String()))
+	x.Elts = append(x.Elts, c.emitStmts(node.Stmt)...)
+	return x
+}
+
+func (c *Compiler) emitMapType(node *ast.MapType) Node {
+	return c.list(node, c.form(node, "map-type"), c.emitType(node.Key), c.emitType(node.Value))
+}
+
+// MergeMode
+// Node
+// ObjKind
+// Object
+// Package
+// ParenExpr
+
+func (c *Compiler) emitRangeStmt(node *ast.RangeStmt) Node {
+	if node.Key == nil {
+		// "(range %s %s)", x, body
+		x := c.list(node, c.form(node, "range"), c.emitExpr(node.X))
+		x.Elts = append(x.Elts, c.emitBlockStmt(node.Body)...)
+		return x
+	}
+	vars := c.emitExpr(node.Key)
+	if node.Value != nil {
+		vars = c.list(node.Key, vars, c.emitExpr(node.Value))
+	}
+	x := c.list(node, c.form(node, "range"),
+		c.list(node.Key, c.form(node.Key, node.Tok.String()), vars, c.emitExpr(node.X)))
+	x.Elts = append(x.Elts, c.emitBlockStmt(node.Body)...)
+	return x
+}
+
+func (c *Compiler) emitReturnStmt(node *ast.ReturnStmt) Node {
+	x := c.list(node, c.form(node, "return"))
+	for _, arg := range node.Results {
+		x.Elts = append(x.Elts, c.emitExpr(arg))
+	}
+	return x
+}
+
+// Scope
+
+func (c *Compiler) emitSelectStmt(node *ast.SelectStmt) Node {
+	x := c.list(node, c.form(node, "comm!"))
+	for _, stmt := range node.Body.List {
+		x.Elts = append(x.Elts, c.emitCommClause(stmt.(*ast.CommClause)))
+	}
+	return x
+}
+
+func (c *Compiler) emitSelectorExpr(node *ast.SelectorExpr) Node {
+	if c.info != nil {
+		return c.emitTypedSelectorExpr(node)
+	}
+	if id, ok := node.X.(*ast.Ident); ok {
+		return c.sym(node, c.ident(id.Name)+"."+c.ident(node.Sel.Name))
+	}
+	return c.list(node, c.form(node, "dot"), c.emitExpr(node.X), c.sym(node.Sel, c.ident(node.Sel.Name)))
+}
+
+func (c *Compiler) emitTypedSelectorExpr(node *ast.SelectorExpr) Node {
+	if id, ok := node.X.(*ast.Ident); ok && c.isPackage(id) {
+		// pkg.Name
+		return c.sym(node, c.ident(id.Name)+"."+c.ident(node.Sel.Name))
+	}
+	form := "dot"
+	if sel := c.selection(node); sel != nil {
+		switch sel.Kind() {
+		case types.FieldVal:
+			if a, ok := c.backend().(fieldAccessor); ok {
+				return c.emitFieldPath(node, sel, a)
+			}
+		case types.MethodVal:
+			form = "method"
+		case types.MethodExpr:
+			form = "method-expr"
+		}
+	}
+	// (dot x f), (method x M), (method-expr T M)
+	var x Node
+	if form == "method-expr" {
+		x = c.emitType(node.X)
+	} else {
+		x = c.emitExpr(node.X)
+	}
+	return c.list(node, c.form(node, form), x, c.sym(node.Sel, c.ident(node.Sel.Name)))
+}
+
+// emitFieldPath returns the field selection node as a selection
+// of each field on the path sel takes to it, (dot (dot x e) f) for
+// an f promoted from the embedded field e, with the fields of the
+// structs the package declares named by their accessors.
+func (c *Compiler) emitFieldPath(node *ast.SelectorExpr, sel *types.Selection, a fieldAccessor) Node {
+	recv := c.emitExpr(node.X)
+	x, t := recv, sel.Recv()
+	for _, i := range sel.Index() {
+		if ptr, ok := t.Underlying().(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			return c.list(node, c.form(node, "dot"), recv, c.sym(node.Sel, c.ident(node.Sel.Name)))
+		}
+		field := st.Field(i)
+		name := c.ident(field.Name())
+		if named, ok := t.(*types.Named); ok && c.pkg != nil && named.Obj().Pkg() == c.pkg {
+			name = a.accessor(named.Obj().Name(), field.Name())
+		}
+		x = c.list(node, c.form(node, "dot"), x, c.sym(node.Sel, name))
+		t = field.Type()
+	}
+	return x
+}
+
+func (c *Compiler) emitSendStmt(node *ast.SendStmt) Node {
+	return c.list(node, c.form(node, "<-!"), c.emitExpr(node.Chan), c.emitExpr(node.Value))
+}
+
+func (c *Compiler) emitSliceExpr(node *ast.SliceExpr) Node {
+	var low, high Node = c.literal(&Bool{Position: c.pos(node)}), c.literal(&Bool{Position: c.pos(node)})
+	if node.Low != nil {
+		low = c.emitExpr(node.Low)
+	}
+	if node.High != nil {
+		high = c.emitExpr(node.High)
+	}
+	return c.list(node, c.form(node, "index"), c.emitExpr(node.X), low, high)
+}
+
+// Spec
+
+func (c *Compiler) emitStarExpr(node *ast.StarExpr) Node {
+	// the dereference *x, as in *ps[0], unless x is known to be a type
+	if c.isType(node.X) {
+		return c.list(node, c.form(node, "ptr"), c.emitType(node.X))
+	}
+	return c.list(node, c.form(node, "ptr"), c.emitExpr(node.X))
+}
+
+func (c *Compiler) emitStmt(node ast.Stmt) Node {
+	switch a := node.(type) {
+
+	// Stmt
+	case *ast.AssignStmt:     return c.emitAssignStmt(a)
+	case *ast.BlockStmt:      return c.list(a, append([]Node{c.form(a, "begin")}, c.emitBlockStmt(a)...)...)
+	case *ast.BranchStmt:     return c.emitBranchStmt(a)
+	case *ast.DeclStmt:       decl := c.emitDecl(a.Decl); return decl[len(decl)-1]
+	case *ast.DeferStmt:      return c.emitDeferStmt(a)
+	case *ast.EmptyStmt:      return c.emitEmptyStmt(a)
+	case *ast.ExprStmt:       return c.emitExpr(a.X)
+	case *ast.ForStmt:        return c.emitForStmt(a)
+	case *ast.GoStmt:         return c.emitGoStmt(a)
+	case *ast.IfStmt:         return c.emitIfStmt(a)
+	case *ast.IncDecStmt:     return c.emitIncDecStmt(a)
+	case *ast.LabeledStmt:    return c.emitLabeledStmt(a)
+	case *ast.RangeStmt:      return c.emitRangeStmt(a)
+	case *ast.ReturnStmt:     return c.emitReturnStmt(a)
+	case *ast.SelectStmt:     return c.emitSelectStmt(a)
+	case *ast.SendStmt:       return c.emitSendStmt(a)
+	case *ast.SwitchStmt:     return c.emitSwitchStmt(a)
+	case *ast.TypeSwitchStmt: return c.emitTypeSwitchStmt(a)
+
+	default:
+		return c.emitUnsupported(node, "statement")
+	}
+}
+
+// emitStmts is emitStmt for a statement in a list: a block is
+// spliced into the enclosing statements, and a declaration keeps
+// its doc comments.
+func (c *Compiler) emitStmts(node ast.Stmt) []Node {
+	switch a := node.(type) {
+	case *ast.BlockStmt:
+		return c.emitBlockStmt(a)
+	case *ast.DeclStmt:
+		return c.emitDecl(a.Decl)
+	case *ast.AssignStmt, *ast.IncDecStmt:
+		// the temporaries of a wrapped assignment, as a block
+		x := c.emitStmt(node)
+		if list, ok := x.(*List); ok && Head(list) == c.backend().Form("begin") {
+			return list.Elts[1:]
+		}
+		return []Node{x}
+	}
+	return []Node{c.emitStmt(node)}
+}
+
+func (c *Compiler) emitStructType(node *ast.StructType) Node {
+	x := c.list(node, c.form(node, "struct"))
+	x.Elts = append(x.Elts, c.emitFieldList(node.Fields)...)
+	return x
+}
+
+func (c *Compiler) emitSwitchStmt(node *ast.SwitchStmt) Node {
+	cond := node.Tag == nil
+	head := "case!"
+	if cond {
+		head = "cond!"
+	}
+	if node.Init != nil {
+		head += "*"
+	}
+	x := c.list(node, c.form(node, head))
+	if node.Init != nil {
+		x.Elts = append(x.Elts, c.emitStmt(node.Init))
+	}
+	if !cond {
+		x.Elts = append(x.Elts, c.emitExpr(node.Tag))
+	}
+	for _, stmt := range node.Body.List {
+		x.Elts = append(x.Elts, c.emitCaseClause(stmt.(*ast.CaseClause), cond))
+	}
+	return x
+}
+
+func (c *Compiler) emitType(node ast.Expr) Node {
+	switch a := node.(type) {
+	case *ast.Ident:
+		return c.sym(a, c.ident(a.Name))
+	case *ast.BinaryExpr:
+		if a.Op == token.OR {
+			return c.emitUnion(a)
+		}
+	case *ast.UnaryExpr:
+		if a.Op == token.TILDE {
+			return c.list(a, c.form(a, "~"), c.emitType(a.X))
+		}
+	case *ast.StarExpr:
+		return c.list(a, c.form(a, "ptr"), c.emitType(a.X))
+	case *ast.IndexExpr:
+		return c.emitInstance(a, a.X, []ast.Expr{a.Index})
+	case *ast.IndexListExpr:
+		return c.emitInstance(a, a.X, a.Indices)
+	}
+	return c.emitExpr(node)
+}
+
+// helper function
+func (c *Compiler) emitTypeParams(name *ast.Ident, params *ast.FieldList) Node {
+	if params == nil {
+		return c.emitIdent(name)
+	}
+	// "(generic %s #(%s %s) ...)", name, param(s), constraint
+	x := c.list(name, c.form(name, "generic"), c.emitIdent(name))
+	x.Elts = append(x.Elts, c.emitFieldList(params)...)
+	return x
+}
+
+func (c *Compiler) emitTypeAssertExpr(node *ast.TypeAssertExpr) Node {
+	var typ Node
+	if node.Type == nil {
+		typ = c.form(node, "type")
+	} else {
+		typ = c.emitType(node.Type)
+	}
+	return c.list(node, c.form(node, "as"), c.emitExpr(node.X), typ)
+}
+
+func (c *Compiler) emitTypeSpec(node *ast.TypeSpec) []Node {
+	return []Node{c.emitTypeParams(node.Name, node.TypeParams), c.emitType(node.Type)}
+}
+
+func (c *Compiler) emitTypeSwitchStmt(node *ast.TypeSwitchStmt) Node {
+	head := "type!"
+	if node.Init != nil {
+		head += "*"
+	}
+	x := c.list(node, c.form(node, head))
+	if node.Init != nil {
+		x.Elts = append(x.Elts, c.emitStmt(node.Init))
+	}
+	x.Elts = append(x.Elts, c.emitStmt(node.Assign))
+	for _, stmt := range node.Body.List {
+		x.Elts = append(x.Elts, c.emitCaseClause(stmt.(*ast.CaseClause), false))
+	}
+	return x
+}
+
+func (c *Compiler) emitUnion(node *ast.BinaryExpr) Node {
+	// "(union %s %s ...)", term(s)
+	var terms []ast.Expr
+	var flatten func(ast.Expr)
+	flatten = func(expr ast.Expr) {
+		if bin, ok := expr.(*ast.BinaryExpr); ok && bin.Op == token.OR {
+			flatten(bin.X)
+			flatten(bin.Y)
+			return
+		}
+		terms = append(terms, expr)
+	}
+	flatten(node)
+	x := c.list(node, c.form(node, "union"))
+	for _, term := range terms {
+		x.Elts = append(x.Elts, c.emitType(term))
+	}
+	return x
+}
+
+func (c *Compiler) emitUnaryExpr(node *ast.UnaryExpr) Node {
+	var x Node = c.list(node, c.form(node, goUnaryOpToSchemeOp(node.Op.String())), c.emitExpr(node.X))
+	if node.Op == token.SUB || node.Op == token.XOR {
+		if name, bits := c.intWrap(node); name != "" && (node.Op == token.SUB || name == "wrap-unsigned") {
+			x = c.wrap(node, name, bits, x)
+		}
+	}
+	return x
+}
+
+// helper function
+func (c *Compiler) emitValueNames(ids []*ast.Ident) Node {
+	if len(ids) == 1 {
+		return c.sym(ids[0], c.ident(ids[0].Name))
+	}
+	x := c.list(ids[0])
+	for _, id := range ids {
+		x.Elts = append(x.Elts, c.sym(id, c.ident(id.Name)))
+	}
+	return x
+}
+
+func (c *Compiler) emitValueTypedNames(ids []*ast.Ident, t ast.Expr) Node {
+	x := c.vector(ids[0])
+	for _, id := range ids {
+		x.Elts = append(x.Elts, c.sym(id, c.ident(id.Name)))
+	}
+	x.Elts = append(x.Elts, c.emitType(t))
+	return x
+}
+
+func (c *Compiler) emitValueSpec(node *ast.ValueSpec) Node {
+	if node.Type != nil {
+		if node.Values != nil {
+			// "(define-const (= #(%s %s) %s))", name, type, value
+			// "(define-var (= #(%s %s) %s))", name(s), type, value(s)
+			x := c.list(node, c.form(node, "="), c.emitValueTypedNames(node.Names, node.Type))
+			for _, arg := range node.Values {
+				x.Elts = append(x.Elts, c.emitExpr(arg))
+			}
+			return x
+		}
+		// "(define-var #(%s %s))", name(s), type
+		return c.emitValueTypedNames(node.Names, node.Type)
+	} else if node.Values != nil {
+		// "(define-const (= %s %s))", name, value
+		// "(define-var (= %s %s))", name, value
+		// "(define-var (= (%s) %s))", name(s), value(s)
+		x := c.list(node, c.form(node, "="), c.emitValueNames(node.Names))
+		for _, arg := range node.Values {
+			x.Elts = append(x.Elts, c.emitExpr(arg))
+		}
+		return x
+	}
+	// "(define-const %s)", name
+	return c.emitValueNames(node.Names)
+}
@@ -0,0 +1,154 @@
+package gos
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzCompile translates arbitrary Go source and checks that the
+// output is well-formed Gos: balanced, free of the old <expr:...>
+// and <stmt:...> placeholders, and readable by Read. The forms
+// read are then lowered, which must not panic.
+func FuzzCompile(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.go"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, name := range files {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(src))
+	}
+	f.Add("package p\nvar r = '\\u00e9'")
+	f.Add("package p\nvar s = \"\\u00e9\\x41;\" + `\\d`")
+	f.Add("package p\nfunc f()")
+	f.Fuzz(func(t *testing.T, src string) {
+		if _, err := parser.ParseFile(token.NewFileSet(), "fuzz.go", src, 0); err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		c := NewCompiler()
+		c.Filename = "fuzz.go"
+		c.Compile(strings.NewReader(src), &buf)
+		out := buf.Bytes()
+		if !balanced(out) {
+			t.Fatalf("unbalanced output:\n%s", out)
+		}
+		for _, bad := range []string{"<expr:", "<stmt:"} {
+			if bytes.Contains(out, []byte(bad)) {
+				t.Fatalf("output contains %s:\n%s", bad, out)
+			}
+		}
+		forms, err := Read("fuzz.gos", out, ReadComments)
+		if err != nil {
+			t.Fatalf("%v\n%s", err, out)
+		}
+		Lower(forms, LowerR7RS)
+	})
+}
+
+// FuzzMangle checks that MangleName undoes UnmangleName for Go
+// identifiers, that the symbol reads back unchanged, and that
+// MangleName always returns a Go identifier.
+func FuzzMangle(f *testing.F) {
+	for _, name := range []string{"x", "fooZ", "Z", "fooZB", "when", "ptr", "$when", "a-b", "set!", "&int", "é", "1x", "type"} {
+		f.Add(name)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		if id := MangleName(name); !token.IsIdentifier(id) {
+			t.Errorf("MangleName(%q) = %q, not an identifier", name, id)
+		}
+		if !token.IsIdentifier(name) {
+			return
+		}
+		sym := UnmangleName(name)
+		if got := MangleName(sym); got != name {
+			t.Errorf("MangleName(UnmangleName(%q)) = %q", name, got)
+		}
+		forms, err := Read("", []byte(sym), 0)
+		if err != nil || len(forms) != 1 || forms[0].String() != sym {
+			t.Errorf("UnmangleName(%q) = %q reads back as %v, %v", name, sym, forms, err)
+		} else if _, ok := forms[0].(*Symbol); !ok {
+			t.Errorf("UnmangleName(%q) = %q is not read as a symbol", name, sym)
+		}
+	})
+}
+
+// FuzzEscape checks that EscapeName returns a Go identifier that
+// UnescapeName turns back into the name.
+func FuzzEscape(f *testing.F) {
+	for _, name := range []string{"x", "list->vector", "a_b", "_", "set!", "&int", "é", "1x", "type", "$when"} {
+		f.Add(name)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		if name == "" || !utf8.ValidString(name) {
+			return
+		}
+		id := EscapeName(name)
+		if !token.IsIdentifier(id) {
+			t.Errorf("EscapeName(%q) = %q, not an identifier", name, id)
+		}
+		if got, ok := UnescapeName(id); got != name || !ok {
+			t.Errorf("UnescapeName(EscapeName(%q)) = %q, %v", name, got, ok)
+		}
+	})
+}
+
+func TestEscapeName(t *testing.T) {
+	tests := []struct{ name, id string }{
+		{"x", "x"},
+		{"list->vector", "list_2d__3e_vector"},
+		{"a_b", "a_5f_b"},
+		{"1+", "_31__2b_"},
+		{"type", "_74_ype"},
+	}
+	for _, test := range tests {
+		if got := EscapeName(test.name); got != test.id {
+			t.Errorf("EscapeName(%q) = %q, want %q", test.name, got, test.id)
+		}
+	}
+	for _, id := range []string{"a_5f", "a_zz_b", "_61_", "a_5F_b"} {
+		if name, ok := UnescapeName(id); ok {
+			t.Errorf("UnescapeName(%q) = %q, want false", id, name)
+		}
+	}
+}
+
+// balanced reports whether the parentheses in src match, ignoring
+// those in strings, characters and comments.
+func balanced(src []byte) bool {
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '"':
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+		case ';':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case '#':
+			if i+2 < len(src) && src[i+1] == '\\' {
+				i += 2
+			}
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
@@ -0,0 +1,232 @@
+package gos
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// TestGolden translates each testdata/*.go file and compares the
+// pretty-printed result with the matching .gos file. Files named
+// *_typed.go are type-checked first, and those named *_wrap.go are
+// translated with WrapIntegers.
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		name := name
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			got := translateGolden(t, name)
+			golden := strings.TrimSuffix(name, ".go") + ".gos"
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from %s:\n--- got ---\n%s\n--- want ---\n%s",
+					name, golden, got, want)
+			}
+		})
+	}
+}
+
+func translateGolden(t *testing.T, name string) []byte {
+	rd, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	c := NewCompiler()
+	c.Filename = name
+	c.TypeCheck = strings.HasSuffix(name, "_typed.go")
+	c.WrapIntegers = strings.HasSuffix(name, "_wrap.go")
+	var raw, out bytes.Buffer
+	if err := c.Compile(rd, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := Format(&out, raw.Bytes(), DefaultStyle); err != nil {
+		t.Fatalf("%v\n%s", err, raw.Bytes())
+	}
+	return out.Bytes()
+}
+
+// TestBuild checks that the tree built from each golden reads
+// back the same once written, and that its forms carry the
+// positions of the Go source they came from.
+func TestBuild(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		rd, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewCompiler()
+		c.Filename = name
+		c.TypeCheck = strings.HasSuffix(name, "_typed.go")
+		c.WrapIntegers = strings.HasSuffix(name, "_wrap.go")
+		forms, err := c.Build(rd)
+		rd.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := Write(&buf, forms); err != nil {
+			t.Fatal(err)
+		}
+		back, err := Read(name, buf.Bytes(), ReadComments)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", name, err, buf.Bytes())
+		}
+		if got, want := joinLines(back), joinLines(forms); got != want {
+			t.Errorf("%s reads back as:\n%s\nwant:\n%s", name, got, want)
+		}
+		walk(forms, func(x *List) bool {
+			if pos := x.Pos(); pos.Filename != name || pos.Line == 0 {
+				t.Errorf("%s: %s has position %v", name, x, pos)
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// TestUnsupported checks that nodes with no Gos form are reported
+// with their position and replaced by a placeholder.
+func TestUnsupported(t *testing.T) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("bad.go", -1, 100)
+	file.SetLines([]int{0, 10})
+	var buf bytes.Buffer
+	err := TranslateExpr(fset, &ast.BadExpr{From: file.Pos(12), To: file.Pos(14)}, &buf, Options{})
+	if got, want := buf.String(), "%unsupported"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if got, want := fmt.Sprint(err), "bad.go:2:3: unsupported expression *ast.BadExpr"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
+
+// TestWrappedAssign checks that the operands of a wrapped x op= y
+// that have side effects are evaluated once, into temporaries.
+func TestWrappedAssign(t *testing.T) {
+	src := `package p
+
+func f(m map[string]int, key func() string, ps []struct{ n uint8 }, i func() int) {
+	m[key()]++
+	ps[i()].n -= 1
+}
+`
+	c := NewCompiler()
+	c.TypeCheck = true
+	c.WrapIntegers = true
+	var out bytes.Buffer
+	if err := c.Compile(strings.NewReader(src), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"(:= %t0 (key)) (= ((index m %t0)) (wrap-signed 64 (+ (index m %t0) 1)))",
+		"(:= %t1 (i)) (= (dot (index ps %t1) n) (wrap-unsigned 8 (- (dot (index ps %t1) n) 1)))",
+	} {
+		if got := strings.Join(strings.Fields(out.String()), " "); !strings.Contains(got, want) {
+			t.Errorf("output lacks %s:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestWrappedAssignInfo checks that the temporaries of wrapped
+// assignments leave the Info a caller supplies as it was.
+func TestWrappedAssignInfo(t *testing.T) {
+	src := `package p
+
+func f(m map[string]int, key func() string, ps []struct{ n uint8 }, i func() int) {
+	m[key()]++
+	ps[i()].n -= 1
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	if _, err := new(types.Config).Check("p", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+	ntypes, nsels := len(info.Types), len(info.Selections)
+	if _, err := BuildFile(fset, file, Options{Info: info, WrapIntegers: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Types) != ntypes || len(info.Selections) != nsels {
+		t.Errorf("Info grew from %d types and %d selections to %d and %d",
+			ntypes, nsels, len(info.Types), len(info.Selections))
+	}
+}
+
+// importerFunc is a types.Importer for packages made by a test.
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// TestImportNames checks that with type information the name of an
+// imported package is the one it declares, even where no qualifier
+// would suggest it.
+func TestImportNames(t *testing.T) {
+	src := `package p
+
+import "example.com/lib"
+
+var _ = foo.X
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types:     make(map[ast.Expr]types.TypeAndValue),
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+	}
+	conf := types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+		pkg := types.NewPackage(path, "foo")
+		pkg.Scope().Insert(types.NewVar(token.NoPos, pkg, "X", types.Typ[types.Int]))
+		pkg.MarkComplete()
+		return pkg, nil
+	})}
+	if _, err := conf.Check("p", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+	forms, err := BuildFile(fset, file, Options{Info: info})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := joinNodes(forms), `(import (as foo "example.com/lib"))`; !strings.Contains(got, want) {
+		t.Errorf("got %s, want it to contain %s", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package gos
+
+// Guile is a Backend that writes each package as a Guile module
+// named (go elem...) after its import path, so that it is found
+// on %load-path as go/elem....scm. The package is lowered as for
+// R7RS. The module exports the names that Go exports, and uses
+// SRFI 11, the module of each imported package, and (go builtin),
+// a runtime as for R7RS that replaces the core bindings it
+// redefines, such as append.
+var Guile Backend = guileBackend{}
+
+type guileBackend struct{ gosBackend }
+
+func (guileBackend) Name() string { return "guile" }
+func (guileBackend) Ext() string  { return ".scm" }
+
+func (guileBackend) Package(pkg *List, importPath string) ([]Node, error) {
+	lib, err := newLibrary(pkg, importPath, LowerR7RS)
+	if err != nil {
+		return nil, err
+	}
+	module := &List{Position: pkg.Position, Elts: []Node{
+		symbol("define-module"),
+		libraryName(pkg.Position, lib.path),
+		symbol("#:use-module"), form("srfi", symbol("srfi-11")),
+		symbol("#:use-module"), libraryName(pkg.Position, "builtin"),
+	}}
+	for _, spec := range lib.imports {
+		module.Elts = append(module.Elts, symbol("#:use-module"), guileInterface(spec))
+	}
+	if names := exports(lib.body); len(names) > 0 {
+		module.Elts = append(module.Elts, symbol("#:export"), &List{Elts: names})
+	}
+	return append([]Node{module}, composites(lib.untyped())...), nil
+}
+
+// guileInterface returns the interface spec for spec: the module
+// of the package renamed with the prefix Gos refers to it by, as
+// ((go fmt) #:prefix fmt.), unrenamed for a dot import, and with
+// nothing selected for a blank one.
+func guileInterface(spec importSpec) Node {
+	name := libraryName(spec.pos, spec.path)
+	switch spec.alias {
+	case ".":
+		return name
+	case "_":
+		return &List{Elts: []Node{name, symbol("#:select"), &List{}}}
+	}
+	return &List{Elts: []Node{name, symbol("#:prefix"), symbol(spec.prefix() + ".")}}
+}
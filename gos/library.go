@@ -0,0 +1,509 @@
+package gos
+
+import (
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// A Packager is a Backend that also lays out each translated
+// package, for example as a library of the target dialect.
+type Packager interface {
+	Backend
+
+	// Package returns the forms to write in place of pkg, the
+	// (package name decls...) form translated from the Go package
+	// with the given import path.
+	Package(pkg *List, importPath string) ([]Node, error)
+
+	// Ext returns the extension of the files the backend writes,
+	// such as ".sld".
+	Ext() string
+}
+
+// library is a package split up the way the library forms of the
+// Scheme backends want it.
+type library struct {
+	name    string       // the Go package name
+	path    string       // the import path, or name if unknown
+	imports []importSpec // in the order they were declared
+	body    []Node       // the lowered declarations, without imports
+}
+
+// typeSpec is one name and type of a (type ...) form.
+type typeSpec struct {
+	name string // without its type parameters
+	typ  Node
+}
+
+// importSpec is one spec of an (import ...) form.
+type importSpec struct {
+	pos   token.Position
+	path  string
+	alias string // "" for none, "." for a dot import, "_" for a blank one
+}
+
+// newLibrary lowers pkg to level and splits it into a library.
+// Unlike Lower, it keeps the (type ...) forms in the body, for the
+// backends whose dialect can define types.
+func newLibrary(pkg *List, importPath string, level Level) (*library, error) {
+	lib := &library{path: importPath}
+	if len(pkg.Elts) > 1 {
+		lib.name = pkg.Elts[1].String()
+	}
+	if lib.path == "" {
+		lib.path = lib.name
+	}
+	var decls []Node
+	if len(pkg.Elts) > 2 {
+		for _, x := range pkg.Elts[2:] {
+			if Head(x) != "import" {
+				decls = append(decls, x)
+				continue
+			}
+			for _, spec := range x.(*List).Elts[1:] {
+				lib.addImport(parseImportSpec(spec))
+			}
+		}
+	}
+	prefixes := make(map[string]bool)
+	for _, spec := range lib.imports {
+		prefixes[spec.prefix()] = true
+	}
+	for i, x := range decls {
+		decls[i] = selectors(x, prefixes)
+	}
+	l := &lowerer{level: level, prefixes: prefixes}
+	for _, x := range decls {
+		if Head(x) == "type" || level == LowerNone {
+			lib.body = append(lib.body, x)
+			continue
+		}
+		lib.body = append(lib.body, l.decl(x)...)
+	}
+	return lib, l.diags.Err()
+}
+
+// untyped returns the body of lib without its type forms.
+func (lib *library) untyped() []Node {
+	var out []Node
+	for _, x := range lib.body {
+		if Head(x) != "type" {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// typeSpecs returns the specs of the (type name T ...) form x.
+func typeSpecs(x *List) []typeSpec {
+	var out []typeSpec
+	var elts []Node
+	for _, elt := range x.Elts[1:] {
+		if _, ok := elt.(*Comment); !ok {
+			elts = append(elts, elt)
+		}
+	}
+	for i := 0; i+1 < len(elts); i += 2 {
+		name := elts[i]
+		if Head(name) == "generic" && len(name.(*List).Elts) > 1 {
+			name = name.(*List).Elts[1]
+		}
+		out = append(out, typeSpec{name: name.String(), typ: elts[i+1]})
+	}
+	return out
+}
+
+// fieldNames returns the names of the fields of the (struct ...)
+// type x, with each embedded field named after its type, as in Go.
+func fieldNames(x *List) []string {
+	var out []string
+	for _, field := range x.Elts[1:] {
+		switch field := field.(type) {
+		case *Comment:
+		case *Vector:
+			for _, name := range field.Elts[:len(field.Elts)-1] {
+				out = append(out, name.String())
+			}
+		default:
+			out = append(out, embeddedName(field))
+		}
+	}
+	return out
+}
+
+// fieldTypes returns the types of the fields of the (struct ...)
+// type x, in the order of fieldNames.
+func fieldTypes(x *List) []Node {
+	var out []Node
+	for _, field := range x.Elts[1:] {
+		switch field := field.(type) {
+		case *Comment:
+		case *Vector:
+			for range field.Elts[:len(field.Elts)-1] {
+				out = append(out, field.Elts[len(field.Elts)-1])
+			}
+		default:
+			out = append(out, field)
+		}
+	}
+	return out
+}
+
+// embeddedName returns the name of the field that embeds the type
+// x: T for T, *T, pkg.T and T[args].
+func embeddedName(x Node) string {
+	for {
+		list, ok := x.(*List)
+		if !ok || len(list.Elts) < 2 {
+			break
+		}
+		if Head(list) == "ptr" {
+			x = list.Elts[1]
+		} else {
+			x = list.Elts[0]
+		}
+	}
+	name := x.String()
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// selectors rewrites each symbol x.f in x into (dot x f), unless
+// x is the name of an imported package, so that without type
+// information field selections are not taken for variables.
+func selectors(x Node, prefixes map[string]bool) Node {
+	switch x := x.(type) {
+	case *List:
+		elts := make([]Node, len(x.Elts))
+		for i, elt := range x.Elts {
+			elts[i] = selectors(elt, prefixes)
+		}
+		return &List{Position: x.Position, Elts: elts}
+	case *Vector:
+		elts := make([]Node, len(x.Elts))
+		for i, elt := range x.Elts {
+			elts[i] = selectors(elt, prefixes)
+		}
+		return &Vector{Position: x.Position, Elts: elts}
+	case *Symbol:
+		i := strings.IndexByte(x.Name, '.')
+		if i <= 0 || i == len(x.Name)-1 || strings.Count(x.Name, ".") > 1 || prefixes[x.Name[:i]] {
+			return x
+		}
+		return &List{Position: x.Position, Elts: []Node{
+			&Symbol{Position: x.Position, Name: "dot"},
+			&Symbol{Position: x.Position, Name: x.Name[:i]},
+			&Symbol{Position: x.Position, Name: x.Name[i+1:]},
+		}}
+	}
+	return x
+}
+
+// addImport adds spec to the imports of lib, unless another file
+// of the package already imported it the same way.
+func (lib *library) addImport(spec importSpec) {
+	for _, have := range lib.imports {
+		if have.path == spec.path && have.alias == spec.alias {
+			return
+		}
+	}
+	lib.imports = append(lib.imports, spec)
+}
+
+// parseImportSpec parses "path", (as name "path") or (dot "path").
+func parseImportSpec(x Node) importSpec {
+	spec := importSpec{pos: x.Pos()}
+	if list, ok := x.(*List); ok && len(list.Elts) > 0 {
+		switch Head(list) {
+		case "as":
+			if len(list.Elts) == 3 {
+				spec.alias = list.Elts[1].String()
+			}
+		case "dot":
+			spec.alias = "."
+		}
+		x = list.Elts[len(list.Elts)-1]
+	}
+	if s, ok := x.(*String); ok {
+		spec.path = s.Value
+	}
+	return spec
+}
+
+// prefix returns the name that Gos refers to the imported package
+// by, as in fmt.Println: its alias, or else the name packageName
+// guesses from its path. The compiler writes the alias of each
+// package whose name is not that guess.
+func (spec importSpec) prefix() string {
+	if spec.alias != "" {
+		return spec.alias
+	}
+	return packageName(spec.path)
+}
+
+// packageName returns the name a package imported from path is
+// expected to have: the last element of path that is not a major
+// version such as v2.
+func packageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && isDigits(name[1:]) {
+		name = elems[len(elems)-2]
+	}
+	return name
+}
+
+// helper function
+func isDigits(s string) bool {
+	for _, ch := range s {
+		if ch < '0' || '9' < ch {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// libraryElems returns the elements of importPath that name a
+// library, leaving out the empty, . and .. elements of a path that
+// is a directory instead.
+func libraryElems(importPath string) []string {
+	var elems []string
+	for _, elem := range strings.Split(importPath, "/") {
+		if elem != "" && elem != "." && elem != ".." {
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
+
+// LibraryFile returns the file, relative to a directory on the
+// load path of the target, that holds the library p writes for
+// the package with the given import path: go/elem.../last.ext, as
+// the library's name (go elem... last) is looked up, unless p
+// finds its libraries otherwise.
+func LibraryFile(p Packager, importPath string) string {
+	if f, ok := p.(libraryFiler); ok {
+		return f.libraryFile(importPath)
+	}
+	return libraryPath(importPath) + p.Ext()
+}
+
+// libraryFiler is implemented by the Packagers whose targets look
+// up a library's file other than by its (go elem...) name.
+type libraryFiler interface {
+	libraryFile(importPath string) string
+}
+
+// typedPackager is implemented by the Packagers that write a
+// package the compiler type-checked differently, as it names the
+// field selections of fieldAccessor backends.
+type typedPackager interface {
+	typedPackage(pkg *List, importPath string) ([]Node, error)
+}
+
+// fieldAccessor is implemented by the backends that give each field
+// of a struct an accessor. Given type information, the compiler
+// writes a selection of the field f of a struct type T the package
+// declares as (dot x accessor), for the backend to call.
+type fieldAccessor interface {
+	accessor(typeName, field string) string
+}
+
+// libraryPath returns go/elem... for importPath.
+func libraryPath(importPath string) string {
+	return path.Join(append([]string{"go"}, libraryElems(importPath)...)...)
+}
+
+// libraryName returns the library name (go elem...) for the Go
+// import path importPath. Elements that are all digits become
+// numbers, as library names require.
+func libraryName(pos token.Position, importPath string) *List {
+	name := &List{Position: pos, Elts: []Node{&Symbol{Position: pos, Name: "go"}}}
+	for _, elem := range libraryElems(importPath) {
+		if isDigits(elem) {
+			n, _ := strconv.Atoi(elem)
+			name.Elts = append(name.Elts, &Number{Position: pos, Lit: strconv.Itoa(n)})
+			continue
+		}
+		name.Elts = append(name.Elts, &Symbol{Position: pos, Name: elem})
+	}
+	return name
+}
+
+// exports returns the names that body defines at top level and
+// that Go exports: those whose Go identifier, or for a method
+// T.M the name of M, begins with an upper-case letter.
+func exports(body []Node) []Node {
+	var out []Node
+	for _, x := range body {
+		for _, name := range defined(x) {
+			if sym, ok := name.(*Symbol); ok && isExported(sym.Name) {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
+// defined returns the names a (define ...) or (define-values ...)
+// form defines.
+func defined(x Node) []Node {
+	list, ok := x.(*List)
+	if !ok || len(list.Elts) < 2 {
+		return nil
+	}
+	switch Head(list) {
+	case "define":
+		if sig, ok := list.Elts[1].(*List); ok && len(sig.Elts) > 0 {
+			return sig.Elts[:1]
+		}
+		return list.Elts[1:2]
+	case "define-values":
+		if formals, ok := list.Elts[1].(*List); ok {
+			var out []Node
+			for _, name := range formals.Elts {
+				if !isSymbol(name, ".") {
+					out = append(out, name)
+				}
+			}
+			return out
+		}
+		return list.Elts[1:2]
+	}
+	return nil
+}
+
+// isExported reports whether Go exports the Gos name, or for a
+// method T.M, the method M.
+func isExported(name string) bool {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return token.IsExported(MangleName(name))
+}
+
+// composites rewrites the vectors left in body into forms that a
+// runtime can define: a composite literal #(T elts...) becomes
+// (composite T elts...), and a field #(names... T) of a struct,
+// interface or function type becomes (field names... T). Scheme
+// would otherwise read them as constant vectors.
+func composites(body []Node) []Node {
+	out := make([]Node, len(body))
+	for i, x := range body {
+		out[i] = composite(x, false)
+	}
+	return out
+}
+
+// helper function
+func composite(x Node, inType bool) Node {
+	switch x := x.(type) {
+	case *List:
+		switch Head(x) {
+		case "struct", "interface", "func", "func...", "generic":
+			inType = true
+		}
+		elts := make([]Node, len(x.Elts))
+		for i, elt := range x.Elts {
+			elts[i] = composite(elt, inType)
+		}
+		return &List{Position: x.Position, Elts: elts}
+	case *Vector:
+		head := "composite"
+		if inType {
+			head = "field"
+		}
+		list := &List{Position: x.Position, Elts: []Node{&Symbol{Position: x.Position, Name: head}}}
+		for _, elt := range x.Elts {
+			list.Elts = append(list.Elts, composite(elt, inType))
+		}
+		return list
+	}
+	return x
+}
+
+// constructors rewrites each (composite T elts...) in x whose T is
+// a struct type of types, the types the package declares, into a
+// call of the constructor of T, which takes every field in order:
+// the keyed elements (: f v) are put in place, and the fields they
+// leave out set to their zero value.
+func constructors(x Node, types map[string]Node) Node {
+	list, ok := x.(*List)
+	if !ok {
+		return x
+	}
+	elts := make([]Node, len(list.Elts))
+	for i, elt := range list.Elts {
+		elts[i] = constructors(elt, types)
+	}
+	list = &List{Position: list.Position, Elts: elts}
+	if Head(list) != "composite" || len(elts) < 2 {
+		return list
+	}
+	typ, ok := types[elts[1].String()].(*List)
+	if !ok || Head(typ) != "struct" {
+		return list
+	}
+	names := fieldNames(typ)
+	args := make([]Node, len(names))
+	if len(elts) > 2 && Head(elts[2]) != ":" {
+		if len(elts)-2 != len(names) {
+			return list
+		}
+		copy(args, elts[2:])
+	} else {
+		for _, elt := range elts[2:] {
+			kv, ok := elt.(*List)
+			if !ok || Head(kv) != ":" || len(kv.Elts) != 3 {
+				return list
+			}
+			i := indexOf(names, kv.Elts[1].String())
+			if i < 0 {
+				return list
+			}
+			args[i] = kv.Elts[2]
+		}
+		fields := fieldTypes(typ)
+		for i, arg := range args {
+			if arg == nil {
+				args[i] = typeZero(fields[i], types, 0)
+			}
+		}
+	}
+	return &List{Position: list.Position, Elts: append([]Node{elts[1]}, args...)}
+}
+
+// typeZero returns the zero value of typ, looking through the
+// types the package declares: a struct type's is a call of its
+// constructor.
+func typeZero(typ Node, types map[string]Node, depth int) Node {
+	under, ok := types[typ.String()]
+	if !ok || depth > len(types) {
+		return zero(typ)
+	}
+	if Head(under) != "struct" {
+		if x := typeZero(under, types, depth+1); Head(x) != "zero" {
+			return x
+		}
+		return zero(typ)
+	}
+	x := &List{Elts: []Node{symbol(typ.String())}}
+	for _, field := range fieldTypes(under.(*List)) {
+		x.Elts = append(x.Elts, typeZero(field, types, depth+1))
+	}
+	return x
+}
+
+// helper function
+func indexOf(names []string, name string) int {
+	for i, have := range names {
+		if have == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,139 @@
+package gos
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"testing"
+)
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		kind token.Token
+		lit  string
+		want string
+	}{
+		{token.INT, "42", "42"},
+		{token.INT, "0", "0"},
+		{token.INT, "1_000_000", "1000000"},
+		{token.INT, "0x1F", "#x1F"},
+		{token.INT, "0X_1f", "#x1f"},
+		{token.INT, "0o17", "#o17"},
+		{token.INT, "0O17", "#o17"},
+		{token.INT, "017", "#o17"},
+		{token.INT, "0b1010", "#b1010"},
+		{token.INT, "0B_1010", "#b1010"},
+		{token.FLOAT, "3.5", "3.5"},
+		{token.FLOAT, "1.", "1."},
+		{token.FLOAT, ".25", ".25"},
+		{token.FLOAT, "1E6", "1e6"},
+		{token.FLOAT, "1_0.2_5e-3", "10.25e-3"},
+		{token.FLOAT, "017.5", "017.5"},
+		{token.FLOAT, "0x1p-2", "#i1/4"},
+		{token.FLOAT, "0x1.8p1", "#i3"},
+		{token.FLOAT, "0X.8P+0", "#i1/2"},
+		{token.IMAG, "3i", "+3i"},
+		{token.IMAG, "017i", "+017i"},
+		{token.IMAG, "1.5i", "+1.5i"},
+		{token.IMAG, "1e3i", "+1e3i"},
+		{token.IMAG, "0x1Fi", "#x+1Fi"},
+		{token.IMAG, "0b101i", "#b+101i"},
+		{token.IMAG, "0x1p-2i", "#i+1/4i"},
+	}
+	for _, test := range tests {
+		got := goNumberToSchemeNumber(&ast.BasicLit{Kind: test.kind, Value: test.lit}, token.Position{}).String()
+		if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.lit, got, test.want)
+		}
+		if !isNumber(got) {
+			t.Errorf("%s: %s is not read as a number", test.lit, got)
+		}
+		kind, lit, err := schemeNumberToGoNumber(got)
+		if err != nil || kind != test.kind {
+			t.Errorf("%s: reversed to %s %s, %v", got, kind, lit, err)
+			continue
+		}
+		if back := goNumberToSchemeNumber(&ast.BasicLit{Kind: kind, Value: lit}, token.Position{}).String(); back != got {
+			t.Errorf("%s: reversed to %s, which translates to %s", got, lit, back)
+		}
+	}
+}
+
+func TestChars(t *testing.T) {
+	tests := []struct {
+		lit  string
+		want string
+	}{
+		{`'A'`, `#\A`},
+		{`'\x41'`, `#\A`},
+		{`'\101'`, `#\A`},
+		{`'é'`, `#\é`},
+		{`'\U0001F600'`, `#\😀`},
+		{`'\''`, `#\'`},
+		{`'\\'`, `#\\`},
+		{`'('`, `#\(`},
+		{`' '`, `#\space`},
+		{`'\n'`, `#\newline`},
+		{`'\a'`, `#\alarm`},
+		{`'\f'`, `#\xc`},
+		{`'\v'`, `#\xb`},
+		{`'\x00'`, `#\null`},
+		{`'\x7f'`, `#\delete`},
+		{`'́'`, `#\x301`},
+		{`'​'`, `#\x200b`},
+		{`'\xff'`, `#\ÿ`},
+		{`'\377'`, `#\ÿ`},
+		{`'\x80'`, `#\x80`},
+	}
+	for _, test := range tests {
+		got := goCharToSchemeChar(&ast.BasicLit{Kind: token.CHAR, Value: test.lit}, token.Position{}).String()
+		if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.lit, got, test.want)
+		}
+		want, _, _, _ := strconv.UnquoteChar(test.lit[1:len(test.lit)-1], '\'')
+		forms, err := Read("", []byte(got), 0)
+		if err != nil || len(forms) != 1 || forms[0].(*Char).Value != want {
+			t.Errorf("%s: %s reads back as %v, %v", test.lit, got, forms, err)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		lit  string
+		want string
+	}{
+		{`"hello"`, `"hello"`},
+		{`"a\tb\n"`, `"a\tb\n"`},
+		{`"\x41\101é\U0001F600"`, `"AAé😀"`},
+		{`"say \"hi\""`, `"say \"hi\""`},
+		{`"back\\slash"`, `"back\\slash"`},
+		{`"\a\b\f\v\x00"`, `"\a\b\xc;\xb;\x0;"`},
+		{"`raw \\d \"q\"`", `"raw \\d \"q\""`},
+		{"`two\nlines`", `"two\nlines"`},
+		{`"\xff\xfe"`, `(convert &imm-string #u8(255 254))`},
+		{`"ok\x80"`, `(convert &imm-string #u8(111 107 128))`},
+	}
+	for _, test := range tests {
+		got := goStringToSchemeString(&ast.BasicLit{Kind: token.STRING, Value: test.lit}, token.Position{}).String()
+		if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.lit, got, test.want)
+		}
+		want, _ := strconv.Unquote(test.lit)
+		forms, err := Read("", []byte(got), 0)
+		if err != nil || len(forms) != 1 {
+			t.Errorf("%s: %s reads back as %v, %v", test.lit, got, forms, err)
+			continue
+		}
+		var value string
+		switch x := forms[0].(type) {
+		case *String:
+			value = x.Value
+		case *List:
+			value = string(x.Elts[2].(*Bytevector).Value)
+		}
+		if value != want {
+			t.Errorf("%s: %s reads back as %q", test.lit, got, value)
+		}
+	}
+}
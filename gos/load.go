@@ -0,0 +1,268 @@
+package gos
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Package is a Go package whose non-test files are translated
+// together into a single (package ...) unit.
+type Package struct {
+	Dir        string
+	ImportPath string
+	Name       string
+	Files      []*ast.File
+
+	// Types and Info are filled in when the package is type-checked,
+	// and Errors holds whatever the parser and type checker reported.
+	Types  *types.Package
+	Info   *types.Info
+	Errors []error
+
+	checking bool
+}
+
+// Loader parses and type-checks packages from source. Packages
+// inside the module being loaded are imported through the Loader
+// itself, so references between them resolve to the same objects;
+// everything else is imported from source with go/importer.
+type Loader struct {
+	Fset *token.FileSet
+
+	// TypeCheck runs go/types over each loaded package.
+	TypeCheck bool
+
+	modPath string
+	modDir  string
+	pkgs    map[string]*Package
+	std     types.ImporterFrom
+}
+
+func NewLoader() *Loader {
+	fset := token.NewFileSet()
+	return &Loader{
+		Fset: fset,
+		pkgs: make(map[string]*Package),
+		std:  importer.ForCompiler(fset, "source", nil).(types.ImporterFrom),
+	}
+}
+
+// PatternRoot returns the directory a package pattern is rooted at.
+func PatternRoot(pattern string) string {
+	root := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+	if root == "" {
+		return "."
+	}
+	return root
+}
+
+// IsPackagePattern reports whether name should be loaded as a
+// package rather than compiled as a single file: either it is a
+// directory, or it ends in "/..." and names a directory tree.
+func IsPackagePattern(name string) bool {
+	if name == "..." || strings.HasSuffix(name, "/...") {
+		return true
+	}
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
+
+// Load returns the packages matched by patterns, in a stable order.
+// A pattern is a directory, or a directory followed by "/..." to
+// include every package beneath it.
+func (l *Loader) Load(patterns ...string) ([]*Package, error) {
+	var dirs []string
+	for _, pattern := range patterns {
+		if pattern == "..." || strings.HasSuffix(pattern, "/...") {
+			found, err := packageDirs(PatternRoot(pattern))
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, found...)
+		} else {
+			dirs = append(dirs, pattern)
+		}
+	}
+
+	var pkgs []*Package
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		pkg, err := l.loadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if seen[pkg.Dir] {
+			continue
+		}
+		seen[pkg.Dir] = true
+		if l.TypeCheck {
+			l.check(pkg)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// packageDirs walks root and returns every directory that holds
+// Go files, skipping testdata, vendor and hidden directories.
+func packageDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		base := filepath.Base(name)
+		if name != root && (base == "testdata" || base == "vendor" ||
+			strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) {
+			return filepath.SkipDir
+		}
+		if _, err := build.ImportDir(name, 0); err == nil {
+			dirs = append(dirs, name)
+		}
+		return nil
+	})
+	sort.Strings(dirs)
+	return dirs, err
+}
+
+func (l *Loader) loadDir(dir string) (*Package, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if l.modDir == "" {
+		l.findModule(dir)
+	}
+	for _, pkg := range l.pkgs {
+		if pkg.Dir == dir {
+			return pkg, nil
+		}
+	}
+
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &Package{
+		Dir:        dir,
+		ImportPath: l.importPath(dir, bp),
+		Name:       bp.Name,
+	}
+	for _, name := range bp.GoFiles {
+		file, err := parser.ParseFile(l.Fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			pkg.Errors = append(pkg.Errors, err)
+		}
+		if file != nil {
+			pkg.Files = append(pkg.Files, file)
+		}
+	}
+	l.pkgs[pkg.ImportPath] = pkg
+	return pkg, nil
+}
+
+// findModule looks for the go.mod governing dir and records the
+// module path, so that import paths within it can be resolved.
+func (l *Loader) findModule(dir string) {
+	for d := dir; ; d = filepath.Dir(d) {
+		if f, err := os.Open(filepath.Join(d, "go.mod")); err == nil {
+			sc := bufio.NewScanner(f)
+			for sc.Scan() {
+				fields := strings.Fields(sc.Text())
+				if len(fields) == 2 && fields[0] == "module" {
+					l.modPath = strings.Trim(fields[1], "\"`")
+					l.modDir = d
+					break
+				}
+			}
+			f.Close()
+			return
+		}
+		if filepath.Dir(d) == d {
+			return
+		}
+	}
+}
+
+func (l *Loader) importPath(dir string, bp *build.Package) string {
+	if l.modDir != "" {
+		if rel, err := filepath.Rel(l.modDir, dir); err == nil && !strings.HasPrefix(rel, "..") {
+			return path.Join(l.modPath, filepath.ToSlash(rel))
+		}
+	}
+	if bp.ImportPath != "" && bp.ImportPath != "." {
+		return bp.ImportPath
+	}
+	return filepath.ToSlash(dir)
+}
+
+// inModule reports whether importPath belongs to the module
+// being loaded.
+func (l *Loader) inModule(importPath string) bool {
+	return l.modPath != "" &&
+		(importPath == l.modPath || strings.HasPrefix(importPath, l.modPath+"/"))
+}
+
+func (l *Loader) check(pkg *Package) {
+	if pkg.Types != nil || pkg.checking {
+		return
+	}
+	pkg.checking = true
+	defer func() { pkg.checking = false }()
+	pkg.Info = &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
+	}
+	conf := types.Config{
+		Importer: l,
+		Error: func(err error) {
+			pkg.Errors = append(pkg.Errors, err)
+		},
+	}
+	pkg.Types, _ = conf.Check(pkg.ImportPath, l.Fset, pkg.Files, pkg.Info)
+}
+
+func (l *Loader) Import(importPath string) (*types.Package, error) {
+	return l.ImportFrom(importPath, "", 0)
+}
+
+func (l *Loader) ImportFrom(importPath, dir string, mode types.ImportMode) (*types.Package, error) {
+	if !l.inModule(importPath) {
+		return l.std.ImportFrom(importPath, dir, mode)
+	}
+	pkg, ok := l.pkgs[importPath]
+	if !ok {
+		rel := strings.TrimPrefix(strings.TrimPrefix(importPath, l.modPath), "/")
+		var err error
+		pkg, err = l.loadDir(filepath.Join(l.modDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if pkg.checking {
+		return nil, fmt.Errorf("import cycle through %q", importPath)
+	}
+	l.check(pkg)
+	if pkg.Types == nil {
+		return nil, fmt.Errorf("could not import %q", importPath)
+	}
+	return pkg.Types, nil
+}
@@ -0,0 +1,1400 @@
+package gos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Level says how far Lower rewrites Gos toward plain Scheme.
+type Level int
+
+const (
+	// LowerNone leaves the forms as they are.
+	LowerNone Level = iota
+
+	// LowerCore rewrites statements into core Scheme. Conditionals
+	// become if, cond and case; loops become named lets; break,
+	// continue, return and goto become calls to escape
+	// continuations captured with call/cc; := and local
+	// declarations become let. Top-level declarations, Go
+	// assignments and expressions keep their Gos forms.
+	LowerCore
+
+	// LowerR7RS also rewrites declarations into define and lambda
+	// and assignments into set!, and drops type declarations, so
+	// that of Gos only the package and import forms, which a
+	// backend turns into a library, and the expression forms, which
+	// a runtime defines, are left.
+	LowerR7RS
+)
+
+var levelNames = []string{"none", "core", "r7rs"}
+
+func (level Level) String() string {
+	if 0 <= level && int(level) < len(levelNames) {
+		return levelNames[level]
+	}
+	return "Level(" + strconv.Itoa(int(level)) + ")"
+}
+
+// ParseLevel returns the Level named s, as in the -lower flag.
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if s == name {
+			return Level(i), nil
+		}
+	}
+	return LowerNone, fmt.Errorf("unknown lowering level %q, want one of %s", s, strings.Join(levelNames, ", "))
+}
+
+// Lower rewrites forms, as read by Read, to the given level.
+//
+// What Go does at run time beyond control flow is left to a
+// runtime: lowered code calls (range-for-each proc x) to iterate,
+// (select cases default) with (send-case ch v proc) and
+// (recv-case ch proc) for select, (type-is? x T) for type switches,
+// (go thunk) to start a goroutine and (zero T) for the zero value
+// of a type that has no literal one; under LowerR7RS assignments
+// also call index-set!, dot-set! and ptr-set!, the last taking
+// x.f for the field f of x unless x names an imported package.
+func Lower(forms []Node, level Level) ([]Node, error) {
+	if level == LowerNone {
+		return forms, nil
+	}
+	l := &lowerer{level: level}
+	var out []Node
+	for _, x := range forms {
+		if pkg, ok := x.(*List); ok && Head(pkg) == "package" && len(pkg.Elts) >= 2 {
+			lowered := &List{Position: pkg.Position, Elts: pkg.Elts[:2:2]}
+			l.prefixes = make(map[string]bool)
+			for _, decl := range pkg.Elts[2:] {
+				if Head(decl) == "import" {
+					for _, spec := range decl.(*List).Elts[1:] {
+						l.prefixes[parseImportSpec(spec).prefix()] = true
+					}
+				}
+			}
+			for _, decl := range pkg.Elts[2:] {
+				lowered.Elts = append(lowered.Elts, l.decl(decl)...)
+			}
+			out = append(out, lowered)
+			continue
+		}
+		out = append(out, l.decl(x)...)
+	}
+	return out, l.diags.Err()
+}
+
+type lowerer struct {
+	level    Level
+	diags    Diagnostics
+	prefixes map[string]bool // the names of the imported packages
+	blanks   int             // number of _ bindings renamed so far
+	gotos    map[string]bool // labels of the current function that a goto targets
+	results  []Node          // named results of the current function
+}
+
+func (l *lowerer) errorf(x Node, format string, params ...interface{}) {
+	l.diags = append(l.diags, Diagnostic{x.Pos(), fmt.Sprintf(format, params...)})
+}
+
+// helper function
+func symbol(name string) *Symbol {
+	return &Symbol{Name: name}
+}
+
+// helper function
+func form(head string, elts ...Node) *List {
+	return &List{Elts: append([]Node{symbol(head)}, elts...)}
+}
+
+// unspecified is (if #f #f), for bodies that must not be empty.
+func unspecified() Node {
+	return form("if", &Bool{}, &Bool{})
+}
+
+// nonEmpty returns body, or a body that does nothing if it holds
+// no expression.
+func nonEmpty(body []Node) []Node {
+	for _, x := range body {
+		if _, ok := x.(*Comment); !ok {
+			return body
+		}
+	}
+	return append(body, unspecified())
+}
+
+// begin returns body as one expression.
+func begin(body []Node) Node {
+	if len(body) == 1 {
+		if _, ok := body[0].(*Comment); !ok {
+			return body[0]
+		}
+	}
+	return form("begin", nonEmpty(body)...)
+}
+
+// binding returns the name to bind for x, renaming _ so that
+// several of them may appear in one binding form.
+func (l *lowerer) binding(x Node) Node {
+	if isSymbol(x, "_") {
+		l.blanks++
+		return symbol("%_" + strconv.Itoa(l.blanks))
+	}
+	return x
+}
+
+// names returns the targets of an assignment or declaration: a
+// single symbol, or a list of them.
+func names(x Node) []Node {
+	if list, ok := x.(*List); ok && Head(list) != "dot" {
+		return list.Elts
+	}
+	return []Node{x}
+}
+
+func (l *lowerer) decl(x Node) []Node {
+	switch Head(x) {
+	case "func", "func...":
+		return []Node{l.funcDecl(x.(*List))}
+	case "var", "const":
+		if l.level >= LowerR7RS {
+			return l.defines(x.(*List))
+		}
+		return []Node{l.declValues(x.(*List))}
+	case "type":
+		if l.level >= LowerR7RS {
+			return nil
+		}
+		return []Node{x}
+	case "import":
+		return []Node{x}
+	}
+	return []Node{l.expr(x)}
+}
+
+// valueSpec is one (= names values...) or #(names type) of a var
+// or const form, or a comment between them.
+type valueSpec struct {
+	names   []Node
+	typ     Node // nil if omitted
+	values  []Node
+	comment Node
+}
+
+// specs parses the specs of a var or const form, repeating the
+// previous values for a bare constant name with iota advanced.
+func (l *lowerer) specs(x *List) []valueSpec {
+	var out []valueSpec
+	var last []Node
+	iota := 0
+	for _, elt := range x.Elts[1:] {
+		var spec valueSpec
+		switch elt := elt.(type) {
+		case *Comment:
+			out = append(out, valueSpec{comment: elt})
+			continue
+		case *Vector:
+			if len(elt.Elts) < 2 {
+				l.errorf(elt, "malformed %s spec", Head(x))
+				continue
+			}
+			spec.names, spec.typ = elt.Elts[:len(elt.Elts)-1], elt.Elts[len(elt.Elts)-1]
+		case *Symbol:
+			spec.names, spec.values = []Node{elt}, last
+		case *List:
+			if Head(elt) != "=" || len(elt.Elts) < 3 {
+				l.errorf(elt, "malformed %s spec", Head(x))
+				continue
+			}
+			spec.names, spec.values = names(elt.Elts[1]), elt.Elts[2:]
+			if v, ok := elt.Elts[1].(*Vector); ok && len(v.Elts) >= 2 {
+				spec.names, spec.typ = v.Elts[:len(v.Elts)-1], v.Elts[len(v.Elts)-1]
+			}
+			last = spec.values
+		}
+		if Head(x) == "const" {
+			values := make([]Node, len(spec.values))
+			for i, v := range spec.values {
+				values[i] = replace(v, "iota", &Number{Lit: strconv.Itoa(iota)})
+			}
+			spec.values = values
+			iota++
+		}
+		out = append(out, spec)
+	}
+	return out
+}
+
+// replace returns x with each symbol name replaced by with.
+func replace(x Node, name string, with Node) Node {
+	switch x := x.(type) {
+	case *Symbol:
+		if x.Name == name {
+			return with
+		}
+	case *List:
+		elts := make([]Node, len(x.Elts))
+		for i, elt := range x.Elts {
+			elts[i] = replace(elt, name, with)
+		}
+		return &List{Position: x.Position, Elts: elts}
+	case *Vector:
+		elts := make([]Node, len(x.Elts))
+		for i, elt := range x.Elts {
+			elts[i] = replace(elt, name, with)
+		}
+		return &Vector{Position: x.Position, Elts: elts}
+	}
+	return x
+}
+
+// declValues lowers the values of a top-level var or const form.
+func (l *lowerer) declValues(x *List) Node {
+	out := &List{Position: x.Position, Elts: []Node{x.Elts[0]}}
+	for _, spec := range x.Elts[1:] {
+		if Head(spec) == "=" {
+			elts := spec.(*List).Elts
+			spec = &List{Position: spec.Pos(), Elts: append(elts[:2:2], l.exprs(elts[2:])...)}
+		}
+		out.Elts = append(out.Elts, spec)
+	}
+	return out
+}
+
+// defines turns a top-level var or const form into definitions.
+func (l *lowerer) defines(x *List) []Node {
+	var out []Node
+	for _, spec := range l.specs(x) {
+		switch {
+		case spec.comment != nil:
+			out = append(out, spec.comment)
+		case len(spec.values) == 0:
+			for _, name := range spec.names {
+				out = append(out, form("define", l.binding(name), zero(spec.typ)))
+			}
+		case len(spec.values) == len(spec.names):
+			for i, name := range spec.names {
+				out = append(out, form("define", l.binding(name), l.expr(spec.values[i])))
+			}
+		case len(spec.values) == 1:
+			vars := &List{}
+			for _, name := range spec.names {
+				vars.Elts = append(vars.Elts, l.binding(name))
+			}
+			out = append(out, form("define-values", vars, l.expr(spec.values[0])))
+		default:
+			l.errorf(x, "%d names but %d values", len(spec.names), len(spec.values))
+		}
+	}
+	return out
+}
+
+// bind lowers a := or a local var or const form, whose scope is
+// body, into let.
+func (l *lowerer) bind(x Node, body []Node) Node {
+	list := x.(*List)
+	if Head(list) == ":=" {
+		if len(list.Elts) < 3 {
+			l.errorf(x, "malformed := form")
+			return begin(body)
+		}
+		return l.let(x, names(list.Elts[1]), nil, list.Elts[2:], body)
+	}
+	specs := l.specs(list)
+	for i := len(specs) - 1; i >= 0; i-- {
+		if spec := specs[i]; spec.comment == nil {
+			body = []Node{l.let(x, spec.names, spec.typ, spec.values, body)}
+		}
+	}
+	return begin(body)
+}
+
+// let binds names to values, or to the zero value of typ if there
+// are none, around body.
+func (l *lowerer) let(x Node, names []Node, typ Node, values []Node, body []Node) Node {
+	body = nonEmpty(body)
+	vars := &List{}
+	switch {
+	case len(values) == 0:
+		for _, name := range names {
+			vars.Elts = append(vars.Elts, &List{Elts: []Node{l.binding(name), zero(typ)}})
+		}
+	case len(values) == len(names):
+		for i, name := range names {
+			vars.Elts = append(vars.Elts, &List{Elts: []Node{l.binding(name), l.expr(values[i])}})
+		}
+	case len(values) == 1:
+		formals := &List{}
+		for _, name := range names {
+			formals.Elts = append(formals.Elts, l.binding(name))
+		}
+		vars.Elts = append(vars.Elts, &List{Elts: []Node{formals, l.expr(values[0])}})
+		return form("let-values", append([]Node{vars}, body...)...)
+	default:
+		l.errorf(x, "%d names but %d values", len(names), len(values))
+	}
+	return form("let", append([]Node{vars}, body...)...)
+}
+
+// zero returns the zero value of the type typ.
+func zero(typ Node) Node {
+	switch name := typ.(type) {
+	case *Symbol:
+		switch name.Name {
+		case "&bool":
+			return &Bool{}
+		case "&imm-string":
+			return &String{}
+		case "&float32", "&float64":
+			return &Number{Lit: "0.0"}
+		case "&any", "&error":
+			return &Nil{}
+		case "&int", "&int8", "&int16", "&int32", "&int64",
+			"&uint", "&uint8", "&uint16", "&uint32", "&uint64",
+			"&byte", "&rune", "&uintptr", "&complex64", "&complex128":
+			return &Number{Lit: "0"}
+		}
+	case *List:
+		switch Head(name) {
+		case "ptr", "slice", "map-type", "chan", "chan<-", "chan<-!", "func", "func...", "interface":
+			return &Nil{}
+		}
+	case nil:
+		return &Nil{}
+	}
+	return form("zero", typ)
+}
+
+// funcDecl lowers the body of (func [recv] name params result body...).
+func (l *lowerer) funcDecl(x *List) Node {
+	elts := x.Elts[1:]
+	var recv Node
+	if len(elts) >= 4 {
+		_, isVector := elts[0].(*Vector)
+		_, isName := elts[1].(*Symbol)
+		if isVector || isName {
+			recv, elts = elts[0], elts[1:]
+		}
+	}
+	if len(elts) < 3 {
+		l.errorf(x, "malformed %s form", Head(x))
+		return x
+	}
+	name, params, result, body := elts[0], elts[1], elts[2], elts[3:]
+	var doc []Node
+	if len(body) > 0 {
+		if s, ok := body[0].(*String); ok {
+			doc, body = []Node{s}, body[1:]
+		}
+	}
+	body = append(doc, l.funcBody(result, body)...)
+	if l.level < LowerR7RS {
+		head := []Node{x.Elts[0]}
+		if recv != nil {
+			head = append(head, recv)
+		}
+		head = append(head, name, params, result)
+		return &List{Position: x.Position, Elts: append(head, body...)}
+	}
+	if Head(name) == "generic" && len(name.(*List).Elts) >= 2 {
+		name = name.(*List).Elts[1]
+	}
+	var formals []Node
+	if recv != nil {
+		// a method T.M takes its receiver first, like the Go
+		// method expression of the same name
+		typ := recv
+		if v, ok := recv.(*Vector); ok && len(v.Elts) == 2 {
+			formals, typ = []Node{l.binding(v.Elts[0])}, v.Elts[1]
+		} else {
+			formals = []Node{l.binding(symbol("_"))}
+		}
+		name = symbol(typeName(typ) + "." + name.String())
+	}
+	formals = append(formals, l.params(params, Head(x) == "func...")...)
+	return &List{Position: x.Position, Elts: append([]Node{symbol("define"), &List{Elts: append([]Node{name}, formals...)}}, nonEmpty(body)...)}
+}
+
+// typeName returns the name of the type a receiver points to or
+// instantiates.
+func typeName(typ Node) string {
+	switch Head(typ) {
+	case "ptr", "inst":
+		if list := typ.(*List); len(list.Elts) >= 2 {
+			return typeName(list.Elts[1])
+		}
+	}
+	return typ.String()
+}
+
+// params returns the names of a parameter list, naming unnamed
+// parameters %0, %1 and so on. A variadic function takes its last
+// parameter as a rest list.
+func (l *lowerer) params(x Node, variadic bool) []Node {
+	list, ok := x.(*List)
+	if !ok {
+		l.errorf(x, "expected parameter list, found %s", x)
+		return nil
+	}
+	var out []Node
+	for _, elt := range list.Elts {
+		switch elt := elt.(type) {
+		case *Comment:
+		case *Vector:
+			for _, name := range elt.Elts[:len(elt.Elts)-1] {
+				out = append(out, l.binding(name))
+			}
+		default:
+			out = append(out, symbol("%"+strconv.Itoa(len(out))))
+		}
+	}
+	if variadic && len(out) > 0 {
+		out = append(out[:len(out)-1], symbol("."), out[len(out)-1])
+	}
+	return out
+}
+
+// funcLit lowers a function literal (func params result body...).
+func (l *lowerer) funcLit(x *List) Node {
+	if len(x.Elts) < 3 {
+		l.errorf(x, "malformed %s form", Head(x))
+		return x
+	}
+	body := l.funcBody(x.Elts[2], x.Elts[3:])
+	if l.level < LowerR7RS {
+		return &List{Position: x.Position, Elts: append(x.Elts[:3:3], body...)}
+	}
+	formals := &List{Elts: l.params(x.Elts[1], Head(x) == "func...")}
+	return &List{Position: x.Position, Elts: append([]Node{symbol("lambda"), formals}, nonEmpty(body)...)}
+}
+
+// funcBody lowers the body of a function with the given result.
+// A return in tail position becomes the value of the body; any
+// other return calls the escape continuation return.
+func (l *lowerer) funcBody(result Node, body []Node) []Node {
+	gotos, results := l.gotos, l.results
+	defer func() { l.gotos, l.results = gotos, results }()
+
+	l.gotos = map[string]bool{}
+	walk(body, func(x *List) bool {
+		if Head(x) == "goto" && len(x.Elts) == 2 {
+			l.gotos[x.Elts[1].String()] = true
+		}
+		return !isFunc(x)
+	})
+	l.results = nil
+	var types []Node
+	fields := []Node{result}
+	if Head(result) == "values" {
+		fields = result.(*List).Elts[1:]
+	}
+	for _, field := range fields {
+		if v, ok := field.(*Vector); ok && len(v.Elts) >= 2 {
+			for _, name := range v.Elts[:len(v.Elts)-1] {
+				l.results = append(l.results, name)
+				types = append(types, v.Elts[len(v.Elts)-1])
+			}
+		}
+	}
+
+	out := tail(l.seq(body))
+	if contains(body, "defer") {
+		out = []Node{form("let", &List{Elts: []Node{form("%defers", form("list"))}},
+			form("dynamic-wind",
+				form("lambda", &List{}, &Bool{}),
+				form("lambda", append([]Node{&List{}}, nonEmpty(out)...)...),
+				form("lambda", &List{},
+					form("for-each", form("lambda", form("%f"), form("%f")), symbol("%defers")))))}
+	}
+	if escapes(out) {
+		out = []Node{form("call/cc", form("lambda", append([]Node{form("return")}, nonEmpty(out)...)...))}
+	}
+	if len(l.results) > 0 {
+		vars := &List{}
+		for i, name := range l.results {
+			vars.Elts = append(vars.Elts, &List{Elts: []Node{l.binding(name), zero(types[i])}})
+		}
+		out = []Node{form("let", append([]Node{vars}, nonEmpty(out)...)...)}
+	}
+	return out
+}
+
+// walk calls f for each list in nodes, and for the lists within
+// each one for which f returns true.
+func walk(nodes []Node, f func(*List) bool) {
+	for _, x := range nodes {
+		switch x := x.(type) {
+		case *List:
+			if f(x) {
+				walk(x.Elts, f)
+			}
+		case *Vector:
+			walk(x.Elts, f)
+		}
+	}
+}
+
+// helper function
+func isFunc(x *List) bool {
+	head := Head(x)
+	return head == "func" || head == "func..." || head == "lambda"
+}
+
+// contains reports whether the statements in body, outside any
+// function literal, include a form named head.
+func contains(body []Node, head string) bool {
+	found := false
+	walk(body, func(x *List) bool {
+		found = found || Head(x) == head
+		return !found && !isFunc(x)
+	})
+	return found
+}
+
+// escapes reports whether lowered code calls return other than
+// within a function literal that captures its own.
+func escapes(body []Node) bool {
+	found := false
+	walk(body, func(x *List) bool {
+		if Head(x) == "call/cc" && len(x.Elts) == 2 && Head(x.Elts[1]) == "lambda" {
+			if formals := x.Elts[1].(*List).Elts; len(formals) > 1 && Head(formals[1]) == "return" {
+				return false
+			}
+		}
+		found = found || Head(x) == "return"
+		return !found
+	})
+	return found
+}
+
+// tail replaces a return at the end of body with the values it
+// returns.
+func tail(body []Node) []Node {
+	i := len(body) - 1
+	for i >= 0 {
+		if _, ok := body[i].(*Comment); !ok {
+			break
+		}
+		i--
+	}
+	if i < 0 {
+		return body
+	}
+	x, ok := body[i].(*List)
+	if !ok {
+		return body
+	}
+	out := append([]Node{}, body[:i]...)
+	after := body[i+1:]
+	var start int
+	switch Head(x) {
+	case "return":
+		switch values := x.Elts[1:]; len(values) {
+		case 0:
+		case 1:
+			out = append(out, values[0])
+		default:
+			out = append(out, form("values", values...))
+		}
+		return append(out, after...)
+	case "if":
+		if len(x.Elts) != 4 {
+			return body
+		}
+		then, els := begin(tail(x.Elts[2:3])), begin(tail(x.Elts[3:4]))
+		return append(append(out, form("if", x.Elts[1], then, els)), after...)
+	case "cond", "case":
+		clauses := []Node{x.Elts[0]}
+		start = 1
+		if Head(x) == "case" {
+			clauses, start = x.Elts[:2], 2
+		}
+		for _, clause := range x.Elts[start:] {
+			if c, ok := clause.(*List); ok && len(c.Elts) > 1 {
+				clause = &List{Position: c.Position, Elts: append(c.Elts[:1:1], nonEmpty(tail(c.Elts[1:]))...)}
+			}
+			clauses = append(clauses, clause)
+		}
+		return append(append(out, &List{Position: x.Position, Elts: clauses}), after...)
+	case "begin":
+		start = 1
+	case "when", "unless", "let-values":
+		start = 2
+	case "let":
+		// a named let is a loop, which returns by escaping
+		if len(x.Elts) < 2 || isNamed(x) {
+			return body
+		}
+		start = 2
+	default:
+		return body
+	}
+	if len(x.Elts) < start {
+		return body
+	}
+	lowered := &List{Position: x.Position, Elts: append(x.Elts[:start:start], nonEmpty(tail(x.Elts[start:]))...)}
+	return append(append(out, lowered), after...)
+}
+
+// helper function
+func isNamed(let *List) bool {
+	_, ok := let.Elts[1].(*Symbol)
+	return ok
+}
+
+// seq lowers a sequence of statements, whose declarations scope
+// over the rest of the sequence.
+func (l *lowerer) seq(stmts []Node) []Node {
+	for _, x := range stmts {
+		if l.isTarget(x) {
+			return []Node{l.gotoBlock(stmts)}
+		}
+	}
+	var out []Node
+	for i, x := range stmts {
+		switch Head(x) {
+		case ":=", "var", "const":
+			return append(out, l.bind(x, l.seq(stmts[i+1:])))
+		}
+		out = append(out, l.stmt(x)...)
+	}
+	return out
+}
+
+func (l *lowerer) stmt(x Node) []Node {
+	switch x := x.(type) {
+	case *Bool:
+		if !x.Value {
+			// the empty statement
+			return nil
+		}
+	case *List:
+		return l.stmtList(x, "")
+	}
+	return []Node{l.expr(x)}
+}
+
+// stmtList lowers the statement x, which is labeled label if that
+// is not "".
+func (l *lowerer) stmtList(x *List, label string) []Node {
+	elts := x.Elts[1:]
+	switch head := Head(x); head {
+	case "label":
+		if len(elts) != 2 {
+			l.errorf(x, "malformed label form")
+			return nil
+		}
+		if stmt, ok := elts[1].(*List); ok {
+			return l.stmtList(stmt, elts[0].String())
+		}
+		return l.stmt(elts[1])
+	case "when", "unless":
+		return []Node{l.ifStmt(x)}
+	case "when*", "unless*":
+		if len(elts) < 2 {
+			l.errorf(x, "malformed %s form", head)
+			return nil
+		}
+		stmt := &List{Position: x.Position, Elts: append([]Node{symbol(strings.TrimSuffix(head, "*"))}, elts[1:]...)}
+		return l.withInit(elts[0], l.ifStmt(stmt))
+	case "while":
+		if len(elts) < 1 {
+			l.errorf(x, "malformed while form")
+			return nil
+		}
+		return []Node{l.loop(label, elts[0], elts[1:], nil)}
+	case "for":
+		if len(elts) < 3 {
+			l.errorf(x, "malformed for form")
+			return nil
+		}
+		return l.withInit(elts[0], l.loop(label, elts[1], elts[3:], l.stmt(elts[2])))
+	case "range":
+		return []Node{l.rangeStmt(x, label)}
+	case "cond!", "case!", "type!", "comm!":
+		return []Node{l.switchStmt(x, label)}
+	case "cond!*", "case!*", "type!*":
+		if len(elts) < 2 {
+			l.errorf(x, "malformed %s form", head)
+			return nil
+		}
+		stmt := &List{Position: x.Position, Elts: append([]Node{symbol(strings.TrimSuffix(head, "*"))}, elts[1:]...)}
+		return l.withInit(elts[0], l.switchStmt(stmt, label))
+	case "goto":
+		if len(elts) != 1 {
+			l.errorf(x, "malformed goto form")
+			return nil
+		}
+		name := elts[0].String()
+		return []Node{form("goto-"+name, symbol("label-"+name))}
+	case "break", "continue":
+		if len(elts) == 1 {
+			return []Node{form(head + "-" + elts[0].String())}
+		}
+		return []Node{form(head)}
+	case "return":
+		if len(elts) == 0 {
+			return []Node{form("return", l.results...)}
+		}
+		return []Node{form("return", l.exprs(elts)...)}
+	case "fallthrough":
+		l.errorf(x, "fallthrough outside a switch clause")
+		return nil
+	case "go":
+		if len(elts) != 1 {
+			l.errorf(x, "malformed go form")
+			return nil
+		}
+		return []Node{form("go", l.thunk(elts[0]))}
+	case "defer":
+		if len(elts) != 1 {
+			l.errorf(x, "malformed defer form")
+			return nil
+		}
+		return []Node{form("set!", symbol("%defers"), form("cons", l.thunk(elts[0]), symbol("%defers")))}
+	case ":=", "var", "const":
+		return []Node{l.bind(x, nil)}
+	case "type":
+		if l.level >= LowerR7RS {
+			return nil
+		}
+		return []Node{x}
+	case "=", "++", "--":
+		if l.level >= LowerR7RS {
+			return l.assign(x)
+		}
+	default:
+		if l.level >= LowerR7RS && len(elts) == 2 && strings.HasSuffix(head, "=") && head != "=" {
+			return l.assign(x)
+		}
+	}
+	return []Node{l.expr(x)}
+}
+
+// withInit lowers the init statement of an if, for or switch
+// around the already lowered stmt.
+func (l *lowerer) withInit(init Node, stmt Node) []Node {
+	switch Head(init) {
+	case ":=", "var", "const":
+		return []Node{l.bind(init, []Node{stmt})}
+	}
+	return append(l.stmt(init), stmt)
+}
+
+// ifStmt lowers (when c body... (else alt...)) and unless.
+func (l *lowerer) ifStmt(x *List) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed %s form", Head(x))
+		return unspecified()
+	}
+	cond, body := l.expr(x.Elts[1]), x.Elts[2:]
+	if n := len(body); n > 0 && Head(body[n-1]) == "else" {
+		then, els := begin(l.seq(body[:n-1])), begin(l.seq(body[n-1].(*List).Elts[1:]))
+		if Head(x) == "unless" {
+			then, els = els, then
+		}
+		return form("if", cond, then, els)
+	}
+	lowered := l.seq(body)
+	if len(lowered) == 0 {
+		return cond
+	}
+	return form(Head(x), append([]Node{cond}, lowered...)...)
+}
+
+// loop lowers a loop with the given condition and body into a
+// named let. post, which is already lowered, runs after each
+// iteration, including those ended by continue.
+func (l *lowerer) loop(label string, cond Node, body []Node, post []Node) Node {
+	iter := l.escape("continue", label, body, loopForms, l.seq(body))
+	iter = append(append(iter, post...), form("%loop"))
+	if !isBool(cond, true) {
+		iter = []Node{form("when", append([]Node{l.expr(cond)}, iter...)...)}
+	}
+	loop := form("let", append([]Node{symbol("%loop"), &List{}}, iter...)...)
+	return begin(l.escape("break", label, body, breakForms, []Node{loop}))
+}
+
+// helper function
+func isBool(x Node, value bool) bool {
+	b, ok := x.(*Bool)
+	return ok && b.Value == value
+}
+
+var loopForms = map[string]bool{"for": true, "while": true, "range": true}
+
+var breakForms = map[string]bool{
+	"for": true, "while": true, "range": true,
+	"cond!": true, "cond!*": true, "case!": true, "case!*": true,
+	"type!": true, "type!*": true, "comm!": true,
+}
+
+// escape wraps lowered in (call/cc (lambda (name) ...)) if the
+// statements body leave it with (name) outside any of the nested
+// forms, or with (name label). The labeled continuation is bound
+// as name-label.
+func (l *lowerer) escape(name, label string, body []Node, nested map[string]bool, lowered []Node) []Node {
+	plain, labeled := exits(name, label, body, nested)
+	var k Node
+	switch {
+	case plain && labeled:
+		k = symbol(name)
+		lowered = []Node{form("let", append([]Node{&List{Elts: []Node{form(name+"-"+label, k)}}}, nonEmpty(lowered)...)...)}
+	case labeled:
+		k = symbol(name + "-" + label)
+	case plain:
+		k = symbol(name)
+	default:
+		return lowered
+	}
+	return []Node{form("call/cc", form("lambda", append([]Node{&List{Elts: []Node{k}}}, nonEmpty(lowered)...)...))}
+}
+
+// exits reports whether the statements body include (name) outside
+// any of the nested forms, and whether they include (name label).
+func exits(name, label string, body []Node, nested map[string]bool) (plain, labeled bool) {
+	var visit func(nodes []Node, inner bool)
+	visit = func(nodes []Node, inner bool) {
+		for _, x := range nodes {
+			list, ok := x.(*List)
+			if !ok || isFunc(list) {
+				continue
+			}
+			if Head(list) == name {
+				switch {
+				case len(list.Elts) == 1:
+					plain = plain || !inner
+				case label != "" && isSymbol(list.Elts[1], label):
+					labeled = true
+				}
+			}
+			visit(list.Elts, inner || nested[Head(list)])
+		}
+	}
+	visit(body, false)
+	return plain, labeled
+}
+
+// rangeStmt lowers (range [(:= vars x)] body...) into a call of
+// range-for-each with a procedure of the key and value.
+func (l *lowerer) rangeStmt(x *List, label string) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed range form")
+		return unspecified()
+	}
+	spec, body := x.Elts[1], x.Elts[2:]
+	subject := spec
+	formals := []Node{symbol("%k"), symbol("%v")}
+	var pre []Node
+	if head := Head(spec); (head == ":=" || head == "=") && len(spec.(*List).Elts) == 3 {
+		vars := names(spec.(*List).Elts[1])
+		subject = spec.(*List).Elts[2]
+		for i := 0; i < len(vars) && i < 2; i++ {
+			if head == ":=" {
+				formals[i] = l.binding(vars[i])
+			} else if !isSymbol(vars[i], "_") {
+				pre = append(pre, form("=", vars[i], formals[i]))
+			}
+		}
+	}
+	var lowered []Node
+	for _, stmt := range pre {
+		lowered = append(lowered, l.stmt(stmt)...)
+	}
+	lowered = append(lowered, l.escape("continue", label, body, loopForms, l.seq(body))...)
+	proc := form("lambda", append([]Node{&List{Elts: formals}}, nonEmpty(lowered)...)...)
+	loop := form("range-for-each", proc, l.expr(subject))
+	return begin(l.escape("break", label, body, breakForms, []Node{loop}))
+}
+
+// clauses returns the statements each switch clause runs, with a
+// trailing fallthrough replaced by those of the next clause.
+func clauses(list []Node) [][]Node {
+	out := make([][]Node, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		clause, ok := list[i].(*List)
+		if !ok || len(clause.Elts) == 0 {
+			continue
+		}
+		body := clause.Elts[1:]
+		if n := len(body); n > 0 && Head(body[n-1]) == "fallthrough" && i+1 < len(list) {
+			body = append(body[:n-1:n-1], out[i+1]...)
+		}
+		out[i] = body
+	}
+	return out
+}
+
+// switchStmt lowers cond!, case!, type! and comm!.
+func (l *lowerer) switchStmt(x *List, label string) Node {
+	var lowered Node
+	var body []Node
+	for _, clause := range x.Elts[1:] {
+		if list, ok := clause.(*List); ok {
+			body = append(body, list.Elts...)
+		}
+	}
+	switch Head(x) {
+	case "cond!":
+		lowered = l.condSwitch(x.Elts[1:])
+	case "case!":
+		lowered = l.caseSwitch(x)
+	case "type!":
+		lowered = l.typeSwitch(x)
+	case "comm!":
+		lowered = l.selectStmt(x.Elts[1:])
+	}
+	return begin(l.escape("break", label, body, breakForms, []Node{lowered}))
+}
+
+// condSwitch lowers the clauses of cond! into cond.
+func (l *lowerer) condSwitch(list []Node) Node {
+	out := []Node{symbol("cond")}
+	for i, body := range clauses(list) {
+		clause, ok := list[i].(*List)
+		if !ok || len(clause.Elts) == 0 {
+			out = append(out, list[i])
+			continue
+		}
+		lowered := l.seq(body)
+		if isSymbol(clause.Elts[0], "else") {
+			lowered = nonEmpty(lowered)
+		}
+		out = append(out, &List{Position: clause.Position, Elts: append([]Node{l.expr(clause.Elts[0])}, lowered...)})
+	}
+	return &List{Elts: out}
+}
+
+// caseSwitch lowers (case! tag ((values...) body...)...) into case
+// when every value is a literal that eqv? compares, and otherwise
+// into cond.
+func (l *lowerer) caseSwitch(x *List) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed case! form")
+		return unspecified()
+	}
+	tag, list := x.Elts[1], x.Elts[2:]
+	literal := true
+	for _, clause := range list {
+		if values, ok := clause.(*List); ok && len(values.Elts) > 0 {
+			if v, ok := values.Elts[0].(*List); ok {
+				for _, value := range v.Elts {
+					switch value.(type) {
+					case *Number, *Char, *Bool:
+					default:
+						literal = false
+					}
+				}
+			}
+		}
+	}
+	if literal {
+		out := []Node{symbol("case"), l.expr(tag)}
+		for i, body := range clauses(list) {
+			clause, ok := list[i].(*List)
+			if !ok || len(clause.Elts) == 0 {
+				out = append(out, list[i])
+				continue
+			}
+			out = append(out, &List{Position: clause.Position, Elts: append(clause.Elts[:1:1], nonEmpty(l.seq(body))...)})
+		}
+		return &List{Elts: out}
+	}
+	return l.temp("%tag", tag, func(tag Node) Node {
+		var conds []Node
+		for _, clause := range list {
+			if Head(clause) == "else" {
+				conds = append(conds, clause)
+				continue
+			}
+			var tests []Node
+			if clause, ok := clause.(*List); ok && len(clause.Elts) > 0 {
+				if v, ok := clause.Elts[0].(*List); ok {
+					for _, value := range v.Elts {
+						tests = append(tests, form("equal?", tag, value))
+					}
+				}
+				conds = append(conds, &List{Position: clause.Position, Elts: append([]Node{or(tests)}, clause.Elts[1:]...)})
+				continue
+			}
+			conds = append(conds, clause)
+		}
+		return l.condSwitch(conds)
+	})
+}
+
+// helper function
+func or(tests []Node) Node {
+	if len(tests) == 1 {
+		return tests[0]
+	}
+	return form("or", tests...)
+}
+
+// temp calls f with the expression x if it is a symbol, and
+// otherwise binds name to its value around what f returns for name.
+func (l *lowerer) temp(name string, x Node, f func(Node) Node) Node {
+	if _, ok := x.(*Symbol); ok {
+		return f(x)
+	}
+	return form("let", &List{Elts: []Node{form(name, l.expr(x))}}, f(symbol(name)))
+}
+
+// typeSwitch lowers (type! [(:= v] (as x type)[)] ((types...) body...)...)
+// into cond over type-is?, binding v in each clause.
+func (l *lowerer) typeSwitch(x *List) Node {
+	if len(x.Elts) < 2 {
+		l.errorf(x, "malformed type! form")
+		return unspecified()
+	}
+	guard := x.Elts[1]
+	var v Node
+	if Head(guard) == ":=" && len(guard.(*List).Elts) == 3 {
+		v, guard = guard.(*List).Elts[1], guard.(*List).Elts[2]
+	}
+	if Head(guard) != "as" || len(guard.(*List).Elts) != 3 {
+		l.errorf(guard, "malformed type! guard %s", guard)
+		return unspecified()
+	}
+	list := x.Elts[2:]
+	return l.temp("%x", guard.(*List).Elts[1], func(subject Node) Node {
+		out := []Node{symbol("cond")}
+		for i, body := range clauses(list) {
+			clause, ok := list[i].(*List)
+			if !ok || len(clause.Elts) == 0 {
+				out = append(out, list[i])
+				continue
+			}
+			test := clause.Elts[0]
+			if types, ok := test.(*List); ok {
+				var tests []Node
+				for _, typ := range types.Elts {
+					if _, ok := typ.(*Nil); ok {
+						tests = append(tests, form("eqv?", subject, typ))
+					} else {
+						tests = append(tests, form("type-is?", subject, typ))
+					}
+				}
+				test = or(tests)
+			}
+			lowered := nonEmpty(l.seq(body))
+			if v != nil {
+				lowered = []Node{form("let", append([]Node{&List{Elts: []Node{&List{Elts: []Node{v, subject}}}}}, lowered...)...)}
+			}
+			out = append(out, &List{Position: clause.Position, Elts: append([]Node{test}, lowered...)})
+		}
+		return &List{Elts: out}
+	})
+}
+
+// selectStmt lowers the clauses of comm! into a call of select.
+func (l *lowerer) selectStmt(list []Node) Node {
+	cases := []Node{symbol("list")}
+	var otherwise Node = &Bool{}
+	for _, x := range list {
+		clause, ok := x.(*List)
+		if !ok || len(clause.Elts) == 0 {
+			continue
+		}
+		comm, body := clause.Elts[0], clause.Elts[1:]
+		if isSymbol(comm, "else") {
+			otherwise = form("lambda", append([]Node{&List{}}, nonEmpty(l.seq(body))...)...)
+			continue
+		}
+		formals := []Node{symbol("%v"), symbol("%ok")}
+		var pre []Node
+		elts := []Node{nil}
+		if list, ok := comm.(*List); ok {
+			elts = list.Elts
+		}
+		switch head := Head(comm); {
+		case head == "<-!" && len(elts) == 3:
+			proc := form("lambda", append([]Node{&List{}}, nonEmpty(l.seq(body))...)...)
+			cases = append(cases, form("send-case", l.expr(elts[1]), l.expr(elts[2]), proc))
+			continue
+		case (head == ":=" || head == "=") && len(elts) == 3:
+			vars := names(elts[1])
+			for i := 0; i < len(vars) && i < 2; i++ {
+				if head == ":=" {
+					formals[i] = l.binding(vars[i])
+				} else if !isSymbol(vars[i], "_") {
+					pre = append(pre, form("=", vars[i], formals[i]))
+				}
+			}
+			comm, elts = elts[2], []Node{nil}
+			if list, ok := comm.(*List); ok {
+				elts = list.Elts
+			}
+		}
+		if Head(comm) != "<-" || len(elts) != 2 {
+			l.errorf(comm, "malformed comm! clause %s", comm)
+			continue
+		}
+		var lowered []Node
+		for _, stmt := range pre {
+			lowered = append(lowered, l.stmt(stmt)...)
+		}
+		lowered = append(lowered, l.seq(body)...)
+		proc := form("lambda", append([]Node{&List{Elts: formals}}, nonEmpty(lowered)...)...)
+		cases = append(cases, form("recv-case", l.expr(elts[1]), proc))
+	}
+	return form("select", &List{Elts: cases}, otherwise)
+}
+
+// isTarget reports whether x is a labeled statement that a goto
+// in the current function jumps to.
+func (l *lowerer) isTarget(x Node) bool {
+	list, ok := x.(*List)
+	return ok && Head(list) == "label" && len(list.Elts) == 3 && l.gotos[list.Elts[1].String()]
+}
+
+// gotoBlock lowers a sequence of statements holding goto targets.
+// Each target starts a procedure label-L that runs the rest of the
+// sequence, and a trampoline calls them in turn: (goto-L label-L)
+// escapes to it with the next one to call. Go does not let a goto
+// jump over a declaration, so the sequence's declarations can be
+// hoisted to its start and become assignments.
+func (l *lowerer) gotoBlock(stmts []Node) Node {
+	vars := &List{}
+	var body []Node
+	for _, x := range stmts {
+		switch Head(x) {
+		case ":=":
+			elts := x.(*List).Elts
+			if len(elts) < 3 {
+				l.errorf(x, "malformed := form")
+				continue
+			}
+			for _, name := range names(elts[1]) {
+				if !isSymbol(name, "_") {
+					vars.Elts = append(vars.Elts, form(name.String(), &Bool{}))
+				}
+			}
+			body = append(body, &List{Position: x.Pos(), Elts: append([]Node{symbol("=")}, elts[1:]...)})
+		case "var", "const":
+			for _, spec := range l.specs(x.(*List)) {
+				if spec.comment != nil {
+					continue
+				}
+				var lhs Node = &List{Elts: spec.names}
+				if len(spec.names) == 1 {
+					lhs = spec.names[0]
+				}
+				values := spec.values
+				if len(values) == 0 {
+					for range spec.names {
+						values = append(values, zero(spec.typ))
+					}
+				}
+				for _, name := range spec.names {
+					if !isSymbol(name, "_") {
+						vars.Elts = append(vars.Elts, form(name.String(), &Bool{}))
+					}
+				}
+				body = append(body, form("=", append([]Node{lhs}, values...)...))
+			}
+		default:
+			body = append(body, x)
+		}
+	}
+
+	// split the statements into the segments before each target
+	type segment struct {
+		label string
+		stmts []Node
+	}
+	segments := []segment{{}}
+	for _, x := range body {
+		if l.isTarget(x) {
+			segments = append(segments, segment{label: x.(*List).Elts[1].String()})
+		}
+		last := &segments[len(segments)-1]
+		last.stmts = append(last.stmts, x)
+	}
+	lowered := make([][]Node, len(segments))
+	for i, seg := range segments {
+		stmts := seg.stmts
+		if seg.label != "" {
+			// keep the label for break and continue
+			lowered[i] = l.stmt(stmts[0])
+			stmts = stmts[1:]
+		}
+		lowered[i] = append(lowered[i], l.seq(stmts)...)
+		if i+1 < len(segments) {
+			lowered[i] = append(lowered[i], form("label-"+segments[i+1].label))
+		}
+	}
+
+	procs := &List{}
+	k := form("call/cc", form("lambda", form("%k")))
+	for i, seg := range segments[1:] {
+		vars.Elts = append(vars.Elts, form("goto-"+seg.label, &Bool{}))
+		procs.Elts = append(procs.Elts, form("label-"+seg.label, form("lambda", append([]Node{&List{}}, nonEmpty(lowered[i+1])...)...)))
+		lambda := k.Elts[1].(*List)
+		lambda.Elts = append(lambda.Elts, form("set!", symbol("goto-"+seg.label), symbol("%k")))
+	}
+	lambda := k.Elts[1].(*List)
+	lambda.Elts = append(lambda.Elts, form("%next"), &Bool{})
+	start := form("lambda", append([]Node{&List{}}, nonEmpty(lowered[0])...)...)
+	trampoline := form("let", symbol("%goto"), &List{Elts: []Node{form("%next", start)}},
+		form("let", &List{Elts: []Node{form("%target", k)}},
+			form("when", symbol("%target"), form("%goto", symbol("%target")))))
+	return form("let", vars, form("letrec", procs, trampoline))
+}
+
+// thunk returns a procedure of no arguments that makes the call x,
+// whose arguments are evaluated at once, as go and defer do.
+func (l *lowerer) thunk(x Node) Node {
+	call, ok := x.(*List)
+	if !ok || len(call.Elts) == 0 {
+		l.errorf(x, "expected call, found %s", x)
+		return form("lambda", &List{}, x)
+	}
+	vars := &List{}
+	elts := []Node{l.expr(call.Elts[0])}
+	for i, arg := range l.exprs(call.Elts[1:]) {
+		switch arg.(type) {
+		case *Number, *String, *Char, *Bool, *Nil:
+			elts = append(elts, arg)
+			continue
+		}
+		if i == 1 && Head(call) == "call-method" {
+			// the method name
+			elts = append(elts, arg)
+			continue
+		}
+		name := symbol("%" + strconv.Itoa(len(vars.Elts)))
+		vars.Elts = append(vars.Elts, &List{Elts: []Node{name, arg}})
+		elts = append(elts, name)
+	}
+	lambda := form("lambda", &List{}, &List{Position: call.Position, Elts: elts})
+	if len(vars.Elts) == 0 {
+		return lambda
+	}
+	return form("let", vars, lambda)
+}
+
+// assign lowers =, compound assignments, ++ and -- into set!.
+func (l *lowerer) assign(x *List) []Node {
+	elts := x.Elts[1:]
+	switch head := Head(x); head {
+	case "++", "--":
+		if len(elts) != 1 {
+			break
+		}
+		return []Node{l.set(elts[0], form(head[:1], l.expr(elts[0]), &Number{Lit: "1"}))}
+	case "=":
+		if len(elts) < 2 {
+			break
+		}
+		targets, values := names(elts[0]), l.exprs(elts[1:])
+		switch {
+		case len(targets) == 1 && len(values) == 1:
+			return []Node{l.set(targets[0], values[0])}
+		case len(targets) == len(values):
+			// evaluate every value before assigning any
+			vars := &List{}
+			var sets []Node
+			for i, target := range targets {
+				name := symbol("%" + strconv.Itoa(i))
+				vars.Elts = append(vars.Elts, &List{Elts: []Node{name, values[i]}})
+				sets = append(sets, l.set(target, name))
+			}
+			return []Node{form("let", append([]Node{vars}, sets...)...)}
+		case len(values) == 1:
+			formals := &List{}
+			var sets []Node
+			for i, target := range targets {
+				name := symbol("%" + strconv.Itoa(i))
+				formals.Elts = append(formals.Elts, name)
+				sets = append(sets, l.set(target, name))
+			}
+			return []Node{form("call-with-values", form("lambda", &List{}, values[0]),
+				form("lambda", append([]Node{formals}, sets...)...))}
+		}
+	default:
+		if len(elts) != 2 || len(names(elts[0])) != 1 {
+			break
+		}
+		targets := names(elts[0])
+		op := strings.TrimSuffix(head, "=")
+		return []Node{l.set(targets[0], form(op, l.expr(targets[0]), l.expr(elts[1])))}
+	}
+	l.errorf(x, "malformed %s form", Head(x))
+	return nil
+}
+
+// set assigns value to the target of an assignment.
+func (l *lowerer) set(target Node, value Node) Node {
+	switch Head(target) {
+	case "index":
+		return form("index-set!", append(l.exprs(target.(*List).Elts[1:]), value)...)
+	case "dot":
+		elts := target.(*List).Elts
+		if len(elts) == 3 {
+			return form("dot-set!", l.expr(elts[1]), elts[2], value)
+		}
+	case "ptr":
+		return form("ptr-set!", append(l.exprs(target.(*List).Elts[1:]), value)...)
+	}
+	if isSymbol(target, "_") {
+		return value
+	}
+	if _, ok := target.(*Symbol); ok {
+		if sel, ok := selectors(target, l.prefixes).(*List); ok {
+			// x.f, the field of a local rather than a package variable
+			return l.set(sel, value)
+		}
+		return form("set!", target, value)
+	}
+	l.errorf(target, "cannot assign to %s", target)
+	return value
+}
+
+// helper function
+func (l *lowerer) exprs(list []Node) []Node {
+	out := make([]Node, len(list))
+	for i, x := range list {
+		out[i] = l.expr(x)
+	}
+	return out
+}
+
+// expr lowers the function literals within the expression x,
+// leaving the types in it alone.
+func (l *lowerer) expr(x Node) Node {
+	switch x := x.(type) {
+	case *List:
+		// the leading elements that are types, not expressions
+		keep := 0
+		switch Head(x) {
+		case "func", "func...":
+			return l.funcLit(x)
+		case "convert", "make", "new", "method-expr":
+			keep = 2
+		case "as":
+			if len(x.Elts) == 3 {
+				return &List{Position: x.Position, Elts: []Node{x.Elts[0], l.expr(x.Elts[1]), x.Elts[2]}}
+			}
+		case "inst":
+			return x
+		case "apply...":
+			if l.level >= LowerR7RS {
+				return &List{Position: x.Position, Elts: append([]Node{symbol("apply")}, l.exprs(x.Elts[1:])...)}
+			}
+		}
+		if keep > len(x.Elts) {
+			keep = len(x.Elts)
+		}
+		return &List{Position: x.Position, Elts: append(x.Elts[:keep:keep], l.exprs(x.Elts[keep:])...)}
+	case *Vector:
+		// a composite literal, whose first element is its type
+		if len(x.Elts) == 0 {
+			return x
+		}
+		return &Vector{Position: x.Position, Elts: append(x.Elts[:1:1], l.exprs(x.Elts[1:])...)}
+	}
+	return x
+}
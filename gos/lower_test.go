@@ -0,0 +1,106 @@
+package gos
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLower lowers the stmts and lower goldens to each level and
+// compares the pretty-printed result with testdata/name.level.scm.
+func TestLower(t *testing.T) {
+	for _, name := range []string{"stmts", "lower"} {
+		for _, level := range []Level{LowerCore, LowerR7RS} {
+			golden := filepath.Join("testdata", name+"."+level.String()+".scm")
+			t.Run(filepath.Base(golden), func(t *testing.T) {
+				got := lowerGolden(t, filepath.Join("testdata", name+".go"), level)
+				if *update {
+					if err := os.WriteFile(golden, got, 0644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s differs:\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+				}
+			})
+		}
+	}
+}
+
+func lowerGolden(t *testing.T, name string, level Level) []byte {
+	forms, err := Read(name, translateGolden(t, name), ReadComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forms, err = Lower(forms, level); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := Fprint(&out, forms, DefaultStyle); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// TestLowerComplete checks that lowering every golden leaves none
+// of the Gos statement forms behind.
+func TestLowerComplete(t *testing.T) {
+	sugar := map[string]bool{
+		"when*": true, "unless*": true, "while": true, "for": true, "range": true,
+		"cond!": true, "cond!*": true, "case!": true, "case!*": true,
+		"type!": true, "type!*": true, "comm!": true,
+		"label": true, "goto": true, "fallthrough": true,
+		":=": true, "defer": true,
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		forms, err := Read(name, lowerGolden(t, name, LowerCore), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walk(forms, func(x *List) bool {
+			if sugar[Head(x)] {
+				t.Errorf("%s: %s left in %s", name, Head(x), x)
+			}
+			return true
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range []Level{LowerNone, LowerCore, LowerR7RS} {
+		if got, err := ParseLevel(level.String()); got != level || err != nil {
+			t.Errorf("ParseLevel(%q) = %v, %v", level, got, err)
+		}
+	}
+	if _, err := ParseLevel("r6rs"); err == nil || !strings.Contains(err.Error(), "none, core, r7rs") {
+		t.Errorf("ParseLevel(\"r6rs\") error = %v", err)
+	}
+}
+
+func TestLowerFieldSet(t *testing.T) {
+	src := `(package p (import "os" (as f "fmt")) (func g (#(c (ptr T))) &void (= c.n 1) (= os.Args nil) (= f.X 2)))`
+	forms, err := Read("p.gos", []byte(src), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lowered, err := Lower(forms, LowerR7RS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := joinNodes(lowered)
+	want := `(package p (import "os" (as f "fmt")) (define (g c) (dot-set! c n 1) (set! os.Args nil) (set! f.X 2)))`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package gos
+
+import (
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// reservedNames holds the Go identifiers that would be read as
+// something else if written unchanged: the Gos forms, and the
+// syntactic keywords of the Scheme dialects Gos is translated to.
+// Go's own keywords cannot be identifiers, so they are not listed.
+var reservedNames = map[string]bool{
+	// Gos forms
+	"adr":     true,
+	"and":     true,
+	"array":   true,
+	"as":      true,
+	"convert": true,
+	"dot":     true,
+	"generic": true,
+	"index":   true,
+	"inst":    true,
+	"label":   true,
+	"method":  true,
+	"not":     true,
+	"or":      true,
+	"ptr":     true,
+	"slice":   true,
+	"union":   true,
+	"unless":  true,
+	"values":  true,
+	"when":    true,
+	"while":   true,
+
+	// Scheme procedures the translation calls
+	"quotient":  true,
+	"remainder": true,
+
+	// Scheme syntax
+	"begin":        true,
+	"cond":         true,
+	"define":       true,
+	"delay":        true,
+	"do":           true,
+	"export":       true,
+	"guard":        true,
+	"lambda":       true,
+	"let":          true,
+	"letrec":       true,
+	"library":      true,
+	"parameterize": true,
+	"quasiquote":   true,
+	"quote":        true,
+	"unquote":      true,
+}
+
+// reservedPrefix marks a reserved Go identifier in Gos. It cannot
+// occur in a Go identifier, so escaped names never collide.
+const reservedPrefix = "$"
+
+// UnmangleName returns the Gos symbol for the Go identifier name.
+// Go identifiers are already valid symbols, so only reserved names
+// change: they get a "$" prefix, as in $when.
+func UnmangleName(name string) string {
+	if reservedNames[name] {
+		return reservedPrefix + name
+	}
+	return name
+}
+
+// MangleName returns the Go identifier for the Gos symbol name,
+// undoing UnmangleName: MangleName(UnmangleName(id)) == id for
+// every Go identifier id. Symbols that UnmangleName never returns,
+// such as list->vector, have each character that is not allowed
+// in a Go identifier replaced by _; this is not reversible, as
+// EscapeName is.
+func MangleName(name string) string {
+	if strings.HasPrefix(name, reservedPrefix) && reservedNames[name[len(reservedPrefix):]] {
+		return name[len(reservedPrefix):]
+	}
+	if token.IsIdentifier(name) {
+		return name
+	}
+	var buf strings.Builder
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case unicode.IsDigit(r) && i > 0:
+		default:
+			r = '_'
+		}
+		buf.WriteRune(r)
+	}
+	id := buf.String()
+	if id == "" || token.IsKeyword(id) {
+		id += "_"
+	}
+	return id
+}
+
+// EscapeName returns a Go identifier for the Gos symbol name, which
+// must be valid UTF-8, that UnescapeName turns back into name. Each
+// character that MangleName would replace by _, and each _ itself,
+// is written as its code point in hex between two _, so that
+// list->vector becomes list_2d__3e_vector. Names that are Go
+// keywords have their first letter escaped.
+func EscapeName(name string) string {
+	var buf strings.Builder
+	for i, r := range name {
+		if r == '_' || !(unicode.IsLetter(r) || unicode.IsDigit(r) && i > 0) ||
+			i == 0 && token.IsKeyword(name) {
+			buf.WriteString("_" + strconv.FormatInt(int64(r), 16) + "_")
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// UnescapeName returns the Gos symbol that EscapeName turned into
+// the Go identifier id, or false if id is not one EscapeName returns.
+func UnescapeName(id string) (string, bool) {
+	var buf strings.Builder
+	for rest := id; rest != ""; {
+		i := strings.IndexByte(rest, '_')
+		if i < 0 {
+			buf.WriteString(rest)
+			break
+		}
+		buf.WriteString(rest[:i])
+		j := strings.IndexByte(rest[i+1:], '_')
+		if j < 0 {
+			return "", false
+		}
+		r, err := strconv.ParseInt(rest[i+1:i+1+j], 16, 32)
+		if err != nil {
+			return "", false
+		}
+		buf.WriteRune(rune(r))
+		rest = rest[i+2+j:]
+	}
+	// only the spelling EscapeName chooses is undone
+	name := buf.String()
+	if EscapeName(name) != id {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,272 @@
+package gos
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Node is an element of a Gos tree, as produced by Read.
+// String returns the node written in Gos syntax.
+type Node interface {
+	Pos() token.Position
+	String() string
+}
+
+// Symbol is an identifier such as func, &int or fmt.Println.
+type Symbol struct {
+	Position token.Position
+	Name     string
+}
+
+// List is a parenthesized form: (head args...).
+type List struct {
+	Position token.Position
+	Elts     []Node
+}
+
+// Vector is a #(...) form, used for typed names and composite
+// literals.
+type Vector struct {
+	Position token.Position
+	Elts     []Node
+}
+
+// String is a string literal; Value holds the decoded contents.
+type String struct {
+	Position token.Position
+	Value    string
+}
+
+// Bytevector is a #u8(...) literal, used for Go strings that are
+// not valid UTF-8.
+type Bytevector struct {
+	Position token.Position
+	Value    []byte
+}
+
+// Char is a #\ character literal.
+type Char struct {
+	Position token.Position
+	Value    rune
+}
+
+// Number is a numeric literal, kept as written.
+type Number struct {
+	Position token.Position
+	Lit      string
+}
+
+// Bool is #t or #f.
+type Bool struct {
+	Position token.Position
+	Value    bool
+}
+
+// Nil is %nil, the translation of Go's nil.
+type Nil struct {
+	Position token.Position
+}
+
+// Comment is a ;; comment, only returned when reading with
+// ReadComments. Trailing is set when the comment followed other
+// text on the same line.
+type Comment struct {
+	Position token.Position
+	Text     string
+	Trailing bool
+}
+
+// Brackets is a [...] vector, or a {...} map if Map is set, which
+// the Clojure and EDN backends write and Read never returns. Pairs
+// lays a vector out two elements to a line, as the bindings of a
+// let are.
+type Brackets struct {
+	Position token.Position
+	Map      bool
+	Pairs    bool
+	Elts     []Node
+}
+
+func (x *Symbol) Pos() token.Position     { return x.Position }
+func (x *List) Pos() token.Position       { return x.Position }
+func (x *Vector) Pos() token.Position     { return x.Position }
+func (x *String) Pos() token.Position     { return x.Position }
+func (x *Bytevector) Pos() token.Position { return x.Position }
+func (x *Char) Pos() token.Position       { return x.Position }
+func (x *Number) Pos() token.Position     { return x.Position }
+func (x *Bool) Pos() token.Position       { return x.Position }
+func (x *Nil) Pos() token.Position        { return x.Position }
+func (x *Comment) Pos() token.Position    { return x.Position }
+func (x *Brackets) Pos() token.Position   { return x.Position }
+
+func (x *Symbol) String() string  { return x.Name }
+func (x *List) String() string    { return "(" + joinNodes(x.Elts) + ")" }
+func (x *Vector) String() string  { return "#(" + joinNodes(x.Elts) + ")" }
+func (x *String) String() string  { return quoteString(x.Value) }
+func (x *Char) String() string    { return quoteChar(x.Value) }
+func (x *Number) String() string  { return x.Lit }
+func (x *Nil) String() string     { return "%nil" }
+func (x *Comment) String() string { return x.Text }
+
+func (x *Brackets) String() string {
+	open, close := x.delims()
+	return open + joinNodes(x.Elts) + close
+}
+
+// delims returns the brackets or braces around x.
+func (x *Brackets) delims() (string, string) {
+	if x.Map {
+		return "{", "}"
+	}
+	return "[", "]"
+}
+
+func (x *Bytevector) String() string {
+	var buf strings.Builder
+	buf.WriteString("#u8(")
+	for i, b := range x.Value {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprint(&buf, b)
+	}
+	buf.WriteByte(')')
+	return buf.String()
+}
+
+func (x *Bool) String() string {
+	if x.Value {
+		return "#t"
+	}
+	return "#f"
+}
+
+// joinNodes writes elts separated by spaces, starting a new line
+// before each comment that does not trail the node before it and
+// ending the line after each comment, so that the result reads
+// back the same.
+func joinNodes(elts []Node) string {
+	var buf strings.Builder
+	for i, elt := range elts {
+		comment, ok := elt.(*Comment)
+		if i > 0 {
+			if _, after := elts[i-1].(*Comment); !after {
+				if ok && !comment.Trailing {
+					buf.WriteByte('\n')
+				} else {
+					buf.WriteByte(' ')
+				}
+			}
+		}
+		buf.WriteString(elt.String())
+		if ok {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.String()
+}
+
+// joinLines writes forms separated by newlines.
+func joinLines(forms []Node) string {
+	var buf strings.Builder
+	for i, form := range forms {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(form.String())
+	}
+	return buf.String()
+}
+
+// Write writes forms to wr unformatted, one top-level form per
+// line. Fprint lays them out for reading instead.
+func Write(wr io.Writer, forms []Node) error {
+	for _, form := range forms {
+		if _, err := fmt.Fprintln(wr, form); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Head returns the name of the symbol that begins x, or "" if x is
+// not a list headed by a symbol.
+func Head(x Node) string {
+	if list, ok := x.(*List); ok && len(list.Elts) > 0 {
+		if sym, ok := list.Elts[0].(*Symbol); ok {
+			return sym.Name
+		}
+	}
+	return ""
+}
+
+// charNames maps the character names that are read and written
+// after #\ to the characters they denote.
+var charNames = map[string]rune{
+	"alarm":     '\a',
+	"backspace": '\b',
+	"delete":    0x7f,
+	"escape":    0x1b,
+	"newline":   '\n',
+	"null":      0,
+	"return":    '\r',
+	"space":     ' ',
+	"tab":       '\t',
+
+	// accepted when reading, never written
+	"bel":      '\a',
+	"esc":      0x1b,
+	"linefeed": '\n',
+	"nul":      0,
+	"page":     '\f',
+	"vtab":     '\v',
+}
+
+// quoteChar returns the #\ syntax for r.
+func quoteChar(r rune) string {
+	for _, name := range []string{"alarm", "backspace", "delete", "escape", "newline", "null", "return", "space", "tab"} {
+		if charNames[name] == r {
+			return `#\` + name
+		}
+	}
+	if unicode.IsPrint(r) && !unicode.Is(unicode.M, r) {
+		return `#\` + string(r)
+	}
+	return fmt.Sprintf(`#\x%x`, r)
+}
+
+// quoteString returns s as a string literal, escaping quotes,
+// backslashes and control characters.
+func quoteString(s string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\a':
+			buf.WriteString(`\a`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if unicode.IsPrint(r) {
+				buf.WriteRune(r)
+			} else {
+				fmt.Fprintf(&buf, `\x%x;`, r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
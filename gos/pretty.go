@@ -0,0 +1,303 @@
+package gos
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Style controls the layout produced by Format.
+type Style struct {
+	// Indent is the number of spaces by which the body of a form
+	// is indented relative to its opening parenthesis.
+	Indent int
+
+	// Width is the line width that Format tries not to exceed.
+	Width int
+
+	// Rules gives, for each form name, how many arguments stay on
+	// the line with the form name before the body is broken onto
+	// indented lines. Forms without a rule align their arguments
+	// under the first one.
+	Rules map[string]int
+}
+
+// DefaultStyle is used by the command line unless -indent or
+// -width say otherwise.
+var DefaultStyle = Style{
+	Indent: 2,
+	Width:  80,
+	Rules: map[string]int{
+		"package":        1,
+		"import":         0,
+		"const":          0,
+		"var":            0,
+		"type":           1,
+		"func":           3,
+		"func...":        3,
+		"struct":         0,
+		"interface":      0,
+		"when":           1,
+		"when*":          2,
+		"unless":         1,
+		"unless*":        2,
+		"else":           0,
+		"while":          1,
+		"for":            3,
+		"range":          1,
+		"cond!":          0,
+		"cond!*":         1,
+		"case!":          1,
+		"case!*":         2,
+		"type!":          1,
+		"type!*":         2,
+		"comm!":          0,
+		"label":          1,
+		"begin":          0,
+		"define":         1,
+		"define-module":  1,
+		"lambda":         1,
+		"let":            1,
+		"let*":           1,
+		"letrec":         1,
+		"let-values":     1,
+		"define-values":  1,
+		"cond":           0,
+		"case":           1,
+		"dynamic-wind":   0,
+		"send-case":      2,
+		"recv-case":      1,
+		"define-library": 1,
+
+		// Common Lisp
+		"defpackage":          1,
+		"defun":               2,
+		"defparameter":        1,
+		"defstruct":           1,
+		"eval-when":           1,
+		"block":               1,
+		"tagbody":             0,
+		"loop":                0,
+		"progn":               0,
+		"unwind-protect":      1,
+		"multiple-value-bind": 2,
+
+		// Clojure
+		"ns":          1,
+		"defn":        1,
+		"defn-":       1,
+		"def":         1,
+		"defrecord":   2,
+		"defprotocol": 1,
+		"extend":      2,
+		"fn":          1,
+		"do":          0,
+		"try":         0,
+		"finally":     0,
+		"when-not":    1,
+		"condp":       2,
+	},
+}
+
+// Format reads the s-expressions in src and writes them to wr laid
+// out according to style. The result depends only on src and style.
+func Format(wr io.Writer, src []byte, style Style) error {
+	forms, err := Read("", src, ReadComments)
+	if err != nil {
+		return err
+	}
+	return Fprint(wr, forms, style)
+}
+
+// Fprint writes forms to wr laid out according to style, one
+// top-level form per line.
+func Fprint(wr io.Writer, forms []Node, style Style) error {
+	p := &prettyPrinter{style: style}
+	for _, form := range forms {
+		p.print(form, 0)
+		p.buf.WriteByte('\n')
+	}
+	_, err := wr.Write(p.buf.Bytes())
+	return err
+}
+
+type prettyPrinter struct {
+	style Style
+	buf   bytes.Buffer
+}
+
+// flat returns the one-line rendering of x, or false if x holds
+// a comment and so cannot be written on one line.
+func (p *prettyPrinter) flat(x Node) (string, bool) {
+	var elts []Node
+	open, close := "(", ")"
+	switch x := x.(type) {
+	case *Comment:
+		return "", false
+	case *List:
+		elts = x.Elts
+	case *Vector:
+		open, elts = "#(", x.Elts
+	case *Brackets:
+		open, close = x.delims()
+		elts = x.Elts
+	default:
+		return x.String(), true
+	}
+	parts := make([]string, len(elts))
+	for i, elt := range elts {
+		s, ok := p.flat(elt)
+		if !ok {
+			return "", false
+		}
+		parts[i] = s
+	}
+	return open + strings.Join(parts, " ") + close, true
+}
+
+func (p *prettyPrinter) newline(col int) {
+	p.buf.WriteByte('\n')
+	p.buf.WriteString(strings.Repeat(" ", col))
+}
+
+// print writes x, whose first character is at column col.
+func (p *prettyPrinter) print(x Node, col int) {
+	if s, ok := p.flat(x); ok && col+utf8.RuneCountInString(s) <= p.style.Width {
+		p.buf.WriteString(s)
+		return
+	}
+	var elts []Node
+	open, close := "(", ")"
+	switch x := x.(type) {
+	case *List:
+		elts = x.Elts
+	case *Vector:
+		open, elts = "#(", x.Elts
+	case *Brackets:
+		open, close = x.delims()
+		if x.Map || x.Pairs {
+			p.buf.WriteString(open)
+			p.printPairs(x.Elts, col+len(open))
+			p.buf.WriteString(close)
+			return
+		}
+		elts = x.Elts
+	default:
+		p.buf.WriteString(x.String())
+		return
+	}
+
+	p.buf.WriteString(open)
+	if len(elts) == 0 {
+		p.buf.WriteString(close)
+		return
+	}
+	inner := col + len(open)
+	body := inner
+	rest := elts
+	if _, ok := elts[0].(*Comment); !ok {
+		p.print(elts[0], inner)
+		rest = elts[1:]
+	}
+	if head, ok := elts[0].(*Symbol); ok && open == "(" {
+		pos := inner + utf8.RuneCountInString(head.Name) + 1
+		n, ok := p.style.Rules[head.Name]
+		if ok {
+			body = col + p.style.Indent
+			if head.Name == "let" && len(rest) > 1 {
+				if _, named := rest[0].(*Symbol); named {
+					// a named let keeps its bindings beside the name
+					n++
+				}
+			}
+		} else {
+			// align the remaining arguments under the first
+			n = 1
+			body = pos
+		}
+		for first := true; n > 0 && len(rest) > 0; n-- {
+			if _, ok := rest[0].(*Comment); ok {
+				break
+			}
+			// only the first argument may be broken across lines
+			if s, ok := p.flat(rest[0]); !first && (!ok || pos+utf8.RuneCountInString(s) > p.style.Width) {
+				break
+			}
+			first = false
+			p.buf.WriteString(" ")
+			p.print(rest[0], pos)
+			pos = p.column() + 1
+			rest = rest[1:]
+		}
+	}
+	p.printBody(rest, body, len(rest) < len(elts))
+	if _, ok := elts[len(elts)-1].(*Comment); ok {
+		p.newline(body)
+	}
+	p.buf.WriteString(close)
+}
+
+// printPairs writes elts two to a line at column col, as the
+// entries of a map. Comments take lines of their own.
+func (p *prettyPrinter) printPairs(elts []Node, col int) {
+	first, key := true, true
+	for _, elt := range elts {
+		c, comment := elt.(*Comment)
+		switch {
+		case comment && c.Trailing:
+			p.buf.WriteString(" ")
+		case !comment && !key:
+			p.buf.WriteString(" ")
+			p.print(elt, p.column())
+			key = true
+			continue
+		case !first:
+			p.newline(col)
+		}
+		first = false
+		p.print(elt, col)
+		if !comment {
+			key = false
+		}
+	}
+	if len(elts) > 0 {
+		if _, ok := elts[len(elts)-1].(*Comment); ok {
+			p.newline(col)
+		}
+	}
+}
+
+// printBody writes elts one per line at column col, starting on
+// a new line if more is set. Trailing comments stay where they were,
+// and a keyword such as #:export stays on the line with its argument.
+func (p *prettyPrinter) printBody(elts []Node, col int, more bool) {
+	for i, elt := range elts {
+		c, comment := elt.(*Comment)
+		switch {
+		case i == 0 && !more:
+		case comment && c.Trailing:
+			p.buf.WriteString(" ")
+		case i > 0 && !comment && isKeyword(elts[i-1]) && !isKeyword(elt):
+			p.buf.WriteString(" ")
+			p.print(elt, p.column())
+			continue
+		default:
+			p.newline(col)
+		}
+		p.print(elt, col)
+	}
+}
+
+// isKeyword reports whether x is a keyword argument name, #:name.
+func isKeyword(x Node) bool {
+	sym, ok := x.(*Symbol)
+	return ok && strings.HasPrefix(sym.Name, "#:")
+}
+
+// column returns the column at which the next byte will be written.
+func (p *prettyPrinter) column() int {
+	b := p.buf.Bytes()
+	i := bytes.LastIndexByte(b, '\n')
+	return utf8.RuneCount(b[i+1:])
+}
@@ -0,0 +1,56 @@
+package gos
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		style Style
+		want  string
+	}{
+		{
+			name:  "fits",
+			src:   "(func f #(x int) #(int)\n  (return x))",
+			style: DefaultStyle,
+			want:  "(func f #(x int) #(int) (return x))\n",
+		},
+		{
+			name:  "indent",
+			src:   "(define (f x) (display x) (newline))",
+			style: Style{Indent: 4, Width: 20, Rules: map[string]int{"define": 1}},
+			want:  "(define (f x)\n    (display x)\n    (newline))\n",
+		},
+		{
+			name:  "width",
+			src:   "(f alpha beta gamma)",
+			style: Style{Indent: 2, Width: 12},
+			want:  "(f alpha\n   beta\n   gamma)\n",
+		},
+		{
+			name:  "rule",
+			src:   "(with-open f path (read f) (close f))",
+			style: Style{Indent: 2, Width: 20, Rules: map[string]int{"with-open": 2}},
+			want:  "(with-open f path\n  (read f)\n  (close f))\n",
+		},
+		{
+			name:  "no rule",
+			src:   "(with-open f path (read f) (close f))",
+			style: Style{Indent: 2, Width: 20},
+			want:  "(with-open f\n           path\n           (read f)\n           (close f))\n",
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := Format(&buf, []byte(test.src), test.style); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
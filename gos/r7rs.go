@@ -0,0 +1,93 @@
+package gos
+
+// R7RS is a Backend that writes each package as an R7RS-small
+// library named (go elem...) after its import path. The package is
+// lowered with LowerR7RS, and its composite literals and untyped
+// field selections made into (composite T elts...) and (dot x f).
+// The library exports the names that Go exports, and imports
+// (scheme base) but for the names it defines itself, the library
+// of each imported package, and
+// (go builtin), which must define Go's predeclared functions and
+// whatever else Lower leaves to a runtime, such as dot, as syntax
+// where an operand is a name.
+var R7RS Backend = r7rsBackend{}
+
+type r7rsBackend struct{ gosBackend }
+
+func (r7rsBackend) Name() string { return "r7rs" }
+func (r7rsBackend) Ext() string  { return ".sld" }
+
+// builtinShadows holds the names that both (scheme base) and
+// (go builtin) export, with their Go meaning in the latter.
+var builtinShadows = []string{"append", "max", "min"}
+
+// r7rsBase holds the names (scheme base) exports that are Go
+// identifiers, which a library that defines one of them must not
+// import, as R7RS forbids redefining an imported binding.
+var r7rsBase = map[string]bool{
+	"abs": true, "and": true, "append": true, "apply": true,
+	"assoc": true, "assq": true, "assv": true, "begin": true,
+	"bytevector": true, "caar": true, "cadr": true, "car": true,
+	"cdar": true, "cddr": true, "cdr": true, "ceiling": true,
+	"cond": true, "cons": true, "define": true, "denominator": true,
+	"do": true, "error": true, "exact": true, "expt": true,
+	"features": true, "floor": true, "gcd": true, "guard": true,
+	"include": true, "inexact": true, "lambda": true, "lcm": true,
+	"length": true, "let": true, "list": true, "max": true,
+	"member": true, "memq": true, "memv": true, "min": true,
+	"modulo": true, "newline": true, "not": true, "numerator": true,
+	"or": true, "parameterize": true, "quasiquote": true,
+	"quote": true, "quotient": true, "raise": true,
+	"rationalize": true, "remainder": true, "reverse": true,
+	"round": true, "square": true, "string": true, "substring": true,
+	"truncate": true, "unless": true, "unquote": true,
+	"values": true, "vector": true, "when": true,
+}
+
+func (r7rsBackend) Package(pkg *List, importPath string) ([]Node, error) {
+	lib, err := newLibrary(pkg, importPath, LowerR7RS)
+	if err != nil {
+		return nil, err
+	}
+	base := form("except", form("scheme", symbol("base")))
+	excepted := make(map[string]bool)
+	for _, name := range builtinShadows {
+		base.Elts = append(base.Elts, symbol(name))
+		excepted[name] = true
+	}
+	for _, x := range lib.untyped() {
+		for _, name := range defined(x) {
+			if r7rsBase[name.String()] && !excepted[name.String()] {
+				base.Elts = append(base.Elts, name)
+				excepted[name.String()] = true
+			}
+		}
+	}
+	imports := form("import", base, libraryName(pkg.Position, "builtin"))
+	for _, spec := range lib.imports {
+		imports.Elts = append(imports.Elts, r7rsImportSet(spec))
+	}
+	body := append([]Node{symbol("begin")}, composites(lib.untyped())...)
+	return []Node{&List{Position: pkg.Position, Elts: []Node{
+		symbol("define-library"),
+		libraryName(pkg.Position, lib.path),
+		form("export", exports(lib.body)...),
+		imports,
+		&List{Position: pkg.Position, Elts: body},
+	}}}, nil
+}
+
+// r7rsImportSet returns the import set for spec: the library of
+// the package prefixed with the name Gos refers to it by, as
+// (prefix (go fmt) fmt.), unprefixed for a dot import, and with
+// nothing imported for a blank one.
+func r7rsImportSet(spec importSpec) Node {
+	name := libraryName(spec.pos, spec.path)
+	switch spec.alias {
+	case ".":
+		return name
+	case "_":
+		return form("only", name)
+	}
+	return form("prefix", name, symbol(spec.prefix()+"."))
+}
@@ -0,0 +1,578 @@
+package gos
+
+import (
+	"fmt"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// Racket is a Backend that writes each package as a #lang
+// racket/base module, found as go/elem....rkt in a collection
+// directory and required by the path go/elem.... The package is
+// lowered as for R7RS. Each struct type becomes a struct with
+// mutable fields, whose composite literals call its constructor
+// and whose field selections its accessors and mutators.
+// The module provides the names that Go exports and the exported
+// structs, and requires the module of each imported package and
+// go/builtin, a runtime as for R7RS whose bindings shadow those of
+// racket/base, such as append.
+var Racket Backend = racketBackend{}
+
+type racketBackend struct{ gosBackend }
+
+func (racketBackend) Name() string { return "racket" }
+func (racketBackend) Ext() string  { return ".rkt" }
+
+// Literal spells the characters and strings that R7RS escapes
+// with \x, and the characters it names alarm and escape, with
+// Racket's \u escapes, and bytevectors as (bytes ...).
+func (racketBackend) Literal(x Node) Node {
+	switch x := x.(type) {
+	case *Char:
+		if s := x.String(); s == `#\alarm` || s == `#\escape` || strings.HasPrefix(s, `#\x`) && s != `#\x` {
+			return &Symbol{Position: x.Position, Name: "#" + racketEscape(x.Value)}
+		}
+	case *String:
+		if s := racketString(x.Value); s != x.String() {
+			return &Symbol{Position: x.Position, Name: s}
+		}
+	case *Bytevector:
+		list := &List{Position: x.Position, Elts: []Node{&Symbol{Position: x.Position, Name: "bytes"}}}
+		for _, b := range x.Value {
+			list.Elts = append(list.Elts, &Number{Position: x.Position, Lit: strconv.Itoa(int(b))})
+		}
+		return list
+	}
+	return x
+}
+
+// racketString is quoteString with \u escapes for the characters
+// that it escapes with \x.
+func racketString(s string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, r := range s {
+		if q := quoteString(string(r)); !strings.HasPrefix(q, `"\x`) {
+			buf.WriteString(q[1 : len(q)-1])
+		} else {
+			buf.WriteString(racketEscape(r))
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// racketEscape returns the \u or \U escape for r.
+func racketEscape(r rune) string {
+	if r > 0xffff {
+		return fmt.Sprintf(`\U%08x`, r)
+	}
+	return fmt.Sprintf(`\u%04x`, r)
+}
+
+func (racketBackend) Package(pkg *List, importPath string) ([]Node, error) {
+	return racketPackage(pkg, importPath, false)
+}
+
+func (racketBackend) typedPackage(pkg *List, importPath string) ([]Node, error) {
+	return racketPackage(pkg, importPath, true)
+}
+
+// accessor names the accessor of the field of a struct T, T-field.
+func (racketBackend) accessor(typeName, field string) string {
+	return typeName + "-" + field
+}
+
+// racketPackage returns the module for pkg, whose field selections
+// the compiler has named by their accessors if typed is set.
+func racketPackage(pkg *List, importPath string, typed bool) ([]Node, error) {
+	lib, err := newLibrary(pkg, importPath, LowerNone)
+	if err != nil {
+		return nil, err
+	}
+	f := newRacketFields(lib.body)
+	f.typed = typed
+	var body []Node
+	for _, x := range lib.body {
+		if Head(x) == "type" {
+			body = append(body, x)
+			continue
+		}
+		for _, y := range f.decl(f.mark(x, f.bindings(x))) {
+			body = append(body, f.accessors(y))
+		}
+	}
+	require := form("require", racketModulePath(pkg.Position, "builtin"))
+	for _, spec := range lib.imports {
+		require.Elts = append(require.Elts, racketRequireSpec(spec))
+	}
+	provide := form("provide", exports(body)...)
+	out := []Node{symbol("#lang racket/base"), require, provide}
+	for _, x := range body {
+		if Head(x) != "type" {
+			out = append(out, constructors(composite(x, false), f.types))
+			continue
+		}
+		for _, spec := range typeSpecs(x.(*List)) {
+			if Head(spec.typ) == "struct" {
+				out = append(out, racketStruct(x.Pos(), spec.name, spec.typ.(*List)))
+				if isExported(spec.name) {
+					provide.Elts = append(provide.Elts, form("struct-out", symbol(spec.name)))
+				}
+			}
+		}
+	}
+	return out, f.diags.Err()
+}
+
+// racketModulePath returns the collection path go/elem... of the
+// module of the package with the given import path.
+func racketModulePath(pos token.Position, importPath string) Node {
+	return &Symbol{Position: pos, Name: libraryPath(importPath)}
+}
+
+// racketRequireSpec returns the require spec for spec: the module
+// of the package prefixed with the name Gos refers to it by, as
+// (prefix-in fmt. go/fmt), unprefixed for a dot import, and with
+// nothing imported for a blank one.
+func racketRequireSpec(spec importSpec) Node {
+	name := racketModulePath(spec.pos, spec.path)
+	switch spec.alias {
+	case ".":
+		return name
+	case "_":
+		return form("only-in", name)
+	}
+	return form("prefix-in", symbol(spec.prefix()+"."), name)
+}
+
+// racketStruct returns the struct definition for the struct type
+// named name.
+func racketStruct(pos token.Position, name string, typ *List) Node {
+	fields := &List{Position: pos}
+	for _, field := range fieldNames(typ) {
+		fields.Elts = append(fields.Elts, symbol(field))
+	}
+	return &List{Position: pos, Elts: []Node{
+		symbol("struct"), symbol(name), fields, symbol("#:mutable"), symbol("#:transparent"),
+	}}
+}
+
+// racketFields lowers the declarations of a package for Racket,
+// with each field selection (dot x f) whose x is known to be one
+// of the package's structs, or a pointer to one, turned into a
+// call of the struct's accessor, (T-f x), or mutator, (set-T-f! x
+// v). If the package was type-checked, the compiler has written
+// those selections as (dot x T-f) already. Otherwise the type of x
+// is known from the declarations of the package and of the function
+// the selection is in, and a selection of a field of one of the
+// structs from an x whose type is not known is reported, since the
+// dot of go/builtin cannot find the fields of Racket structs by
+// name.
+type racketFields struct {
+	*lowerer
+	typed   bool              // whether the package was type-checked
+	types   map[string]Node   // the package's types
+	fields  map[string]bool   // the names of the fields of its structs
+	globals map[string]Node   // the types of its variables, nil if not known
+	results map[string][]Node // the result types of its functions
+	marked  map[string]bool   // the accessors T-f of the marked selections
+
+	addressed map[*List]bool // the selections (adr (dot x f)) points to
+}
+
+func newRacketFields(body []Node) *racketFields {
+	f := &racketFields{
+		lowerer: &lowerer{level: LowerR7RS},
+		types:   make(map[string]Node),
+		fields:  make(map[string]bool),
+		globals: make(map[string]Node),
+		results: make(map[string][]Node),
+		marked:  make(map[string]bool),
+
+		addressed: make(map[*List]bool),
+	}
+	for _, x := range body {
+		switch Head(x) {
+		case "type":
+			for _, spec := range typeSpecs(x.(*List)) {
+				f.types[spec.name] = spec.typ
+				if Head(spec.typ) == "struct" {
+					for _, name := range fieldNames(spec.typ.(*List)) {
+						f.fields[name] = true
+					}
+				}
+			}
+		case "func":
+			if elts := x.(*List).Elts; len(elts) >= 4 {
+				if name, ok := elts[1].(*Symbol); ok {
+					f.results[name.Name] = resultTypes(elts[3])
+				}
+			}
+		}
+	}
+	for _, x := range body {
+		switch Head(x) {
+		case "var", "const":
+			f.bindSpecs(f.globals, x.(*List))
+		}
+	}
+	return f
+}
+
+// bindings returns the types of the variables the declaration x
+// can refer to: the package's, and those x binds, its parameters
+// included. A name bound to two types is not known to have either.
+func (f *racketFields) bindings(x Node) map[string]Node {
+	env := make(map[string]Node, len(f.globals))
+	for name, typ := range f.globals {
+		env[name] = typ
+	}
+	if Head(x) == "func" {
+		f.bindFunc(env, x.(*List))
+	}
+	return env
+}
+
+// bind records that name has type typ in env, or nil if typ is nil
+// or name already has another type.
+func (f *racketFields) bind(env map[string]Node, name Node, typ Node) {
+	sym, ok := name.(*Symbol)
+	if !ok || sym.Name == "_" {
+		return
+	}
+	if have, ok := env[sym.Name]; ok && (have == nil || typ == nil || have.String() != typ.String()) {
+		typ = nil
+	}
+	env[sym.Name] = typ
+}
+
+// bindVector binds the names of the field #(names... T) to T.
+func (f *racketFields) bindVector(env map[string]Node, x Node) {
+	if v, ok := x.(*Vector); ok && len(v.Elts) >= 2 {
+		for _, name := range v.Elts[:len(v.Elts)-1] {
+			f.bind(env, name, v.Elts[len(v.Elts)-1])
+		}
+	}
+}
+
+// bindSpecs binds the names of the (var ...) or (const ...) form x.
+func (f *racketFields) bindSpecs(env map[string]Node, x *List) {
+	for _, spec := range f.specs(x) {
+		for i, name := range spec.names {
+			typ := spec.typ
+			if typ == nil && len(spec.values) == len(spec.names) {
+				typ = f.typeOf(env, spec.values[i])
+			}
+			f.bind(env, name, typ)
+		}
+	}
+}
+
+// bindFunc binds the receiver, parameters and named results of the
+// (func ...) form x, and the variables its body declares.
+func (f *racketFields) bindFunc(env map[string]Node, x *List) {
+	for _, elt := range x.Elts[1:] {
+		switch elt := elt.(type) {
+		case *Vector:
+			// the receiver
+			f.bindVector(env, elt)
+		case *List:
+			// the parameters and results, then the body
+			if Head(elt) == "values" {
+				for _, result := range elt.Elts[1:] {
+					f.bindVector(env, result)
+				}
+				continue
+			}
+			for _, param := range elt.Elts {
+				f.bindVector(env, param)
+			}
+			f.bindStmt(env, elt)
+		}
+	}
+}
+
+// bindStmt binds the variables that x and the forms in it declare.
+func (f *racketFields) bindStmt(env map[string]Node, x Node) {
+	list, ok := x.(*List)
+	if !ok {
+		return
+	}
+	switch Head(list) {
+	case "func":
+		f.bindFunc(env, list)
+		return
+	case "var":
+		f.bindSpecs(env, list)
+	case ":=":
+		if len(list.Elts) == 3 && Head(list.Elts[1]) == "" {
+			f.bind(env, list.Elts[1], f.typeOf(env, list.Elts[2]))
+		} else if len(list.Elts) >= 2 {
+			targets := names(list.Elts[1])
+			var results []Node
+			if len(list.Elts) == 3 {
+				results = f.resultsOf(env, list.Elts[2])
+			}
+			for i, name := range targets {
+				var typ Node
+				if len(list.Elts)-2 == len(targets) {
+					typ = f.typeOf(env, list.Elts[2+i])
+				} else if len(results) == len(targets) {
+					typ = results[i]
+				}
+				f.bind(env, name, typ)
+			}
+		}
+	case "range":
+		if len(list.Elts) >= 2 && Head(list.Elts[1]) == ":=" && len(list.Elts[1].(*List).Elts) == 3 {
+			// the keys and elements are not the ranged value
+			for _, name := range names(list.Elts[1].(*List).Elts[1]) {
+				f.bind(env, name, nil)
+			}
+			for _, elt := range list.Elts[2:] {
+				f.bindStmt(env, elt)
+			}
+			return
+		}
+	}
+	for _, elt := range list.Elts {
+		f.bindStmt(env, elt)
+	}
+}
+
+// typeOf returns the type of the expression x, or nil if it is not
+// known.
+func (f *racketFields) typeOf(env map[string]Node, x Node) Node {
+	switch x := x.(type) {
+	case *Symbol:
+		return env[x.Name]
+	case *Vector:
+		if len(x.Elts) > 0 && !isSymbol(x.Elts[0], "_") {
+			return x.Elts[0]
+		}
+	case *List:
+		switch Head(x) {
+		case "adr":
+			if len(x.Elts) == 2 {
+				if typ := f.typeOf(env, x.Elts[1]); typ != nil {
+					return form("ptr", typ)
+				}
+			}
+		case "convert":
+			if len(x.Elts) == 3 {
+				return x.Elts[1]
+			}
+		case "dot":
+			if len(x.Elts) == 3 {
+				if name, path := f.path(f.typeOf(env, x.Elts[1]), x.Elts[2].String()); path != nil {
+					typ := f.types[name].(*List)
+					return fieldTypes(typ)[indexOf(fieldNames(typ), path[len(path)-1])]
+				}
+			}
+		default:
+			if results := f.resultsOf(env, x); len(results) == 1 {
+				return results[0]
+			}
+		}
+	}
+	return nil
+}
+
+// resultsOf returns the result types of x if it calls one of the
+// package's functions, and otherwise nil.
+func (f *racketFields) resultsOf(env map[string]Node, x Node) []Node {
+	list, ok := x.(*List)
+	if !ok || len(list.Elts) == 0 {
+		return nil
+	}
+	if sym, ok := list.Elts[0].(*Symbol); ok {
+		if _, local := env[sym.Name]; !local {
+			return f.results[sym.Name]
+		}
+	}
+	return nil
+}
+
+// resultTypes returns the types of the results of a function whose
+// result is typ: one type, or (values ...) of types and of named
+// results #(names... T).
+func resultTypes(typ Node) []Node {
+	if Head(typ) != "values" {
+		return []Node{typ}
+	}
+	var out []Node
+	for _, elt := range typ.(*List).Elts[1:] {
+		if v, ok := elt.(*Vector); ok && len(v.Elts) >= 2 {
+			for range v.Elts[:len(v.Elts)-1] {
+				out = append(out, v.Elts[len(v.Elts)-1])
+			}
+			continue
+		}
+		out = append(out, elt)
+	}
+	return out
+}
+
+// structName returns the name of the package's struct type that
+// typ is, or points to, or "" if it is neither.
+func (f *racketFields) structName(typ Node) string {
+	if Head(typ) == "ptr" && len(typ.(*List).Elts) == 2 {
+		typ = typ.(*List).Elts[1]
+	}
+	if sym, ok := typ.(*Symbol); ok && Head(f.types[sym.Name]) == "struct" {
+		return sym.Name
+	}
+	return ""
+}
+
+// path returns the fields that select the field name from a value
+// of type typ, the last of them in the struct named last, with the
+// embedded fields that promote it first. It returns nil if typ is
+// not one of the package's structs or a pointer to one, or it has
+// no such field, or more than one at the shallowest depth.
+func (f *racketFields) path(typ Node, name string) (last string, path []string) {
+	type step struct {
+		typ  string
+		path []string
+	}
+	level := []step{{typ: f.structName(typ)}}
+	seen := make(map[string]bool)
+	for len(level) > 0 && level[0].typ != "" {
+		var next []step
+		found := 0
+		for _, s := range level {
+			if seen[s.typ] {
+				continue
+			}
+			seen[s.typ] = true
+			st := f.types[s.typ].(*List)
+			names, types := fieldNames(st), fieldTypes(st)
+			for i, field := range names {
+				p := append(append([]string(nil), s.path...), field)
+				if field == name {
+					last, path = s.typ, p
+					found++
+				}
+				if t := f.structName(types[i]); t != "" && isEmbedded(st, i) {
+					next = append(next, step{t, p})
+				}
+			}
+		}
+		if found == 1 {
+			return last, path
+		} else if found > 1 {
+			return "", nil
+		}
+		level = next
+	}
+	return "", nil
+}
+
+// isEmbedded reports whether the i'th field of the (struct ...)
+// type x is an embedded one.
+func isEmbedded(x *List, i int) bool {
+	for _, field := range x.Elts[1:] {
+		switch field := field.(type) {
+		case *Comment:
+			continue
+		case *Vector:
+			if i < len(field.Elts)-1 {
+				return false
+			}
+			i -= len(field.Elts) - 1
+		default:
+			if i == 0 {
+				return true
+			}
+			i--
+		}
+	}
+	return false
+}
+
+// mark rewrites each field selection (dot x f) in x whose x is of
+// one of the package's structs into (dot x T-f), for accessors to
+// turn into a call once x has been lowered, and reports the others
+// that select a field of one of its structs, and the pointers to
+// the fields of its structs, which go/builtin cannot make.
+func (f *racketFields) mark(x Node, env map[string]Node) Node {
+	list, ok := x.(*List)
+	if !ok {
+		return x
+	}
+	if Head(list) == "adr" && len(list.Elts) == 2 {
+		if sel, ok := list.Elts[1].(*List); ok {
+			f.addressed[sel] = true
+		}
+	}
+	elts := make([]Node, len(list.Elts))
+	for i, elt := range list.Elts {
+		elts[i] = f.mark(elt, env)
+	}
+	out := &List{Position: list.Position, Elts: elts}
+	if Head(list) != "dot" || len(elts) != 3 {
+		return out
+	}
+	name, ok := elts[2].(*Symbol)
+	if !ok {
+		return out
+	}
+	if f.typed {
+		if i := strings.IndexByte(name.Name, '-'); i > 0 && f.structName(symbol(name.Name[:i])) != "" {
+			f.marked[name.Name] = true
+			if f.addressed[list] {
+				f.errorf(list, "cannot take the address of field %s of %s", name.Name[i+1:], name.Name[:i])
+			}
+		}
+		return out
+	}
+	typ := f.typeOf(env, list.Elts[1])
+	_, path := f.path(typ, name.Name)
+	switch {
+	case path == nil:
+		if typ == nil && f.fields[name.Name] {
+			f.errorf(list, "cannot tell the struct type of %s to select its field %s", list.Elts[1], name.Name)
+		}
+		return out
+	case f.addressed[list]:
+		f.errorf(list, "cannot take the address of field %s of %s", name.Name, f.structName(typ))
+		return out
+	}
+	sel, t := elts[1], f.structName(typ)
+	for _, field := range path {
+		accessor := t + "-" + field
+		f.marked[accessor] = true
+		sel = &List{Position: list.Position, Elts: []Node{elts[0], sel, &Symbol{Position: name.Position, Name: accessor}}}
+		st := f.types[t].(*List)
+		t = f.structName(fieldTypes(st)[indexOf(fieldNames(st), field)])
+	}
+	return sel
+}
+
+// accessors rewrites the marked selections (dot x T-f) in the
+// lowered x into (T-f x), and (dot-set! x T-f v) into
+// (set-T-f! x v).
+func (f *racketFields) accessors(x Node) Node {
+	list, ok := x.(*List)
+	if !ok {
+		return x
+	}
+	elts := make([]Node, len(list.Elts))
+	for i, elt := range list.Elts {
+		elts[i] = f.accessors(elt)
+	}
+	switch Head(list) {
+	case "dot":
+		if len(elts) == 3 && f.marked[elts[2].String()] {
+			return &List{Position: list.Position, Elts: []Node{elts[2], elts[1]}}
+		}
+	case "dot-set!":
+		if len(elts) == 4 && f.marked[elts[2].String()] {
+			set := &Symbol{Position: list.Position, Name: "set-" + elts[2].String() + "!"}
+			return &List{Position: list.Position, Elts: []Node{set, elts[1], elts[3]}}
+		}
+	}
+	return &List{Position: list.Position, Elts: elts}
+}
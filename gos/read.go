@@ -0,0 +1,337 @@
+package gos
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode controls optional behavior of Read.
+type Mode uint
+
+const (
+	// ReadComments keeps ;; comments in the tree as Comment nodes.
+	ReadComments Mode = 1 << iota
+)
+
+// Read parses the Gos forms in src, which is named filename in
+// positions and diagnostics. Errors are returned as Diagnostics.
+func Read(filename string, src []byte, mode Mode) ([]Node, error) {
+	r := &reader{
+		src:   src,
+		mode:  mode,
+		pos:   token.Position{Filename: filename, Line: 1, Column: 1},
+		fresh: true,
+	}
+	var forms []Node
+	for {
+		r.skipSpace()
+		if r.eof() {
+			break
+		}
+		if r.peek() == ')' {
+			r.errorf(r.pos, "unexpected )")
+			r.next()
+			continue
+		}
+		forms = append(forms, r.read())
+	}
+	return forms, r.diags.Err()
+}
+
+type reader struct {
+	src   []byte
+	mode  Mode
+	pos   token.Position // position of src[pos.Offset]
+	fresh bool           // nothing but space since the last newline
+	diags Diagnostics
+}
+
+func (r *reader) errorf(pos token.Position, format string, params ...interface{}) {
+	r.diags = append(r.diags, Diagnostic{pos, fmt.Sprintf(format, params...)})
+}
+
+func (r *reader) eof() bool {
+	return r.pos.Offset >= len(r.src)
+}
+
+func (r *reader) peek() rune {
+	ch, _ := utf8.DecodeRune(r.src[r.pos.Offset:])
+	return ch
+}
+
+func (r *reader) next() rune {
+	ch, size := utf8.DecodeRune(r.src[r.pos.Offset:])
+	r.pos.Offset += size
+	if ch == '\n' {
+		r.pos.Line++
+		r.pos.Column = 1
+		r.fresh = true
+	} else {
+		r.pos.Column++
+	}
+	return ch
+}
+
+func (r *reader) hasPrefix(prefix string) bool {
+	return bytes.HasPrefix(r.src[r.pos.Offset:], []byte(prefix))
+}
+
+// skipSpace skips white space, and comments unless they are kept.
+func (r *reader) skipSpace() {
+	for !r.eof() {
+		switch r.peek() {
+		case ' ', '\t', '\r', '\n', '\f':
+			r.next()
+		case ';':
+			if r.mode&ReadComments != 0 {
+				return
+			}
+			for !r.eof() && r.peek() != '\n' {
+				r.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isDelimiter(ch rune) bool {
+	switch ch {
+	case ' ', '\t', '\r', '\n', '\f', '(', ')', '"', ';':
+		return true
+	}
+	return false
+}
+
+// token reads up to the next delimiter.
+func (r *reader) token() string {
+	start := r.pos.Offset
+	for !r.eof() && !isDelimiter(r.peek()) {
+		r.next()
+	}
+	return string(r.src[start:r.pos.Offset])
+}
+
+// read returns the datum or comment at the current position,
+// which is neither white space nor the end of the input.
+func (r *reader) read() Node {
+	pos := r.pos
+	switch {
+	case r.peek() == ';':
+		trailing := !r.fresh
+		start := r.pos.Offset
+		for !r.eof() && r.peek() != '\n' {
+			r.next()
+		}
+		text := strings.TrimRight(string(r.src[start:r.pos.Offset]), " \t\r")
+		return &Comment{Position: pos, Text: text, Trailing: trailing}
+	case r.peek() == '(':
+		r.next()
+		r.fresh = false
+		return &List{Position: pos, Elts: r.readList(pos)}
+	case r.hasPrefix("#u8("):
+		for i := 0; i < 4; i++ {
+			r.next()
+		}
+		r.fresh = false
+		return r.readBytevector(pos)
+	case r.hasPrefix("#("):
+		r.next()
+		r.next()
+		r.fresh = false
+		return &Vector{Position: pos, Elts: r.readList(pos)}
+	}
+	r.fresh = false
+	switch {
+	case r.peek() == '"':
+		return r.readString()
+	case r.hasPrefix(`#\`):
+		return r.readChar()
+	}
+
+	text := r.token()
+	x := atom(pos, text)
+	if _, ok := x.(*Symbol); ok && strings.HasPrefix(text, "#") {
+		r.errorf(pos, "unknown syntax %s", text)
+	}
+	return x
+}
+
+// atom returns the node for the token text read at pos: a
+// boolean, %nil, a number, or otherwise a symbol.
+func atom(pos token.Position, text string) Node {
+	switch {
+	case text == "#t" || text == "#true":
+		return &Bool{Position: pos, Value: true}
+	case text == "#f" || text == "#false":
+		return &Bool{Position: pos, Value: false}
+	case text == "%nil":
+		return &Nil{Position: pos}
+	case isNumber(text):
+		return &Number{Position: pos, Lit: text}
+	}
+	return &Symbol{Position: pos, Name: text}
+}
+
+// readList reads elements up to the closing parenthesis of the
+// list that was opened at pos.
+func (r *reader) readList(pos token.Position) []Node {
+	elts := []Node{}
+	for {
+		r.skipSpace()
+		if r.eof() {
+			r.errorf(pos, "missing )")
+			return elts
+		}
+		if r.peek() == ')' {
+			r.next()
+			return elts
+		}
+		elts = append(elts, r.read())
+	}
+}
+
+// readBytevector reads the bytes of a #u8( opened at pos.
+func (r *reader) readBytevector(pos token.Position) Node {
+	x := &Bytevector{Position: pos, Value: []byte{}}
+	for _, elt := range r.readList(pos) {
+		n, ok := elt.(*Number)
+		if !ok {
+			r.errorf(elt.Pos(), "invalid byte %s", elt)
+			continue
+		}
+		b, err := strconv.ParseUint(n.Lit, 10, 8)
+		if err != nil {
+			r.errorf(elt.Pos(), "invalid byte %s", elt)
+		}
+		x.Value = append(x.Value, byte(b))
+	}
+	return x
+}
+
+func (r *reader) readString() Node {
+	pos := r.pos
+	r.next()
+	var buf strings.Builder
+	for {
+		if r.eof() {
+			r.errorf(pos, "unterminated string")
+			break
+		}
+		ch := r.next()
+		if ch == '"' {
+			break
+		}
+		if ch != '\\' {
+			buf.WriteRune(ch)
+			continue
+		}
+		if r.eof() {
+			continue
+		}
+		esc := r.pos
+		switch ch = r.next(); ch {
+		case 'a':
+			buf.WriteByte('\a')
+		case 'b':
+			buf.WriteByte('\b')
+		case 't':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case '0':
+			buf.WriteByte(0)
+		case '"', '\\', '|':
+			buf.WriteRune(ch)
+		case 'x':
+			start := r.pos.Offset
+			for !r.eof() && isHex(r.peek()) {
+				r.next()
+			}
+			n, err := strconv.ParseUint(string(r.src[start:r.pos.Offset]), 16, 32)
+			if err != nil || n > utf8.MaxRune {
+				r.errorf(esc, "invalid \\x escape")
+			}
+			if !r.eof() && r.peek() == ';' {
+				r.next()
+			}
+			buf.WriteRune(rune(n))
+		case ' ', '\t', '\n':
+			// line continuation: \ space* newline space*
+			for ch != '\n' && !r.eof() && (r.peek() == ' ' || r.peek() == '\t') {
+				r.next()
+			}
+			if ch != '\n' && !r.eof() && r.peek() == '\n' {
+				r.next()
+			} else if ch != '\n' {
+				r.errorf(esc, "invalid line continuation")
+			}
+			for !r.eof() && (r.peek() == ' ' || r.peek() == '\t') {
+				r.next()
+			}
+		default:
+			r.errorf(esc, "unknown escape \\%c", ch)
+		}
+	}
+	return &String{Position: pos, Value: buf.String()}
+}
+
+func (r *reader) readChar() Node {
+	pos := r.pos
+	r.next()
+	r.next()
+	if r.eof() {
+		r.errorf(pos, "missing character after #\\")
+		return &Char{Position: pos}
+	}
+	// the first character may itself be a delimiter
+	first := r.next()
+	rest := r.token()
+	if rest == "" {
+		return &Char{Position: pos, Value: first}
+	}
+	name := string(first) + rest
+	if ch, ok := charNames[name]; ok {
+		return &Char{Position: pos, Value: ch}
+	}
+	if first == 'x' {
+		if n, err := strconv.ParseUint(rest, 16, 32); err == nil && n <= utf8.MaxRune {
+			return &Char{Position: pos, Value: rune(n)}
+		}
+	}
+	if n, err := strconv.ParseUint(name, 8, 32); err == nil {
+		return &Char{Position: pos, Value: rune(n)}
+	}
+	r.errorf(pos, "unknown character #\\%s", name)
+	return &Char{Position: pos, Value: first}
+}
+
+func isHex(ch rune) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+// isNumber reports whether text is spelled like a number rather
+// than a symbol: it starts with a digit, with a sign or point
+// followed by a digit, or with a radix or exactness prefix.
+func isNumber(text string) bool {
+	if len(text) >= 2 && text[0] == '#' {
+		return strings.ContainsRune("xXoObBdDeEiI", rune(text[1]))
+	}
+	if text == "" {
+		return false
+	}
+	if '0' <= text[0] && text[0] <= '9' {
+		return true
+	}
+	if text[0] == '+' || text[0] == '-' || text[0] == '.' {
+		rest := strings.TrimPrefix(text[1:], ".")
+		return rest != "" && '0' <= rest[0] && rest[0] <= '9'
+	}
+	return false
+}
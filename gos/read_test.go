@@ -0,0 +1,56 @@
+package gos
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	src := "(package p\n  (when #t (f #(x &int) \"a\\n\\x41;\" #\\space #\\x41 #\\( 1.5 -2 %nil #f)))"
+	forms, err := Read("t.gos", []byte(src), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forms) != 1 {
+		t.Fatalf("got %d forms, want 1", len(forms))
+	}
+	want := `(package p (when #t (f #(x &int) "a\nA" #\space #\A #\( 1.5 -2 %nil #f)))`
+	if got := forms[0].String(); got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+
+	when := forms[0].(*List).Elts[2].(*List)
+	if pos := when.Pos(); pos.Line != 2 || pos.Column != 3 {
+		t.Errorf("when at %v, want 2:3", pos)
+	}
+	call := when.Elts[2].(*List)
+	types := []string{"*gos.Symbol", "*gos.Vector", "*gos.String", "*gos.Char", "*gos.Char",
+		"*gos.Char", "*gos.Number", "*gos.Number", "*gos.Nil", "*gos.Bool"}
+	for i, elt := range call.Elts {
+		if got := fmt.Sprintf("%T", elt); i >= len(types) || got != types[i] {
+			t.Errorf("element %d is %s", i, got)
+		}
+	}
+}
+
+func TestReadComments(t *testing.T) {
+	src := ";; doc\n(type T ;; trailing\n  &int)"
+	forms, err := Read("", []byte(src), ReadComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := forms[0].(*Comment); !ok || c.Text != ";; doc" || c.Trailing {
+		t.Errorf("forms[0] = %#v, want leading comment", forms[0])
+	}
+	if c, ok := forms[1].(*List).Elts[2].(*Comment); !ok || !c.Trailing {
+		t.Errorf("type form = %v, want trailing comment", forms[1])
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, src := range []string{"(a (b)", "a)", `"abc`, `#\bogus`, `"\q"`, "#z"} {
+		if _, err := Read("e.gos", []byte(src), 0); err == nil {
+			t.Errorf("Read(%q) succeeded, want error", src)
+		}
+	}
+}
@@ -0,0 +1,999 @@
+package gos
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ReverseTranslate reads the Gos (package ...) forms in src and
+// writes each of them to wr as gofmt-formatted Go source.
+func ReverseTranslate(wr io.Writer, filename string, src []byte) error {
+	forms, err := Read(filename, src, 0)
+	if err != nil {
+		return err
+	}
+	var diags Diagnostics
+	for i, form := range forms {
+		file, err := ToGo(form)
+		if list, ok := err.(Diagnostics); ok {
+			diags = append(diags, list...)
+		} else if err != nil {
+			return err
+		}
+		if file == nil {
+			continue
+		}
+		if i > 0 {
+			io.WriteString(wr, "\n")
+		}
+		if err := printFile(wr, file); err != nil {
+			return err
+		}
+	}
+	return diags.Err()
+}
+
+// printFile formats file one declaration at a time, since the
+// rebuilt tree has no positions to place doc comments by.
+func printFile(wr io.Writer, file *ast.File) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s\n", file.Name.Name)
+	fset := token.NewFileSet()
+	fset.AddFile("", int(oneLine), 1)
+	for _, decl := range file.Decls {
+		buf.WriteString("\n")
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Doc != nil {
+			for _, c := range fd.Doc.List {
+				buf.WriteString(c.Text + "\n")
+			}
+			nodoc := *fd
+			nodoc.Doc = nil
+			decl = &nodoc
+		}
+		if err := format.Node(&buf, fset, decl); err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = wr.Write(src)
+	return err
+}
+
+// ToGo rebuilds the Go syntax tree of a (package ...) form, as
+// written by Translate. Forms that cannot be understood are
+// reported in the returned Diagnostics, positioned in the Gos input.
+func ToGo(form Node) (*ast.File, error) {
+	b := &goBuilder{}
+	file := b.file(form)
+	return file, b.diags.Err()
+}
+
+// schemeIdTable is the inverse of goIdTable.
+var schemeIdTable = func() map[string]string {
+	table := make(map[string]string)
+	for name, id := range goIdTable {
+		table[id] = name
+	}
+	return table
+}()
+
+// schemeOpTable maps the Gos spelling of an operator back to Go.
+var schemeOpTable = map[string]token.Token{
+	"and":              token.LAND,
+	"or":               token.LOR,
+	"not":              token.NOT,
+	"adr":              token.AND,
+	"bitwise-and":      token.AND,
+	"bitwise-or":       token.OR,
+	"bitwise-xor":      token.XOR,
+	"bitwise-but":      token.AND_NOT,
+	"bitwise-not":      token.XOR,
+	"quotient":         token.QUO,
+	"remainder":        token.REM,
+	"arithmetic-shift": token.SHL,
+	"=":                token.EQL,
+	"eqv?":             token.EQL,
+	"equal?":           token.EQL,
+	"string=?":         token.EQL,
+	"string<?":         token.LSS,
+	"string<=?":        token.LEQ,
+	"string>?":         token.GTR,
+	"string>=?":        token.GEQ,
+	"string-append":    token.ADD,
+}
+
+// goTokens maps operator spellings to Go tokens.
+var goTokens = func() map[string]token.Token {
+	table := make(map[string]token.Token)
+	for tok := token.ADD; tok <= token.TILDE; tok++ {
+		if tok.IsOperator() {
+			table[tok.String()] = tok
+		}
+	}
+	return table
+}()
+
+func schemeIdToGoId(name string) string {
+	if id, ok := schemeIdTable[name]; ok {
+		return id
+	}
+	return MangleName(name)
+}
+
+// oneLine is the position given to the braces of empty bodies,
+// and to the func keyword of declarations with one, so that they
+// print as {} rather than across two lines. printFile resolves it
+// to line 1.
+const oneLine token.Pos = 1
+
+type goBuilder struct {
+	diags Diagnostics
+}
+
+func (b *goBuilder) errorf(x Node, format string, params ...interface{}) {
+	b.diags = append(b.diags, Diagnostic{x.Pos(), fmt.Sprintf(format, params...)})
+}
+
+// args returns the elements of x after its head, checking that
+// there are at least min of them.
+func (b *goBuilder) args(x Node, min int) []Node {
+	list, ok := x.(*List)
+	if !ok || len(list.Elts) < min+1 {
+		b.errorf(x, "malformed %s form", Head(x))
+		elts := make([]Node, min)
+		for i := range elts {
+			elts[i] = &Symbol{Position: x.Pos(), Name: "_"}
+		}
+		return elts
+	}
+	return list.Elts[1:]
+}
+
+func (b *goBuilder) ident(x Node) *ast.Ident {
+	if sym, ok := x.(*Symbol); ok {
+		return ast.NewIdent(schemeIdToGoId(sym.Name))
+	}
+	if x != nil {
+		b.errorf(x, "expected identifier, found %s", x)
+	}
+	return ast.NewIdent("_")
+}
+
+func (b *goBuilder) file(x Node) *ast.File {
+	if Head(x) != "package" {
+		b.errorf(x, "expected (package ...), found %s", x)
+		return nil
+	}
+	elts := b.args(x, 1)
+	file := &ast.File{Name: b.ident(elts[0])}
+	for _, elt := range elts[1:] {
+		if decl := b.decl(elt); decl != nil {
+			file.Decls = append(file.Decls, decl)
+		}
+	}
+	return file
+}
+
+func (b *goBuilder) decl(x Node) ast.Decl {
+	switch head := Head(x); head {
+	case "import":
+		decl := &ast.GenDecl{Tok: token.IMPORT, Lparen: 1}
+		for _, spec := range b.args(x, 0) {
+			decl.Specs = append(decl.Specs, b.importSpec(spec))
+		}
+		if len(decl.Specs) == 1 {
+			decl.Lparen = token.NoPos
+		}
+		return decl
+	case "const", "var":
+		tok := token.CONST
+		if head == "var" {
+			tok = token.VAR
+		}
+		decl := &ast.GenDecl{Tok: tok}
+		for _, spec := range b.args(x, 0) {
+			decl.Specs = append(decl.Specs, b.valueSpec(spec))
+		}
+		if len(decl.Specs) != 1 {
+			decl.Lparen = 1
+		}
+		return decl
+	case "type":
+		decl := &ast.GenDecl{Tok: token.TYPE}
+		elts := b.args(x, 0)
+		for i := 0; i+1 < len(elts); i += 2 {
+			spec := &ast.TypeSpec{Type: b.typ(elts[i+1])}
+			spec.Name, spec.TypeParams = b.typeParams(elts[i])
+			decl.Specs = append(decl.Specs, spec)
+		}
+		if len(elts)%2 != 0 {
+			b.errorf(x, "type form needs name and type pairs")
+		}
+		if len(decl.Specs) != 1 {
+			decl.Lparen = 1
+		}
+		return decl
+	case "func", "func...":
+		return b.funcDecl(x.(*List))
+	}
+	b.errorf(x, "expected declaration, found %s", x)
+	return nil
+}
+
+func (b *goBuilder) importSpec(x Node) ast.Spec {
+	spec := &ast.ImportSpec{}
+	switch Head(x) {
+	case "as":
+		elts := b.args(x, 2)
+		spec.Name = b.ident(elts[0])
+		x = elts[1]
+	case "dot":
+		spec.Name = ast.NewIdent(".")
+		x = b.args(x, 1)[0]
+	}
+	spec.Path = b.basicLit(x)
+	return spec
+}
+
+// valueSpec rebuilds name, #(names... type) or (= lhs values...).
+func (b *goBuilder) valueSpec(x Node) ast.Spec {
+	spec := &ast.ValueSpec{}
+	lhs := x
+	if Head(x) == "=" {
+		elts := b.args(x, 2)
+		lhs = elts[0]
+		spec.Values = b.exprs(elts[1:])
+	}
+	switch a := lhs.(type) {
+	case *Vector:
+		if len(a.Elts) < 2 {
+			b.errorf(a, "typed names need a type")
+			break
+		}
+		for _, name := range a.Elts[:len(a.Elts)-1] {
+			spec.Names = append(spec.Names, b.ident(name))
+		}
+		spec.Type = b.typ(a.Elts[len(a.Elts)-1])
+	case *List:
+		for _, name := range a.Elts {
+			spec.Names = append(spec.Names, b.ident(name))
+		}
+	default:
+		spec.Names = []*ast.Ident{b.ident(lhs)}
+	}
+	return spec
+}
+
+// typeParams splits a declared name, which may be written
+// (generic name #(params... constraint) ...), into its parts.
+func (b *goBuilder) typeParams(x Node) (*ast.Ident, *ast.FieldList) {
+	if Head(x) != "generic" {
+		return b.ident(x), nil
+	}
+	elts := b.args(x, 1)
+	return b.ident(elts[0]), b.fields(elts[1:])
+}
+
+// funcDecl rebuilds (func[...] [recv] name (params) result body...).
+// A receiver is a typed name, or a type followed by a plain name
+// rather than a parameter list.
+func (b *goBuilder) funcDecl(x *List) ast.Decl {
+	elts := b.args(x, 3)
+	decl := &ast.FuncDecl{}
+	_, isVector := elts[0].(*Vector)
+	_, isName := elts[1].(*Symbol)
+	if isVector || isName {
+		decl.Recv = b.fields(elts[:1])
+		if elts = elts[1:]; len(elts) < 3 {
+			b.errorf(x, "malformed %s form", Head(x))
+			return nil
+		}
+	}
+	name, params := b.typeParams(elts[0])
+	decl.Name = name
+	decl.Type = b.funcType(Head(x) == "func...", elts[1], elts[2])
+	decl.Type.TypeParams = params
+	body := elts[3:]
+	if len(body) > 0 {
+		if doc, ok := body[0].(*String); ok {
+			decl.Doc = docComment(doc.Value)
+			body = body[1:]
+		}
+	}
+	decl.Body = b.block(body)
+	if len(body) == 0 {
+		decl.Type.Func = oneLine
+	}
+	return decl
+}
+
+func docComment(text string) *ast.CommentGroup {
+	group := &ast.CommentGroup{}
+	for _, line := range strings.Split(text, "\n") {
+		group.List = append(group.List, &ast.Comment{Text: strings.TrimRight("// "+line, " ")})
+	}
+	return group
+}
+
+// funcType rebuilds a function signature from its parameter list
+// and result, which is &void, a single field or (values fields...).
+func (b *goBuilder) funcType(variadic bool, params, result Node) *ast.FuncType {
+	list, ok := params.(*List)
+	if !ok {
+		b.errorf(params, "expected parameter list, found %s", params)
+		list = &List{}
+	}
+	ft := &ast.FuncType{Params: b.fields(list.Elts)}
+	if variadic && len(ft.Params.List) > 0 {
+		last := ft.Params.List[len(ft.Params.List)-1]
+		last.Type = &ast.Ellipsis{Elt: last.Type}
+	}
+	switch {
+	case isSymbol(result, "&void"):
+	case Head(result) == "values":
+		ft.Results = b.fields(b.args(result, 0))
+	default:
+		ft.Results = b.fields([]Node{result})
+	}
+	return ft
+}
+
+func isSymbol(x Node, name string) bool {
+	sym, ok := x.(*Symbol)
+	return ok && sym.Name == name
+}
+
+// braced marks an empty struct or interface body as one line.
+func (b *goBuilder) braced(list *ast.FieldList) *ast.FieldList {
+	if len(list.List) == 0 {
+		list.Opening, list.Closing = oneLine, oneLine
+	}
+	return list
+}
+
+// fields rebuilds a field list from #(names... type) and bare types.
+func (b *goBuilder) fields(elts []Node) *ast.FieldList {
+	list := &ast.FieldList{}
+	for _, elt := range elts {
+		field := &ast.Field{}
+		if v, ok := elt.(*Vector); ok {
+			if len(v.Elts) < 2 {
+				b.errorf(v, "field needs a name and type")
+				continue
+			}
+			for _, name := range v.Elts[:len(v.Elts)-1] {
+				field.Names = append(field.Names, b.ident(name))
+			}
+			elt = v.Elts[len(v.Elts)-1]
+		}
+		field.Type = b.typ(elt)
+		list.List = append(list.List, field)
+	}
+	return list
+}
+
+func (b *goBuilder) stmts(elts []Node) []ast.Stmt {
+	var list []ast.Stmt
+	for _, elt := range elts {
+		list = append(list, b.stmt(elt))
+	}
+	return list
+}
+
+func (b *goBuilder) stmt(x Node) ast.Stmt {
+	if v, ok := x.(*Bool); ok && !v.Value {
+		return &ast.EmptyStmt{Implicit: true}
+	}
+	switch head := Head(x); head {
+	case ":=", "=":
+		elts := b.args(x, 2)
+		return &ast.AssignStmt{Lhs: b.targets(elts[0]), Tok: goTokens[head], Rhs: b.exprs(elts[1:])}
+	case "++", "--":
+		return &ast.IncDecStmt{X: b.expr(b.args(x, 1)[0]), Tok: goTokens[head]}
+	case "when", "unless", "when*", "unless*":
+		return b.ifStmt(x.(*List))
+	case "while":
+		elts := b.args(x, 1)
+		return &ast.ForStmt{Cond: b.optExpr(elts[0], true), Body: b.block(elts[1:])}
+	case "for":
+		elts := b.args(x, 3)
+		return &ast.ForStmt{
+			Init: b.optStmt(elts[0]),
+			Cond: b.optExpr(elts[1], true),
+			Post: b.optStmt(elts[2]),
+			Body: b.block(elts[3:]),
+		}
+	case "range":
+		return b.rangeStmt(x)
+	case "cond!", "cond!*", "case!", "case!*":
+		return b.switchStmt(x.(*List))
+	case "type!", "type!*":
+		return b.typeSwitchStmt(x.(*List))
+	case "comm!":
+		sel := &ast.SelectStmt{Body: &ast.BlockStmt{}}
+		for _, clause := range b.args(x, 0) {
+			list, ok := clause.(*List)
+			if !ok || len(list.Elts) == 0 {
+				b.errorf(clause, "malformed comm! clause")
+				continue
+			}
+			cc := &ast.CommClause{Body: b.stmts(list.Elts[1:])}
+			if !isSymbol(list.Elts[0], "else") {
+				cc.Comm = b.stmt(list.Elts[0])
+			}
+			sel.Body.List = append(sel.Body.List, cc)
+		}
+		return sel
+	case "go":
+		return &ast.GoStmt{Call: b.call(b.args(x, 1)[0])}
+	case "defer":
+		return &ast.DeferStmt{Call: b.call(b.args(x, 1)[0])}
+	case "return":
+		return &ast.ReturnStmt{Results: b.exprs(b.args(x, 0))}
+	case "break", "continue", "goto", "fallthrough":
+		stmt := &ast.BranchStmt{Tok: token.Lookup(head)}
+		if elts := b.args(x, 0); len(elts) > 0 {
+			stmt.Label = b.ident(elts[0])
+		}
+		return stmt
+	case "label":
+		elts := b.args(x, 2)
+		return &ast.LabeledStmt{Label: b.ident(elts[0]), Stmt: b.stmt(elts[1])}
+	case "<-!":
+		elts := b.args(x, 2)
+		return &ast.SendStmt{Chan: b.expr(elts[0]), Value: b.expr(elts[1])}
+	case "const", "var", "type":
+		return &ast.DeclStmt{Decl: b.decl(x)}
+	}
+	if tok, ok := b.assignOp(Head(x)); ok {
+		elts := b.args(x, 2)
+		if n, ok := negated(elts[1]); ok && tok == token.SHL_ASSIGN {
+			tok, elts = token.SHR_ASSIGN, []Node{elts[0], n}
+		}
+		return &ast.AssignStmt{Lhs: b.targets(elts[0]), Tok: tok, Rhs: b.exprs(elts[1:])}
+	}
+	return &ast.ExprStmt{X: b.expr(x)}
+}
+
+// assignOp recognizes compound assignments such as += and
+// bitwise-or=, returning the Go assignment token.
+func (b *goBuilder) assignOp(head string) (token.Token, bool) {
+	if !strings.HasSuffix(head, "=") || len(head) < 2 {
+		return token.ILLEGAL, false
+	}
+	op, ok := b.binaryOp(strings.TrimSuffix(head, "="))
+	if !ok {
+		return token.ILLEGAL, false
+	}
+	tok, ok := goTokens[op.String()+"="]
+	return tok, ok && tok != token.EQL && tok != token.LEQ && tok != token.GEQ && tok != token.NEQ
+}
+
+func (b *goBuilder) binaryOp(name string) (token.Token, bool) {
+	if tok, ok := schemeOpTable[name]; ok && name != "not" && name != "adr" && name != "bitwise-not" {
+		return tok, true
+	}
+	switch name {
+	case "+", "-", "*", "/", "%", "<<", ">>", "==", "!=", "<", "<=", ">", ">=":
+		return goTokens[name], true
+	}
+	return token.ILLEGAL, false
+}
+
+// negated returns n if x is (- n).
+func negated(x Node) (Node, bool) {
+	if list, ok := x.(*List); ok && Head(x) == "-" && len(list.Elts) == 2 {
+		return list.Elts[1], true
+	}
+	return nil, false
+}
+
+// targets rebuilds the left-hand side of an assignment: a name,
+// a (dot x f) selector, or a list of expressions.
+func (b *goBuilder) targets(x Node) []ast.Expr {
+	if list, ok := x.(*List); ok && Head(x) != "dot" {
+		return b.exprs(list.Elts)
+	}
+	return []ast.Expr{b.expr(x)}
+}
+
+func (b *goBuilder) block(elts []Node) *ast.BlockStmt {
+	block := &ast.BlockStmt{List: b.stmts(elts)}
+	if len(block.List) == 0 {
+		block.Lbrace, block.Rbrace = oneLine, oneLine
+	}
+	return block
+}
+
+// optStmt treats #f as an absent statement.
+func (b *goBuilder) optStmt(x Node) ast.Stmt {
+	if v, ok := x.(*Bool); ok && !v.Value {
+		return nil
+	}
+	return b.stmt(x)
+}
+
+// optExpr treats #f, or #t if always is set, as an absent expression.
+func (b *goBuilder) optExpr(x Node, always bool) ast.Expr {
+	if v, ok := x.(*Bool); ok && v.Value == always {
+		return nil
+	}
+	return b.expr(x)
+}
+
+// ifStmt rebuilds when, unless, when* and unless*, with an
+// optional trailing (else ...) clause.
+func (b *goBuilder) ifStmt(x *List) *ast.IfStmt {
+	head := Head(x)
+	stmt := &ast.IfStmt{}
+	elts := b.args(x, 1)
+	if strings.HasSuffix(head, "*") {
+		elts = b.args(x, 2)
+		stmt.Init = b.stmt(elts[0])
+		elts = elts[1:]
+	}
+	stmt.Cond = b.expr(elts[0])
+	if strings.HasPrefix(head, "unless") {
+		stmt.Cond = &ast.UnaryExpr{Op: token.NOT, X: operand(stmt.Cond, token.UnaryPrec)}
+	}
+	body := elts[1:]
+	if n := len(body); n > 0 && Head(body[n-1]) == "else" {
+		alt := b.args(body[n-1], 0)
+		body = body[:n-1]
+		stmt.Else = b.block(alt)
+		if len(alt) == 1 {
+			switch Head(alt[0]) {
+			case "when", "unless", "when*", "unless*":
+				stmt.Else = b.ifStmt(alt[0].(*List))
+			}
+		}
+	}
+	stmt.Body = b.block(body)
+	return stmt
+}
+
+func (b *goBuilder) rangeStmt(x Node) ast.Stmt {
+	elts := b.args(x, 1)
+	stmt := &ast.RangeStmt{Body: b.block(elts[1:])}
+	spec := elts[0]
+	head := Head(spec)
+	if head != ":=" && head != "=" {
+		stmt.X = b.expr(spec)
+		return stmt
+	}
+	parts := b.args(spec, 2)
+	stmt.Tok = goTokens[head]
+	stmt.X = b.expr(parts[1])
+	if list, ok := parts[0].(*List); ok {
+		keys := b.exprs(list.Elts)
+		if len(keys) != 2 {
+			b.errorf(list, "range needs a key and value")
+			return stmt
+		}
+		stmt.Key, stmt.Value = keys[0], keys[1]
+	} else {
+		stmt.Key = b.expr(parts[0])
+	}
+	return stmt
+}
+
+// switchStmt rebuilds (cond! clauses...) and (case! tag clauses...),
+// and their starred forms which take an init statement first.
+func (b *goBuilder) switchStmt(x *List) ast.Stmt {
+	head := Head(x)
+	elts := x.Elts[1:]
+	stmt := &ast.SwitchStmt{Body: &ast.BlockStmt{}}
+	if strings.HasSuffix(head, "*") && len(elts) > 0 {
+		stmt.Init = b.stmt(elts[0])
+		elts = elts[1:]
+	}
+	cond := strings.HasPrefix(head, "cond!")
+	if !cond {
+		if len(elts) == 0 {
+			b.errorf(x, "case! needs a tag")
+			return stmt
+		}
+		stmt.Tag = b.expr(elts[0])
+		elts = elts[1:]
+	}
+	for _, clause := range elts {
+		stmt.Body.List = append(stmt.Body.List, b.caseClause(clause, cond, b.expr))
+	}
+	return stmt
+}
+
+func (b *goBuilder) typeSwitchStmt(x *List) ast.Stmt {
+	elts := x.Elts[1:]
+	stmt := &ast.TypeSwitchStmt{Body: &ast.BlockStmt{}}
+	if Head(x) == "type!*" && len(elts) > 0 {
+		stmt.Init = b.stmt(elts[0])
+		elts = elts[1:]
+	}
+	if len(elts) == 0 {
+		b.errorf(x, "type! needs a type assertion")
+		return stmt
+	}
+	stmt.Assign = b.stmt(elts[0])
+	for _, clause := range elts[1:] {
+		stmt.Body.List = append(stmt.Body.List, b.caseClause(clause, false, b.typ))
+	}
+	return stmt
+}
+
+// caseClause rebuilds (else body...), (cond body...) when cond is
+// set, and ((values...) body...) otherwise.
+func (b *goBuilder) caseClause(x Node, cond bool, value func(Node) ast.Expr) ast.Stmt {
+	list, ok := x.(*List)
+	if !ok || len(list.Elts) == 0 {
+		b.errorf(x, "malformed case clause")
+		return &ast.CaseClause{}
+	}
+	clause := &ast.CaseClause{Body: b.stmts(list.Elts[1:])}
+	switch first := list.Elts[0]; {
+	case isSymbol(first, "else"):
+	case cond:
+		clause.List = []ast.Expr{b.expr(first)}
+	default:
+		values, ok := first.(*List)
+		if !ok {
+			b.errorf(first, "expected case values, found %s", first)
+			break
+		}
+		for _, v := range values.Elts {
+			clause.List = append(clause.List, value(v))
+		}
+	}
+	return clause
+}
+
+func (b *goBuilder) exprs(elts []Node) []ast.Expr {
+	var list []ast.Expr
+	for _, elt := range elts {
+		list = append(list, b.expr(elt))
+	}
+	return list
+}
+
+func (b *goBuilder) call(x Node) *ast.CallExpr {
+	if call, ok := b.expr(x).(*ast.CallExpr); ok {
+		return call
+	}
+	b.errorf(x, "expected function call, found %s", x)
+	return &ast.CallExpr{Fun: ast.NewIdent("_")}
+}
+
+func (b *goBuilder) basicLit(x Node) *ast.BasicLit {
+	switch a := x.(type) {
+	case *String:
+		return &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(a.Value)}
+	case *Char:
+		return &ast.BasicLit{Kind: token.CHAR, Value: strconv.QuoteRune(a.Value)}
+	case *Number:
+		kind, lit, err := schemeNumberToGoNumber(a.Lit)
+		if err != nil {
+			b.errorf(x, "%v", err)
+		}
+		return &ast.BasicLit{Kind: kind, Value: lit}
+	}
+	b.errorf(x, "expected literal, found %s", x)
+	return &ast.BasicLit{Kind: token.INT, Value: "0"}
+}
+
+// schemeNumberToGoNumber is the inverse of goNumberToSchemeNumber.
+// It returns the kind of Go literal and its spelling.
+func schemeNumberToGoNumber(lit string) (token.Token, string, error) {
+	radix, exactness := byte('d'), byte('e')
+	num := lit
+	for len(num) >= 2 && num[0] == '#' {
+		switch num[1] | 0x20 { // lower case
+		case 'x', 'o', 'b', 'd':
+			radix = num[1]
+		case 'e', 'i':
+			exactness = num[1]
+		}
+		num = num[2:]
+	}
+
+	kind := token.INT
+	if strings.HasSuffix(num, "i") {
+		if !strings.HasPrefix(num, "+") {
+			return token.IMAG, "0i", fmt.Errorf("no Go literal for %s", lit)
+		}
+		kind = token.IMAG
+		num = strings.TrimSuffix(num[1:], "i")
+	}
+
+	if strings.Contains(num, "/") || exactness == 'i' && !strings.ContainsAny(num, ".eE") {
+		// an inexact ratio, as written for hex floats
+		r, ok := new(big.Rat).SetString(num)
+		if !ok || radix != 'd' || r.Sign() < 0 || !isPowerOfTwo(r.Denom()) {
+			return token.FLOAT, "0.0", fmt.Errorf("no Go literal for %s", lit)
+		}
+		k := r.Denom().BitLen() - 1
+		num = fmt.Sprintf("0x%sp%+d", r.Num().Text(16), -k)
+		if kind == token.IMAG {
+			return kind, num + "i", nil
+		}
+		return token.FLOAT, num, nil
+	}
+
+	switch radix {
+	case 'x':
+		num = "0x" + num
+	case 'o':
+		num = "0o" + num
+	case 'b':
+		num = "0b" + num
+	default:
+		if kind != token.IMAG && strings.ContainsAny(num, ".eE") {
+			kind = token.FLOAT
+		}
+	}
+	if kind == token.IMAG {
+		num += "i"
+	}
+	return kind, num, nil
+}
+
+func isPowerOfTwo(n *big.Int) bool {
+	return n.TrailingZeroBits() == uint(n.BitLen()-1)
+}
+
+// expr rebuilds a Go expression, or a type used as one.
+func (b *goBuilder) expr(x Node) ast.Expr {
+	switch a := x.(type) {
+	case *Symbol:
+		return b.name(a)
+	case *Bool:
+		if a.Value {
+			return ast.NewIdent("true")
+		}
+		return ast.NewIdent("false")
+	case *Nil:
+		return ast.NewIdent("nil")
+	case *String, *Char, *Number:
+		return b.basicLit(x)
+	case *Vector:
+		return b.compositeLit(a)
+	case *Bytevector:
+		lit := &ast.CompositeLit{Type: &ast.ArrayType{Elt: ast.NewIdent("byte")}}
+		for _, v := range a.Value {
+			lit.Elts = append(lit.Elts, &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(int(v))})
+		}
+		return lit
+	case *List:
+		return b.listExpr(a)
+	}
+	b.errorf(x, "unexpected %s", x)
+	return ast.NewIdent("_")
+}
+
+// name rebuilds an identifier, or a qualified pkg.Name or x.f.
+func (b *goBuilder) name(x *Symbol) ast.Expr {
+	if _, ok := schemeIdTable[x.Name]; !ok && strings.Contains(x.Name, ".") &&
+		!strings.HasPrefix(x.Name, ".") && !strings.HasSuffix(x.Name, ".") {
+		parts := strings.Split(x.Name, ".")
+		var expr ast.Expr = ast.NewIdent(schemeIdToGoId(parts[0]))
+		for _, part := range parts[1:] {
+			expr = &ast.SelectorExpr{X: expr, Sel: ast.NewIdent(schemeIdToGoId(part))}
+		}
+		return expr
+	}
+	return ast.NewIdent(schemeIdToGoId(x.Name))
+}
+
+func (b *goBuilder) compositeLit(x *Vector) ast.Expr {
+	if len(x.Elts) == 0 {
+		b.errorf(x, "composite literal needs a type")
+		return &ast.CompositeLit{}
+	}
+	lit := &ast.CompositeLit{}
+	if !isSymbol(x.Elts[0], "_") {
+		lit.Type = b.typ(x.Elts[0])
+	}
+	for _, elt := range x.Elts[1:] {
+		if Head(elt) == ":" {
+			kv := b.args(elt, 2)
+			var key ast.Expr
+			if sym, ok := kv[0].(*Symbol); ok {
+				key = b.name(sym)
+			} else {
+				key = b.expr(kv[0])
+			}
+			lit.Elts = append(lit.Elts, &ast.KeyValueExpr{Key: key, Value: b.expr(kv[1])})
+			continue
+		}
+		lit.Elts = append(lit.Elts, b.expr(elt))
+	}
+	return lit
+}
+
+func (b *goBuilder) listExpr(x *List) ast.Expr {
+	if len(x.Elts) == 0 {
+		b.errorf(x, "empty form")
+		return ast.NewIdent("_")
+	}
+	head := Head(x)
+	elts := x.Elts[1:]
+	switch head {
+	case "slice", "array", "array...", "map-type", "chan", "chan<-", "chan<-!",
+		"struct", "interface", "union", "~", "inst":
+		return b.typ(x)
+	case "ptr":
+		return &ast.StarExpr{X: primary(b.expr(b.args(x, 1)[0]))}
+	case "func", "func...":
+		elts := b.args(x, 2)
+		ft := b.funcType(head == "func...", elts[0], elts[1])
+		return &ast.FuncLit{Type: ft, Body: b.block(elts[2:])}
+	case "index":
+		elts := b.args(x, 2)
+		if len(elts) == 2 {
+			return &ast.IndexExpr{X: primary(b.expr(elts[0])), Index: b.expr(elts[1])}
+		}
+		return &ast.SliceExpr{
+			X:    primary(b.expr(elts[0])),
+			Low:  b.optExpr(elts[1], false),
+			High: b.optExpr(elts[2], false),
+		}
+	case "dot", "method":
+		elts := b.args(x, 2)
+		return &ast.SelectorExpr{X: primary(b.expr(elts[0])), Sel: b.ident(elts[1])}
+	case "method-expr":
+		elts := b.args(x, 2)
+		return &ast.SelectorExpr{X: primary(b.typ(elts[0])), Sel: b.ident(elts[1])}
+	case "call-method":
+		elts := b.args(x, 2)
+		fun := &ast.SelectorExpr{X: primary(b.expr(elts[0])), Sel: b.ident(elts[1])}
+		return &ast.CallExpr{Fun: fun, Args: b.exprs(elts[2:])}
+	case "convert":
+		elts := b.args(x, 2)
+		if v, ok := elts[1].(*Bytevector); ok && len(elts) == 2 && isSymbol(elts[0], goIdTable["string"]) {
+			// a string that is not valid UTF-8
+			return &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(string(v.Value))}
+		}
+		return &ast.CallExpr{Fun: convertible(b.typ(elts[0])), Args: b.exprs(elts[1:])}
+	case "as":
+		elts := b.args(x, 2)
+		assert := &ast.TypeAssertExpr{X: primary(b.expr(elts[0]))}
+		if !isSymbol(elts[1], "type") {
+			assert.Type = b.typ(elts[1])
+		}
+		return assert
+	case "wrap-signed", "wrap-unsigned":
+		// Go arithmetic wraps by itself
+		return b.expr(b.args(x, 2)[1])
+	case "apply...":
+		elts := b.args(x, 1)
+		return &ast.CallExpr{Fun: primary(b.expr(elts[0])), Args: b.exprs(elts[1:]), Ellipsis: 1}
+	}
+
+	if len(elts) == 1 {
+		if op, ok := b.unaryOp(head); ok {
+			expr := b.expr(elts[0])
+			if bin, ok := expr.(*ast.BinaryExpr); ok && op == token.NOT && bin.Op == token.EQL {
+				// (not (= x y)) is x != y
+				bin.Op = token.NEQ
+				return bin
+			}
+			return &ast.UnaryExpr{Op: op, X: operand(expr, token.UnaryPrec)}
+		}
+	}
+	if len(elts) == 2 {
+		if op, ok := b.binaryOp(head); ok {
+			y := elts[1]
+			if n, ok := negated(y); ok && op == token.SHL {
+				// (arithmetic-shift x (- n)) is x >> n
+				op, y = token.SHR, n
+			}
+			return &ast.BinaryExpr{
+				X:  operand(b.expr(elts[0]), op.Precedence()),
+				Op: op,
+				Y:  operand(b.expr(y), op.Precedence()+1),
+			}
+		}
+	}
+	return &ast.CallExpr{Fun: primary(b.expr(x.Elts[0])), Args: b.exprs(elts)}
+}
+
+func (b *goBuilder) unaryOp(name string) (token.Token, bool) {
+	switch name {
+	case "not", "adr", "bitwise-not":
+		return schemeOpTable[name], true
+	case "-", "+", "<-":
+		return goTokens[name], true
+	}
+	return token.ILLEGAL, false
+}
+
+// typ rebuilds a Go type.
+func (b *goBuilder) typ(x Node) ast.Expr {
+	list, ok := x.(*List)
+	if !ok {
+		return b.expr(x)
+	}
+	head := Head(x)
+	switch head {
+	case "slice":
+		return &ast.ArrayType{Elt: b.typ(b.args(x, 1)[0])}
+	case "array":
+		elts := b.args(x, 2)
+		return &ast.ArrayType{Len: b.expr(elts[0]), Elt: b.typ(elts[1])}
+	case "array...":
+		return &ast.ArrayType{Len: &ast.Ellipsis{}, Elt: b.typ(b.args(x, 1)[0])}
+	case "map-type":
+		elts := b.args(x, 2)
+		return &ast.MapType{Key: b.typ(elts[0]), Value: b.typ(elts[1])}
+	case "chan", "chan<-", "chan<-!":
+		dir := map[string]ast.ChanDir{
+			"chan": ast.SEND | ast.RECV, "chan<-": ast.RECV, "chan<-!": ast.SEND,
+		}[head]
+		return &ast.ChanType{Dir: dir, Value: b.typ(b.args(x, 1)[0])}
+	case "ptr":
+		return &ast.StarExpr{X: b.typ(b.args(x, 1)[0])}
+	case "func", "func...":
+		elts := b.args(x, 2)
+		return b.funcType(head == "func...", elts[0], elts[1])
+	case "struct":
+		return &ast.StructType{Fields: b.braced(b.fields(list.Elts[1:]))}
+	case "interface":
+		return &ast.InterfaceType{Methods: b.braced(b.fields(list.Elts[1:]))}
+	case "union":
+		elts := b.args(x, 1)
+		expr := b.typ(elts[0])
+		for _, elt := range elts[1:] {
+			expr = &ast.BinaryExpr{X: expr, Op: token.OR, Y: b.typ(elt)}
+		}
+		return expr
+	case "~":
+		return &ast.UnaryExpr{Op: token.TILDE, X: b.typ(b.args(x, 1)[0])}
+	case "inst":
+		elts := b.args(x, 2)
+		var args []ast.Expr
+		for _, elt := range elts[1:] {
+			args = append(args, b.typ(elt))
+		}
+		if len(args) == 1 {
+			return &ast.IndexExpr{X: b.typ(elts[0]), Index: args[0]}
+		}
+		return &ast.IndexListExpr{X: b.typ(elts[0]), Indices: args}
+	}
+	return b.expr(x)
+}
+
+// operand parenthesizes expr if it binds less tightly than prec.
+func operand(expr ast.Expr, prec int) ast.Expr {
+	if bin, ok := expr.(*ast.BinaryExpr); ok && bin.Op.Precedence() < prec {
+		return &ast.ParenExpr{X: expr}
+	}
+	return expr
+}
+
+// primary parenthesizes expr if it cannot be the operand of a
+// selector, index or call.
+func primary(expr ast.Expr) ast.Expr {
+	switch expr.(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr, *ast.StarExpr:
+		return &ast.ParenExpr{X: expr}
+	}
+	return expr
+}
+
+// convertible parenthesizes a type that cannot be called as it is.
+func convertible(expr ast.Expr) ast.Expr {
+	switch expr.(type) {
+	case *ast.StarExpr, *ast.FuncType, *ast.ChanType:
+		return &ast.ParenExpr{X: expr}
+	}
+	return expr
+}
@@ -0,0 +1,82 @@
+package gos
+
+import (
+	"bytes"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReverse translates each golden file back to Go, which must
+// be gofmt's layout of the matching file in testdata/reverse, and
+// then to Gos again, which must give the same forms. Comments are
+// dropped by the reverse translation, so they are not compared.
+func TestReverse(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		name := name
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			gos := translateGolden(t, name)
+			var src bytes.Buffer
+			if err := ReverseTranslate(&src, name+"s", gos); err != nil {
+				t.Fatalf("%v\n%s", err, gos)
+			}
+			golden := filepath.Join("testdata", "reverse", filepath.Base(name))
+			if *update {
+				if err := os.WriteFile(golden, src.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted, err := format.Source(want); err != nil {
+				t.Fatalf("%s: %v", golden, err)
+			} else if !bytes.Equal(formatted, want) {
+				t.Errorf("%s is not gofmt-formatted", golden)
+			}
+			if got := src.Bytes(); !bytes.Equal(got, want) {
+				t.Errorf("reverse translation differs from %s:\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+			}
+
+			c := NewCompiler()
+			c.Filename = name
+			c.TypeCheck = strings.HasSuffix(name, "_typed.go")
+			c.WrapIntegers = strings.HasSuffix(name, "_wrap.go")
+			var again bytes.Buffer
+			if err := c.Compile(bytes.NewReader(src.Bytes()), &again); err != nil {
+				t.Fatalf("%v\n%s", err, src.Bytes())
+			}
+			if got, want := readForms(t, again.Bytes()), readForms(t, gos); got != want {
+				t.Errorf("round trip through Go differs:\n--- got ---\n%s\n--- want ---\n%s\n--- via ---\n%s",
+					got, want, src.Bytes())
+			}
+		})
+	}
+}
+
+func readForms(t *testing.T, src []byte) string {
+	forms, err := Read("", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return joinNodes(forms)
+}
+
+func TestReverseErrors(t *testing.T) {
+	var buf bytes.Buffer
+	err := ReverseTranslate(&buf, "bad.gos", []byte("(package p\n  (func f () &void\n    (when)))"))
+	if got, want := err.Error(), "bad.gos:3:5: malformed when form"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+	err = ReverseTranslate(&buf, "bad.gos", []byte("(define x 1)"))
+	if got, want := err.Error(), "bad.gos:1:1: expected (package ...), found (define x 1)"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,25 @@
+// Package comments checks that comments survive translation.
+package comments
+
+// Answer is the answer.
+const Answer = 42 // trailing
+
+/*
+Config holds
+settings.
+*/
+type Config struct {
+	// Name is a "name".
+	Name string // trailing name
+	Size int
+}
+
+var (
+	// Verbose turns on logging.
+	Verbose bool
+)
+
+// Run runs c.
+//
+// It returns \ nothing.
+func Run(c *Config) {}
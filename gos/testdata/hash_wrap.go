@@ -0,0 +1,33 @@
+package wrap
+
+func hash(data []byte) uint32 {
+	var h uint32 = 2166136261
+	for _, b := range data {
+		h ^= uint32(b)
+		h *= 16777619
+	}
+	return h
+}
+
+func ops(a, b int8, u uint8, n int, i int64, f float64) {
+	_ = a + b
+	_ = a - b
+	_ = a * b
+	_ = a / b
+	_ = a % b
+	_ = a << 1
+	_ = a >> 1
+	_ = -a
+	_ = ^a
+	_ = ^u
+	_ = u + 1
+	_ = n * n
+	_ = i - 1
+	_ = f * f
+	_ = byte(n)
+	_ = int8(n)
+	a += b
+	u -= 1
+	a++
+	u--
+}
@@ -0,0 +1,52 @@
+;; Package shapes is what the backends that write libraries are
+;; tested on.
+(eval-when (:compile-toplevel :load-toplevel :execute)
+  (setf *readtable* (copy-readtable nil))
+  (setf (readtable-case *readtable*) :invert))
+(defpackage #:go/example.com/shapes
+  (:use #:cl #:go/builtin #:go/strings)
+  (:shadowing-import-from #:go/builtin
+                          #:append
+                          #:close
+                          #:complex
+                          #:delete
+                          #:max
+                          #:method
+                          #:min
+                          #:print
+                          #:real)
+  (:shadow #:scale)
+  (:local-nicknames (#:units #:go/example.com/go-units)
+                    (#:fmt #:go/fmt)
+                    (#:yaml #:go/gopkg.in/yaml.v3)
+                    (#:m #:go/math))
+  (:export #:Pi
+           #:Point
+           #:Point.Moved
+           #:Circle
+           #:Circle.Area
+           #:Origin
+           #:Describe
+           #:Encode))
+(in-package #:go/example.com/shapes)
+;; Pi is exported; scale is not.
+(defparameter Pi m:Pi)
+(defparameter scale 2.0d0)
+;; Point is a point in the plane.
+(defstruct (Point (:constructor Point (X Y))) X Y)
+(defun Point.Moved (p dx)
+  "Moved returns p moved dx to the right."
+  (incf (dot p X) dx)
+  p)
+;; Shape has an area.
+(defstruct (Circle (:constructor Circle (Center Radius))) Center Radius)
+(defun Circle.Area (c) (* (* Pi (dot c Radius)) (dot c Radius)))
+(defun Circle.grow (c) (setf (dot c Radius) (* (dot c Radius) scale)))
+(defun Origin (r)
+  "Origin returns a circle of radius r at the origin."
+  (adr (Circle (Point 0 0) r)))
+(defun Describe (s)
+  (fmt:Sprintf "%s %v" (ToUpper "area") (funcall (dot s Area))))
+(defun Encode (s)
+  "Encode returns the YAML for s, whose radius is in metres."
+  (yaml:Marshal (units:Metres (dot s Radius))))
@@ -0,0 +1,36 @@
+;; Package shapes is what the backends that write libraries are
+;; tested on.
+(ns go.example.com.shapes
+  (:refer-clojure :exclude [max min print println])
+  (:require [go.builtin :refer :all]
+            [go.example.com.go-units :as units]
+            [go.fmt :as fmt]
+            [go.gopkg.in.yaml.v3 :as yaml]
+            [go.math :as m]
+            go.os
+            [go.strings :refer :all]))
+;; Point is a point in the plane.
+(defrecord Point [X Y])
+;; Shape has an area.
+(defprotocol Shape (Area [this]))
+(defrecord Circle [Center Radius])
+(declare Pi scale Point-Moved Circle-Area Circle-grow Origin Describe Encode)
+;; Pi is exported; scale is not.
+(def Pi m/Pi)
+(def ^:private scale 2.0)
+(defn Point-Moved
+  "Moved returns p moved dx to the right."
+  [p dx]
+  (let [p (volatile! p)] (vswap! p assoc :X (+ (dot @p :X) dx)) @p))
+(defn Circle-Area [c] (* (* Pi (dot c :Radius)) (dot c :Radius)))
+(defn- Circle-grow [c] (dot-set! c :Radius (* (dot c :Radius) scale)))
+(defn Origin
+  "Origin returns a circle of radius r at the origin."
+  [r]
+  (adr (->Circle (->Point 0 0) r)))
+(defn Describe [s] (fmt/Sprintf "%s %v" (ToUpper "area") (Area s)))
+(defn Encode
+  "Encode returns the YAML for s, whose radius is in metres."
+  [s]
+  (yaml/Marshal (units/Metres (dot s :Radius))))
+(extend Circle Shape {:Area Circle-Area})
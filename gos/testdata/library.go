@@ -0,0 +1,60 @@
+// Package shapes is what the backends that write libraries are
+// tested on.
+package shapes
+
+import (
+	"example.com/go-units"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	m "math"
+	_ "os"
+	. "strings"
+)
+
+// Pi is exported; scale is not.
+const Pi = m.Pi
+
+var scale = 2.0
+
+// Point is a point in the plane.
+type Point struct {
+	X, Y float64
+}
+
+// Moved returns p moved dx to the right.
+func (p Point) Moved(dx float64) Point {
+	p.X += dx
+	return p
+}
+
+// Shape has an area.
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+func (c *Circle) Area() float64 {
+	return Pi * c.Radius * c.Radius
+}
+
+func (c *Circle) grow() {
+	c.Radius *= scale
+}
+
+// Origin returns a circle of radius r at the origin.
+func Origin(r float64) *Circle {
+	return &Circle{Center: Point{0, 0}, Radius: r}
+}
+
+func Describe(s Shape) string {
+	return fmt.Sprintf("%s %v", ToUpper("area"), s.Area())
+}
+
+// Encode returns the YAML for s, whose radius is in metres.
+func Encode(s *Circle) ([]byte, error) {
+	return yaml.Marshal(units.Metres(s.Radius))
+}
@@ -0,0 +1,30 @@
+;; Package shapes is what the backends that write libraries are
+;; tested on.
+(define-module (go example.com shapes)
+  #:use-module (srfi srfi-11)
+  #:use-module (go builtin)
+  #:use-module ((go example.com go-units) #:prefix units.)
+  #:use-module ((go fmt) #:prefix fmt.)
+  #:use-module ((go gopkg.in yaml.v3) #:prefix yaml.)
+  #:use-module ((go math) #:prefix m.)
+  #:use-module ((go os) #:select ())
+  #:use-module (go strings)
+  #:export (Pi Point.Moved Circle.Area Origin Describe Encode))
+;; Pi is exported; scale is not.
+(define Pi m.Pi)
+(define scale 2.0)
+;; Point is a point in the plane.
+(define (Point.Moved p dx)
+  "Moved returns p moved dx to the right."
+  (dot-set! p X (+ (dot p X) dx))
+  p)
+;; Shape has an area.
+(define (Circle.Area c) (* (* Pi (dot c Radius)) (dot c Radius)))
+(define (Circle.grow c) (dot-set! c Radius (* (dot c Radius) scale)))
+(define (Origin r)
+  "Origin returns a circle of radius r at the origin."
+  (adr (composite Circle (: Center (composite Point 0 0)) (: Radius r))))
+(define (Describe s) (fmt.Sprintf "%s %v" (ToUpper "area") ((dot s Area))))
+(define (Encode s)
+  "Encode returns the YAML for s, whose radius is in metres."
+  (yaml.Marshal (units.Metres (dot s Radius))))
@@ -0,0 +1,52 @@
+(package lower
+  (const (= A (* iota 10)) B)
+  (var (= _ A))
+  (func #(c (ptr Counter)) Add (#(n &int)) &void (+= c.n n))
+  (func find (#(xs (slice &imm-string)) #(s &imm-string))
+    (values #(i &int) #(ok &bool))
+    (let ((i 0) (ok #f))
+      (call/cc (lambda (return)
+                 (= i 0)
+                 (let %loop ()
+                   (when (< i (len xs))
+                     (call/cc (lambda (continue)
+                                (call/cc (lambda (break)
+                                           (let ((%tag (index xs i)))
+                                             (cond
+                                               ((equal? %tag "") (continue))
+                                               ((equal? %tag s) (return i #t))
+                                               (else (when (> i 10) (break)))))))))
+                     (++ i)
+                     (%loop)))
+                 (values i ok)))))
+  (func count (#(n &int)) &int
+    (call/cc (lambda (return)
+               (let ((i #f) (goto-loop #f))
+                 (letrec ((label-loop (lambda ()
+                                        (when (< i n)
+                                          (++ i)
+                                          (goto-loop label-loop))
+                                        (return i))))
+                   (let %goto ((%next (lambda () (= i 0) (label-loop))))
+                     (let ((%target (call/cc (lambda (%k)
+                                               (set! goto-loop %k)
+                                               (%next)
+                                               #f))))
+                       (when %target (%goto %target)))))))))
+  (func... first (#(xs &int)) &int
+    (call/cc (lambda (return)
+               (let ((%defers (list)))
+                 (dynamic-wind
+                   (lambda () #f)
+                   (lambda ()
+                     (set! %defers (cons (lambda () (cleanup)) %defers))
+                     (let ((f (func (#(x &int)) &int
+                                (call/cc (lambda (return)
+                                           (when (< x 0) (return (- x)))
+                                           x)))))
+                       (range-for-each (lambda (%_1 x)
+                                         (when (not (equal? x 0))
+                                           (return (f x))))
+                                       xs)
+                       0))
+                   (lambda () (for-each (lambda (%f) (%f)) %defers))))))))
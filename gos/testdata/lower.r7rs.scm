@@ -0,0 +1,52 @@
+(package lower
+  (define A (* 0 10))
+  (define B (* 1 10))
+  (define %_1 A)
+  (define (Counter.Add c n) (dot-set! c n (+ c.n n)))
+  (define (find xs s)
+    (let ((i 0) (ok #f))
+      (call/cc (lambda (return)
+                 (set! i 0)
+                 (let %loop ()
+                   (when (< i (len xs))
+                     (call/cc (lambda (continue)
+                                (call/cc (lambda (break)
+                                           (let ((%tag (index xs i)))
+                                             (cond
+                                               ((equal? %tag "") (continue))
+                                               ((equal? %tag s) (return i #t))
+                                               (else (when (> i 10) (break)))))))))
+                     (set! i (+ i 1))
+                     (%loop)))
+                 (values i ok)))))
+  (define (count n)
+    (call/cc (lambda (return)
+               (let ((i #f) (goto-loop #f))
+                 (letrec ((label-loop (lambda ()
+                                        (when (< i n)
+                                          (set! i (+ i 1))
+                                          (goto-loop label-loop))
+                                        (return i))))
+                   (let %goto ((%next (lambda () (set! i 0) (label-loop))))
+                     (let ((%target (call/cc (lambda (%k)
+                                               (set! goto-loop %k)
+                                               (%next)
+                                               #f))))
+                       (when %target (%goto %target)))))))))
+  (define (first . xs)
+    (call/cc (lambda (return)
+               (let ((%defers (list)))
+                 (dynamic-wind
+                   (lambda () #f)
+                   (lambda ()
+                     (set! %defers (cons (lambda () (cleanup)) %defers))
+                     (let ((f (lambda (x)
+                                (call/cc (lambda (return)
+                                           (when (< x 0) (return (- x)))
+                                           x)))))
+                       (range-for-each (lambda (%_2 x)
+                                         (when (not (equal? x 0))
+                                           (return (f x))))
+                                       xs)
+                       0))
+                   (lambda () (for-each (lambda (%f) (%f)) %defers))))))))
@@ -0,0 +1,15 @@
+package comments
+
+const Answer = 42
+
+type Config struct {
+	Name string
+	Size int
+}
+
+var Verbose bool
+
+// Run runs c.
+//
+// It returns \ nothing.
+func Run(c *Config) {}
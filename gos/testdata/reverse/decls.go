@@ -0,0 +1,76 @@
+package decls
+
+import "fmt"
+
+import (
+	"io"
+	str "strings"
+	. "unicode"
+)
+
+const Pi = 3.14
+
+const (
+	A = iota
+	B
+	C    int = 10
+	D, E     = 1, 2
+)
+
+var x int
+
+var (
+	y, z float64
+	s         = "s"
+	u, v      = 1, 2
+	w    uint = 3
+)
+
+type (
+	Point struct {
+		X, Y int
+		name string
+		io.Reader
+		*fmt.Stringer
+	}
+	Shape interface {
+		Area() float64
+		fmt.Stringer
+	}
+	Table  map[string][]int
+	Grid   [3][3]byte
+	Dots   [...]int
+	Source <-chan int
+	Sink   chan<- rune
+	Pipe   chan bool
+	Op     func(a, b int) (int, error)
+	Ptr    *Point
+)
+
+func noop() {}
+
+func (p Point) Norm() float64 {
+	return 0
+}
+
+func (p *Point) Move(dx, dy int) {}
+
+func sum(xs ...int) int {
+	return 0
+}
+
+func pair() (int, error) {
+	return 0, nil
+}
+
+func named() (n int, err error) {
+	return
+}
+
+func use() {
+	fmt.Println(str.ToUpper("a"), IsUpper('A'))
+}
+
+func index(when, fooZB int) int {
+	return when + fooZB
+}
@@ -0,0 +1,65 @@
+package exprs
+
+type T struct {
+	a int
+	b string
+}
+
+func exprs(p *T, xs []int, m map[string]int, i interface{}) {
+	_ = 42
+	_ = 3.5
+	_ = 0x1F + 0o17 + 0o17 + 0b1010 + 1000000
+	_ = 1e-3 + 0x1p-2
+	_ = 3i + 0x1p-2i
+	_ = 'x'
+	_ = '\n'
+	_ = ' '
+	_ = "hello\tworld"
+	_ = "raw \"quoted\""
+	_ = "C:\\dir"
+	_ = "AéA\x00"
+	_ = "\xff\xfe"
+	_ = 'é' + 'A' + 'A' + '\\' + '\''
+	_ = true
+	_ = false
+	_ = nil
+	_ = (1 + 2) * 3
+	_ = 1 - 2/3%4
+	_ = 1<<2 | 3>>1&4 ^ 5&^6
+	_ = a && b || !c
+	_ = a == b && a != b && a < b && a <= b && a > b && a >= b
+	_ = -x + +y
+	_ = ^x
+	_ = &x
+	_ = *p
+	_ = <-ch
+	_ = xs[1]
+	_ = xs[1:2]
+	_ = xs[:2]
+	_ = xs[1:]
+	_ = xs[:]
+	_ = m["k"]
+	_ = p.a
+	_ = f().b
+	_ = i.(int)
+	_ = f(1, 2)
+	_ = f(xs...)
+	_ = T{1, "b"}
+	_ = T{a: 1, b: "b"}
+	_ = &T{}
+	_ = []int{1, 2, 3}
+	_ = [...]string{"a"}
+	_ = map[string]int{"a": 1}
+	_ = map[[2]int]bool{{1, 2}: true}
+	_ = map[int]int{1 + 1: 2}
+	_ = func(x int) int {
+		return x
+	}
+	_ = func() {
+	}
+	_ = struct{}{}
+	ps := []*int{nil}
+	pm := map[string]*int{}
+	_ = *ps[0]
+	_ = *pm["k"]
+}
@@ -0,0 +1,61 @@
+package generics
+
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+type Ordered interface {
+	Number | ~string
+	Less(other any) bool
+}
+
+type List[T any] struct {
+	items []T
+}
+
+type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+func (l *List[T]) Push(x T) {
+	l.items = append(l.items, x)
+}
+
+func (p Pair[K, V]) Swap() Pair[K, V] {
+	return p
+}
+
+func Map[T, U any](xs []T, f func(T) U) []U {
+	var out []U
+	for _, x := range xs {
+		out = append(out, f(x))
+	}
+	return out
+}
+
+func Sum[N Number](xs ...N) N {
+	var s N
+	return s
+}
+
+func Keys[M ~map[K]V, K comparable, V any](m M) []K {
+	return nil
+}
+
+func use() {
+	var l List[int]
+	l.Push(1)
+	p := Pair[string, int]{"a", 1}
+	_ = p.Swap()
+	_ = Map[int, string](nil, nil)
+	_ = Sum[float64]()
+	_ = Sum(1, 2)
+	xs := []int{1}
+	_ = xs[0]
+	ps := []*int{nil}
+	m := map[string]*List[int]{}
+	_ = *ps[0]
+	_ = *m["k"]
+	_ = (*List[int])(nil)
+}
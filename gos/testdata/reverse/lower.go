@@ -0,0 +1,54 @@
+package lower
+
+const (
+	A = iota * 10
+	B
+)
+
+var _ = A
+
+func (c *Counter) Add(n int) {
+	c.n += n
+}
+
+func find(xs []string, s string) (i int, ok bool) {
+	for i = 0; i < len(xs); i++ {
+		switch xs[i] {
+		case "":
+			continue
+		case s:
+			return i, true
+		default:
+			if i > 10 {
+				break
+			}
+		}
+	}
+	return
+}
+
+func count(n int) int {
+	i := 0
+loop:
+	if i < n {
+		i++
+		goto loop
+	}
+	return i
+}
+
+func first(xs ...int) int {
+	defer cleanup()
+	f := func(x int) int {
+		if x < 0 {
+			return -x
+		}
+		return x
+	}
+	for _, x := range xs {
+		if x != 0 {
+			return f(x)
+		}
+	}
+	return 0
+}
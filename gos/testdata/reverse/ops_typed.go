@@ -0,0 +1,36 @@
+package ops
+
+type T struct {
+	n int
+}
+
+func ops(i, j int, u uint8, f float64, s, t string, p, q *T, a, b T, e error, ok bool) {
+	_ = i / j
+	_ = u / 2
+	_ = f / 2
+	_ = 7 / 2
+	_ = 7.0 / 2
+	_ = i % j
+	_ = i << 3
+	_ = i >> j
+	_ = i == j
+	_ = f != 1.5
+	_ = s + t
+	_ = s == t
+	_ = s != "x"
+	_ = s < t
+	_ = s >= t
+	_ = p == q
+	_ = p != nil
+	_ = nil == p
+	_ = a == b
+	_ = e != nil
+	_ = ok == true
+	i /= j
+	f /= 2
+	i %= j
+	i <<= 1
+	i >>= 2
+	i -= 1
+	s += t
+}
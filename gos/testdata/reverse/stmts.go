@@ -0,0 +1,143 @@
+package stmts
+
+func assign(p *T, xs []int) {
+	a := 1
+	b, c := 2, 3
+	a = b
+	p.x = c
+	xs[0] = a
+	a, b = b, a
+	a += 1
+	a -= 2
+	a |= 4
+	a &= 5
+	a ^= 6
+	a &^= 7
+	a++
+	b--
+}
+
+func ifs(a, b int) {
+	if a > b {
+		a = b
+	}
+	if !ok() {
+		return
+	}
+	if err := f(); err != nil {
+		panic(err)
+	}
+	if a == 1 {
+		a = 2
+	} else {
+		a = 3
+	}
+	if a == 1 {
+		a = 2
+	} else if a == 2 {
+		a = 3
+	} else if !ok() {
+		a = 4
+	} else {
+		a = 5
+	}
+}
+
+func loops(xs []int, m map[string]int) {
+	for {
+		break
+	}
+	for ok() {
+		continue
+	}
+	for i := 0; i < 10; i++ {
+		f()
+	}
+	for ok() {
+		f()
+	}
+	for i := 0; ; {
+		i++
+	}
+	for range xs {
+	}
+	for i := range xs {
+		f(i)
+	}
+	for k, v := range m {
+		f(k, v)
+	}
+	for _, v = range xs {
+	}
+}
+
+func switches(a int, x interface{}) {
+	switch {
+	case a < 0:
+		f()
+	case a > 0:
+		g()
+	default:
+		h()
+	}
+	switch a {
+	case 1, 2:
+		f()
+		fallthrough
+	case 3:
+		g()
+	}
+	switch b := a * 2; b {
+	case 4:
+		f()
+	}
+	switch v := x.(type) {
+	case int, uint:
+		f(v)
+	case nil:
+	default:
+		g(v)
+	}
+	switch x.(type) {
+	case string:
+	}
+}
+
+func chans(c chan int, d chan<- int, done <-chan bool) {
+	c <- 1
+	v := <-c
+	select {
+	case x := <-c:
+		f(x)
+	case d <- v:
+	case <-done:
+		return
+	default:
+	}
+	go f(v)
+	defer g()
+}
+
+func labels() {
+outer:
+	for i := 0; i < 3; i++ {
+		for {
+			if ok() {
+				continue outer
+			}
+			break outer
+		}
+	}
+	goto done
+done:
+	;
+	f()
+	var n int = 3
+	const m = 4
+	type local struct{}
+	_, _ = n, m
+}
+
+func results() (int, string) {
+	return 1, "a"
+}
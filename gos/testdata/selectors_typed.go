@@ -0,0 +1,29 @@
+package typed
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Inner struct{ N int }
+
+type T struct {
+	Inner
+	name string
+}
+
+func (t *T) Get() string { return t.name }
+
+func (t T) Set(n string) {}
+
+func use(t *T, x float64) {
+	n := int(x)
+	s := string(rune(n))
+	f := t.Get
+	g := (*T).Get
+	h := T.Set
+	fmt.Println(t.name, t.N, t.Get(), f(), g(t), s)
+	h(*t, strings.ToUpper(s))
+	_ = []byte(s)
+	_ = (*T)(nil)
+}
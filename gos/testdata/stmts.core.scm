@@ -0,0 +1,86 @@
+(package stmts
+  (func assign (#(p (ptr T)) #(xs (slice &int))) &void
+    (let ((a 1))
+      (let ((b 2) (c 3))
+        (= a b)
+        (= p.x c)
+        (= ((index xs 0)) a)
+        (= (a b) b a)
+        (+= a 1)
+        (-= a 2)
+        (bitwise-or= a 4)
+        (bitwise-and= a 5)
+        (bitwise-xor= a 6)
+        (bitwise-but= a 7)
+        (++ a)
+        (-- b))))
+  (func ifs (#(a b &int)) &void
+    (call/cc (lambda (return)
+               (when (> a b) (= a b))
+               (unless (ok) (return))
+               (let ((err (f))) (when (not (equal? err %nil)) (panic err)))
+               (if (equal? a 1) (= a 2) (= a 3))
+               (if (equal? a 1)
+                   (= a 2)
+                   (if (equal? a 2) (= a 3) (if (ok) (= a 5) (= a 4)))))))
+  (func loops (#(xs (slice &int)) #(m (map-type &imm-string &int))) &void
+    (call/cc (lambda (break) (let %loop () (break) (%loop))))
+    (let %loop () (when (ok) (call/cc (lambda (continue) (continue))) (%loop)))
+    (let ((i 0)) (let %loop () (when (< i 10) (f) (++ i) (%loop))))
+    (let %loop () (when (ok) (f) (%loop)))
+    (let ((i 0)) (let %loop () (++ i) (%loop)))
+    (range-for-each (lambda (%k %v) (if #f #f)) xs)
+    (range-for-each (lambda (i %v) (f i)) xs)
+    (range-for-each (lambda (k v) (f k v)) m)
+    (range-for-each (lambda (%k %v) (= v %v)) xs))
+  (func switches (#(a &int) #(x (interface))) &void
+    (cond ((< a 0) (f)) ((> a 0) (g)) (else (h)))
+    (case a ((1 2) (f) (g)) ((3) (g)))
+    (let ((b (* a 2))) (case b ((4) (f))))
+    (cond
+      ((or (type-is? x &int) (type-is? x &uint)) (let ((v x)) (f v)))
+      ((eqv? x %nil) (let ((v x)) (if #f #f)))
+      (else (let ((v x)) (g v))))
+    (cond ((type-is? x &imm-string) (if #f #f))))
+  (func chans (#(c (chan &int)) #(d (chan<-! &int)) #(done (chan<- &bool)))
+    &void
+    (call/cc (lambda (return)
+               (let ((%defers (list)))
+                 (dynamic-wind
+                   (lambda () #f)
+                   (lambda ()
+                     (<-! c 1)
+                     (let ((v (<- c)))
+                       (select (list (recv-case c (lambda (x %ok) (f x)))
+                                     (send-case d v (lambda () (if #f #f)))
+                                     (recv-case done (lambda (%v %ok) (return))))
+                               (lambda () (if #f #f)))
+                       (go (let ((%0 v)) (lambda () (f %0))))
+                       (set! %defers (cons (lambda () (g)) %defers))))
+                   (lambda () (for-each (lambda (%f) (%f)) %defers)))))))
+  (func labels () &void
+    (let ((n #f) (m #f) (goto-done #f))
+      (letrec ((label-done (lambda ()
+                             (f)
+                             (= n 3)
+                             (= m 4)
+                             (type local (struct))
+                             (= (_ _) n m))))
+        (let %goto
+          ((%next (lambda ()
+                    (let ((i 0))
+                      (call/cc (lambda (break-outer)
+                                 (let %loop ()
+                                   (when (< i 3)
+                                     (call/cc (lambda (continue-outer)
+                                                (let %loop ()
+                                                  (when (ok) (continue-outer))
+                                                  (break-outer)
+                                                  (%loop))))
+                                     (++ i)
+                                     (%loop))))))
+                    (goto-done label-done)
+                    (label-done))))
+          (let ((%target (call/cc (lambda (%k) (set! goto-done %k) (%next) #f))))
+            (when %target (%goto %target)))))))
+  (func results () (values &int &imm-string) (values 1 "a")))
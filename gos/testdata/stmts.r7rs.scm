@@ -0,0 +1,84 @@
+(package stmts
+  (define (assign p xs)
+    (let ((a 1))
+      (let ((b 2) (c 3))
+        (set! a b)
+        (dot-set! p x c)
+        (index-set! xs 0 a)
+        (let ((%0 b) (%1 a)) (set! a %0) (set! b %1))
+        (set! a (+ a 1))
+        (set! a (- a 2))
+        (set! a (bitwise-or a 4))
+        (set! a (bitwise-and a 5))
+        (set! a (bitwise-xor a 6))
+        (set! a (bitwise-but a 7))
+        (set! a (+ a 1))
+        (set! b (- b 1)))))
+  (define (ifs a b)
+    (call/cc (lambda (return)
+               (when (> a b) (set! a b))
+               (unless (ok) (return))
+               (let ((err (f))) (when (not (equal? err %nil)) (panic err)))
+               (if (equal? a 1) (set! a 2) (set! a 3))
+               (if (equal? a 1)
+                   (set! a 2)
+                   (if (equal? a 2) (set! a 3) (if (ok) (set! a 5) (set! a 4)))))))
+  (define (loops xs m)
+    (call/cc (lambda (break) (let %loop () (break) (%loop))))
+    (let %loop () (when (ok) (call/cc (lambda (continue) (continue))) (%loop)))
+    (let ((i 0)) (let %loop () (when (< i 10) (f) (set! i (+ i 1)) (%loop))))
+    (let %loop () (when (ok) (f) (%loop)))
+    (let ((i 0)) (let %loop () (set! i (+ i 1)) (%loop)))
+    (range-for-each (lambda (%k %v) (if #f #f)) xs)
+    (range-for-each (lambda (i %v) (f i)) xs)
+    (range-for-each (lambda (k v) (f k v)) m)
+    (range-for-each (lambda (%k %v) (set! v %v)) xs))
+  (define (switches a x)
+    (cond ((< a 0) (f)) ((> a 0) (g)) (else (h)))
+    (case a ((1 2) (f) (g)) ((3) (g)))
+    (let ((b (* a 2))) (case b ((4) (f))))
+    (cond
+      ((or (type-is? x &int) (type-is? x &uint)) (let ((v x)) (f v)))
+      ((eqv? x %nil) (let ((v x)) (if #f #f)))
+      (else (let ((v x)) (g v))))
+    (cond ((type-is? x &imm-string) (if #f #f))))
+  (define (chans c d done)
+    (call/cc (lambda (return)
+               (let ((%defers (list)))
+                 (dynamic-wind
+                   (lambda () #f)
+                   (lambda ()
+                     (<-! c 1)
+                     (let ((v (<- c)))
+                       (select (list (recv-case c (lambda (x %ok) (f x)))
+                                     (send-case d v (lambda () (if #f #f)))
+                                     (recv-case done (lambda (%v %ok) (return))))
+                               (lambda () (if #f #f)))
+                       (go (let ((%0 v)) (lambda () (f %0))))
+                       (set! %defers (cons (lambda () (g)) %defers))))
+                   (lambda () (for-each (lambda (%f) (%f)) %defers)))))))
+  (define (labels)
+    (let ((n #f) (m #f) (goto-done #f))
+      (letrec ((label-done (lambda ()
+                             (f)
+                             (set! n 3)
+                             (set! m 4)
+                             (let ((%0 n) (%1 m)) %0 %1))))
+        (let %goto
+          ((%next (lambda ()
+                    (let ((i 0))
+                      (call/cc (lambda (break-outer)
+                                 (let %loop ()
+                                   (when (< i 3)
+                                     (call/cc (lambda (continue-outer)
+                                                (let %loop ()
+                                                  (when (ok) (continue-outer))
+                                                  (break-outer)
+                                                  (%loop))))
+                                     (set! i (+ i 1))
+                                     (%loop))))))
+                    (goto-done label-done)
+                    (label-done))))
+          (let ((%target (call/cc (lambda (%k) (set! goto-done %k) (%next) #f))))
+            (when %target (%goto %target)))))))
+  (define (results) (values 1 "a")))
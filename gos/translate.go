@@ -0,0 +1,96 @@
+package gos
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+)
+
+// Options configures a translation.
+type Options struct {
+	// TypeCheck runs go/types over the file being translated,
+	// unless Info already holds the results.
+	TypeCheck bool
+
+	// Info, if not nil, supplies type information for the nodes
+	// being translated, as recorded by a previous go/types check.
+	// Its Implicits name the packages that imports leave unnamed.
+	Info *types.Info
+
+	// WrapIntegers makes sized integer arithmetic wrap on
+	// overflow, as Compiler.WrapIntegers does. It implies
+	// TypeCheck.
+	WrapIntegers bool
+
+	// Backend spells the output, as Compiler.Backend does; nil
+	// means Gos.
+	Backend Backend
+
+	// ImportPath is the import path of the file's package, as in
+	// Compiler.ImportPath.
+	ImportPath string
+}
+
+func newCompiler(fset *token.FileSet, wr io.Writer, opts Options) *Compiler {
+	return &Compiler{
+		wr:        wr,
+		fset:      fset,
+		info:      opts.Info,
+		TypeCheck: opts.TypeCheck,
+
+		WrapIntegers: opts.WrapIntegers,
+		Backend:      opts.Backend,
+		ImportPath:   opts.ImportPath,
+	}
+}
+
+// Translate writes the Gos translation of file, which was parsed
+// into fset, to wr. Constructs that cannot be translated are
+// reported together in the returned Diagnostics.
+func Translate(fset *token.FileSet, file *ast.File, wr io.Writer, opts Options) error {
+	forms, err := BuildFile(fset, file, opts)
+	if werr := Write(wr, forms); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// BuildFile returns the Gos translation of file as a tree, for
+// passes that rewrite it before it is written. The forms are
+// returned even if there are diagnostics.
+func BuildFile(fset *token.FileSet, file *ast.File, opts Options) ([]Node, error) {
+	c := newCompiler(fset, nil, opts)
+	if c.info == nil && (opts.TypeCheck || opts.WrapIntegers) {
+		c.check(file)
+	}
+	return c.emitFile(file), c.diags.Err()
+}
+
+// TranslateDecl writes the Gos translation of a single declaration.
+func TranslateDecl(fset *token.FileSet, decl ast.Decl, wr io.Writer, opts Options) error {
+	c := newCompiler(fset, wr, opts)
+	return c.write(c.emitDecl(decl)...)
+}
+
+// TranslateStmt writes the Gos translation of a single statement.
+func TranslateStmt(fset *token.FileSet, stmt ast.Stmt, wr io.Writer, opts Options) error {
+	c := newCompiler(fset, wr, opts)
+	return c.write(c.emitStmts(stmt)...)
+}
+
+// TranslateExpr writes the Gos translation of a single expression
+// or type.
+func TranslateExpr(fset *token.FileSet, expr ast.Expr, wr io.Writer, opts Options) error {
+	c := newCompiler(fset, wr, opts)
+	return c.write(c.emitExpr(expr))
+}
+
+// write writes forms to c.wr, separated by newlines, and returns
+// the diagnostics recorded while building them.
+func (c *Compiler) write(forms ...Node) error {
+	if _, err := io.WriteString(c.wr, joinLines(forms)); err != nil {
+		c.addError(err)
+	}
+	return c.diags.Err()
+}
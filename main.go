@@ -2,20 +2,110 @@
 package main
 
 import (
-//	"bytes"
-//	"fmt"
+	"fmt"
 	"flag"
+	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"runtime/pprof"
+	"strings"
+
+	"github.com/andydude/go2gos/gos"
 )
 
 var debug = flag.Bool("d", false, "print debugging info")
 var raw = flag.Bool("r", false, "print unformatted output")
-var inputname = flag.String("i", "-", "input filename")
-var outputname = flag.String("o", "-", "output filename")
+var inputname = flag.String("i", "-", "input filename, package directory or dir/... pattern")
+var outputname = flag.String("o", "-", "output filename, or directory to mirror the input tree into")
+var indent = flag.Int("indent", gos.DefaultStyle.Indent, "indent width for pretty-printed output")
+var width = flag.Int("width", gos.DefaultStyle.Width, "line width for pretty-printed output")
+var typecheck = flag.Bool("t", false, "type-check input to disambiguate output")
+var wrap = flag.Bool("wrap", false, "make sized integer arithmetic wrap on overflow, as in Go; implies -t")
+var gos2go = flag.Bool("gos2go", false, "translate Gos input back to Go")
+var lower = flag.String("lower", "none", "rewrite Gos forms into Scheme: none, core or r7rs")
+var target = flag.String("target", "gos", "output dialect: "+strings.Join(gos.BackendNames(), ", "))
+
+// lowering is the level parsed from -lower.
+var lowering gos.Level
+
+// backend is the backend named by -target.
+var backend gos.Backend
+
+func compile() error {
+	var err error
+	if lowering, err = gos.ParseLevel(*lower); err != nil {
+		return err
+	}
+	if backend, err = gos.LookupBackend(*target); err != nil {
+		return err
+	}
+	if lowering != gos.LowerNone && backend != gos.Gos {
+		return fmt.Errorf("-lower reads Gos, so it cannot be used with -target=%s", *target)
+	}
+	if *gos2go {
+		return decompile()
+	}
+	if gos.IsPackagePattern(*inputname) {
+		return compilePackages()
+	}
+
+	name := *outputname
+	if isDirOutput(name) {
+		base := filepath.Base(*inputname)
+		if *inputname == "-" {
+			base = "stdin"
+		}
+		name = filepath.Join(name, strings.TrimSuffix(base, ".go")+ext())
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+	}
+	return outputForms(name, translate)
+}
+
+// ext returns the extension of the files -target writes.
+func ext() string {
+	if p, ok := backend.(gos.Packager); ok {
+		return p.Ext()
+	}
+	return ".gos"
+}
+
+// decompile translates the Gos file named by -i back to Go.
+func decompile() error {
+	if gos.IsPackagePattern(*inputname) {
+		return fmt.Errorf("-gos2go takes a single file, not %s", *inputname)
+	}
+	name := *outputname
+	if isDirOutput(name) {
+		base := filepath.Base(*inputname)
+		if *inputname == "-" {
+			base = "stdin"
+		}
+		name = filepath.Join(name, strings.TrimSuffix(base, ".gos")+".go")
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+	}
+	return output(name, func(wr io.Writer) error {
+		var src []byte
+		var err error
+		filename := *inputname
+		if filename == "-" {
+			filename = "<stdin>"
+			src, err = io.ReadAll(os.Stdin)
+		} else {
+			src, err = os.ReadFile(filename)
+		}
+		if err != nil {
+			return err
+		}
+		return gos.ReverseTranslate(wr, filename, src)
+	})
+}
 
-func compile() {
+// translate compiles the single file named by -i.
+func translate() ([]gos.Node, error) {
 	// open input file
 	rd, err := func()(file *os.File, err error){
 		if *inputname == "-" {
@@ -24,47 +114,97 @@ func compile() {
 		return os.Open(*inputname)
 	}()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	defer rd.Close()
 
-	// open output file
-	wr, err := func()(file *os.File, err error){
-		if *outputname == "-" {
-			return os.Stdout, nil
-		}
-		return os.Open(*outputname)
-	}()
-	if err != nil {
-		panic(err)
+	c := gos.NewCompiler()
+	c.TypeCheck = *typecheck
+	c.WrapIntegers = *wrap
+	c.Backend = backend
+	c.Filename = *inputname
+	if c.Filename == "-" {
+		c.Filename = "<stdin>"
 	}
+	return c.Build(rd)
+}
 
-	// find guile
-	guile, err := exec.LookPath("guile")
-	if err != nil || *raw {
-		NewCompiler().Compile(rd, wr)
-		rd.Close()
-		return
+// compilePackages compiles every package matched by -i. When -o
+// is a directory, each package is written to its own file in a
+// tree mirroring the input; otherwise all of them go to -o.
+func compilePackages() error {
+	l := gos.NewLoader()
+	l.TypeCheck = *typecheck || *wrap
+	pkgs, err := l.Load(*inputname)
+	if err != nil {
+		return err
+	}
+	translatePackage := func(pkg *gos.Package) func() ([]gos.Node, error) {
+		return func() ([]gos.Node, error) {
+			c := gos.NewCompiler()
+			c.WrapIntegers = *wrap
+			c.Backend = backend
+			return c.BuildPackage(l.Fset, pkg)
+		}
 	}
 
-	// pretty-print
-	const pretty = "(begin (use-modules (ice-9 pretty-print)) (pretty-print (read)))"
-	cmd := exec.Command(guile, "-c", pretty)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	pr, err := cmd.StdinPipe()
-	if err != nil {
-		panic(err)
+	var diags gos.Diagnostics
+	collect := func(err error) error {
+		if list, ok := err.(gos.Diagnostics); ok {
+			diags = append(diags, list...)
+			return nil
+		}
+		return err
+	}
+	if !isDirOutput(*outputname) {
+		err := outputForms(*outputname, func() ([]gos.Node, error) {
+			var all []gos.Node
+			for _, pkg := range pkgs {
+				forms, err := translatePackage(pkg)()
+				if err := collect(err); err != nil {
+					return all, err
+				}
+				all = append(all, forms...)
+			}
+			return all, diags.Err()
+		})
+		return err
 	}
 
-	// compile to pipe
-	NewCompiler().Compile(rd, pr)
-	err = cmd.Run()
+	root, err := filepath.Abs(gos.PatternRoot(*inputname))
 	if err != nil {
-		panic(err)
+		return err
+	}
+	for _, pkg := range pkgs {
+		rel, err := filepath.Rel(root, pkg.Dir)
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(*outputname, rel, pkg.Name+ext())
+		if p, ok := backend.(gos.Packager); ok {
+			// where the target looks for the library by its name
+			name = filepath.Join(*outputname, filepath.FromSlash(gos.LibraryFile(p, pkg.ImportPath)))
+		}
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		if err := collect(outputForms(name, translatePackage(pkg))); err != nil {
+			return err
+		}
 	}
+	return diags.Err()
+}
 
-	rd.Close()
-	//wr.Close()
+// report prints err on stderr, one "file:line:col: message"
+// line per diagnostic.
+func report(err error) {
+	if list, ok := err.(gos.Diagnostics); ok {
+		for _, d := range list {
+			fmt.Fprintln(os.Stderr, d)
+		}
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
 }
 
 func main() {
@@ -83,10 +223,15 @@ func main() {
 		if err != nil { panic(err) }
 		err = pprof.StartCPUProfile(out)
 		if err != nil { panic(err) }
-		compile()
+		err = compile()
 		pprof.StopCPUProfile()
 		out.Close()
-	} else {
-		compile()
+		if err != nil {
+			report(err)
+			os.Exit(1)
+		}
+	} else if err := compile(); err != nil {
+		report(err)
+		os.Exit(1)
 	}
 }
\ No newline at end of file
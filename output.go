@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andydude/go2gos/gos"
+)
+
+// output runs gen and writes what it produced to the file name,
+// or to stdout if name is "-".
+// A file is only written if gen succeeds, and is replaced
+// atomically, so a failed compile never leaves a partial file.
+func output(name string, gen func(io.Writer) error) error {
+	var buf bytes.Buffer
+	err := gen(&buf)
+	data := buf.Bytes()
+
+	if name == "-" {
+		if _, werr := os.Stdout.Write(data); err == nil {
+			err = werr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(name, data)
+}
+
+// outputForms is output for the Gos forms returned by build,
+// which are lowered as -lower says and written one per line if
+// -r was given and pretty-printed otherwise.
+func outputForms(name string, build func() ([]gos.Node, error)) error {
+	return output(name, func(wr io.Writer) error {
+		forms, err := build()
+		forms, lerr := gos.Lower(forms, lowering)
+		if err == nil {
+			err = lerr
+		}
+		if *raw {
+			if werr := gos.Write(wr, forms); err == nil {
+				err = werr
+			}
+			return err
+		}
+		style := gos.DefaultStyle
+		style.Indent = *indent
+		style.Width = *width
+		if werr := gos.Fprint(wr, forms, style); err == nil {
+			err = werr
+		}
+		return err
+	})
+}
+
+// writeFileAtomic writes data to a temporary file beside name,
+// flushes it to disk, and renames it over name.
+func writeFileAtomic(name string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// isDirOutput reports whether name is meant as an output
+// directory: it exists as one, or ends in a slash.
+func isDirOutput(name string) bool {
+	if name == "-" {
+		return false
+	}
+	if strings.HasSuffix(name, "/") || strings.HasSuffix(name, string(filepath.Separator)) {
+		return true
+	}
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}